@@ -4,74 +4,120 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"regexp"
 
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Supported database/sql driver names
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite3"
 )
 
 type DB struct {
 	*sql.DB
+	driver string
 }
 
-func NewConnection(databaseURL string) (*DB, error) {
-	db, err := sql.Open("postgres", databaseURL)
+func NewConnection(driver, databaseURL string) (*DB, error) {
+	dsn := databaseURL
+	if driver == DriverSQLite {
+		dsn = sqliteDSN(databaseURL)
+	}
+
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	if driver == DriverSQLite {
+		// SQLite allows a single writer; one shared connection avoids
+		// "database is locked" errors and keeps :memory: databases alive
+		db.SetMaxOpenConns(1)
+	}
+
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("✅ Successfully connected to PostgreSQL database")
+	if driver == DriverSQLite {
+		log.Println("✅ Successfully connected to SQLite database")
+	} else {
+		log.Println("✅ Successfully connected to PostgreSQL database")
+	}
 
-	return &DB{db}, nil
+	return &DB{DB: db, driver: driver}, nil
 }
 
 func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
-func (db *DB) InitSchema() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS boxes (
-		id UUID PRIMARY KEY,
-		user_id VARCHAR(255) NOT NULL,
-		name VARCHAR(255) NOT NULL,
-		description TEXT,
-		items TEXT[],
-		qr_code TEXT NOT NULL,
-		qr_code_url TEXT NOT NULL,
-		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_boxes_user_id ON boxes(user_id);
-	CREATE INDEX IF NOT EXISTS idx_boxes_created_at ON boxes(created_at);
-	
-	-- Add description column if it doesn't exist (for existing databases)
-	DO $$ 
-	BEGIN
-		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-					   WHERE table_name='boxes' AND column_name='description') THEN
-			ALTER TABLE boxes ADD COLUMN description TEXT;
-		END IF;
-	END $$;
-
-	-- Add room column if it doesn't exist (for existing databases)
-	DO $$ 
-	BEGIN
-		IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-					   WHERE table_name='boxes' AND column_name='room') THEN
-			ALTER TABLE boxes ADD COLUMN room TEXT;
-		END IF;
-	END $$;
-	`
-
-	_, err := db.Exec(query)
+// Driver returns the database/sql driver name of the connection
+func (db *DB) Driver() string {
+	return db.driver
+}
+
+// IsSQLite reports whether the connection is backed by SQLite
+func (db *DB) IsSQLite() bool {
+	return db.driver == DriverSQLite
+}
+
+var placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+
+// Rebind rewrites PostgreSQL-style $N placeholders for the connection's driver.
+// SQLite understands ?N as the same numbered parameter, so queries can keep a
+// single spelling.
+func (db *DB) Rebind(query string) string {
+	if !db.IsSQLite() {
+		return query
+	}
+	return placeholderPattern.ReplaceAllString(query, "?$1")
+}
+
+// Exec runs a statement after rebinding its placeholders
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.Rebind(query), args...)
+}
+
+// Query runs a query after rebinding its placeholders
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.Rebind(query), args...)
+}
+
+// QueryRow runs a single-row query after rebinding its placeholders
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.Rebind(query), args...)
+}
+
+// Tx is a transaction that rebinds placeholders like DB does
+type Tx struct {
+	*sql.Tx
+	db *DB
+}
+
+// Begin starts a transaction
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
+		return nil, err
 	}
+	return &Tx{Tx: tx, db: db}, nil
+}
+
+// Exec runs a statement in the transaction after rebinding its placeholders
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.db.Rebind(query), args...)
+}
+
+// Query runs a query in the transaction after rebinding its placeholders
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.db.Rebind(query), args...)
+}
 
-	log.Println("✅ Database schema initialized successfully")
-	return nil
+// QueryRow runs a single-row query in the transaction after rebinding its placeholders
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.db.Rebind(query), args...)
 }
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS boxes (
+	id UUID PRIMARY KEY,
+	user_id VARCHAR(255) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	items TEXT[],
+	qr_code TEXT NOT NULL,
+	qr_code_url TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_boxes_user_id ON boxes(user_id);
+CREATE INDEX IF NOT EXISTS idx_boxes_created_at ON boxes(created_at);
@@ -0,0 +1,2 @@
+-- Databases created before migrations existed may already have the column
+ALTER TABLE boxes ADD COLUMN IF NOT EXISTS room TEXT;
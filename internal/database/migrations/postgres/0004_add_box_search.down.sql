@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_boxes_search_text_trgm;
+DROP INDEX IF EXISTS idx_boxes_search_vector;
+ALTER TABLE boxes DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS box_search_document(TEXT, TEXT, TEXT, TEXT[]);
+DROP FUNCTION IF EXISTS box_search_text(TEXT, TEXT, TEXT, TEXT[]);
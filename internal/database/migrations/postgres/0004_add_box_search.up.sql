@@ -0,0 +1,27 @@
+-- Full-text search over name, items, room and description, with trigram
+-- matching as a fallback for typos
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Both helpers are declared IMMUTABLE so they can back a generated column and
+-- an expression index; they only combine their arguments
+CREATE OR REPLACE FUNCTION box_search_text(name TEXT, description TEXT, room TEXT, items TEXT[])
+RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+	SELECT lower(concat_ws(' ', name, array_to_string(items, ' '), room, description))
+$$;
+
+CREATE OR REPLACE FUNCTION box_search_document(name TEXT, description TEXT, room TEXT, items TEXT[])
+RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+	SELECT setweight(to_tsvector('simple', coalesce(name, '')), 'A')
+		|| setweight(to_tsvector('simple', coalesce(array_to_string(items, ' '), '')), 'B')
+		|| setweight(to_tsvector('simple', coalesce(room, '')), 'C')
+		|| setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+$$;
+
+ALTER TABLE boxes ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (box_search_document(name, description, room, items)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_boxes_search_vector ON boxes USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_boxes_search_text_trgm ON boxes
+	USING GIN (box_search_text(name, description, room, items) gin_trgm_ops);
@@ -0,0 +1,43 @@
+-- Fold the item rows back into the boxes.items array and restore the
+-- array-based search from 0004
+ALTER TABLE boxes ADD COLUMN IF NOT EXISTS items TEXT[];
+
+UPDATE boxes SET items = coalesce((
+	SELECT array_agg(items.name ORDER BY items.position, items.created_at)
+	FROM items
+	WHERE items.box_id = boxes.id
+), '{}');
+
+DROP TRIGGER IF EXISTS items_refresh_box_items_text ON items;
+DROP FUNCTION IF EXISTS refresh_box_items_text();
+
+DROP INDEX IF EXISTS idx_boxes_search_text_trgm;
+DROP INDEX IF EXISTS idx_boxes_search_vector;
+ALTER TABLE boxes DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS box_search_document(TEXT, TEXT, TEXT, TEXT);
+DROP FUNCTION IF EXISTS box_search_text(TEXT, TEXT, TEXT, TEXT);
+ALTER TABLE boxes DROP COLUMN IF EXISTS items_text;
+
+CREATE OR REPLACE FUNCTION box_search_text(name TEXT, description TEXT, room TEXT, items TEXT[])
+RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+	SELECT lower(concat_ws(' ', name, array_to_string(items, ' '), room, description))
+$$;
+
+CREATE OR REPLACE FUNCTION box_search_document(name TEXT, description TEXT, room TEXT, items TEXT[])
+RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+	SELECT setweight(to_tsvector('simple', coalesce(name, '')), 'A')
+		|| setweight(to_tsvector('simple', coalesce(array_to_string(items, ' '), '')), 'B')
+		|| setweight(to_tsvector('simple', coalesce(room, '')), 'C')
+		|| setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+$$;
+
+ALTER TABLE boxes ADD COLUMN search_vector tsvector
+	GENERATED ALWAYS AS (box_search_document(name, description, room, items)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_boxes_search_vector ON boxes USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_boxes_search_text_trgm ON boxes
+	USING GIN (box_search_text(name, description, room, items) gin_trgm_ops);
+
+DROP TABLE IF EXISTS items;
@@ -0,0 +1,81 @@
+-- Items become rows with a stable ID instead of elements of boxes.items
+CREATE TABLE IF NOT EXISTS items (
+	id UUID PRIMARY KEY,
+	box_id UUID NOT NULL REFERENCES boxes(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	notes TEXT,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_items_box_id ON items(box_id, position);
+
+-- Split the existing arrays into rows, keeping their order
+INSERT INTO items (id, box_id, name, quantity, position, created_at)
+SELECT gen_random_uuid(), boxes.id, trim(item.name), 1, item.position, boxes.created_at
+FROM boxes, unnest(boxes.items) WITH ORDINALITY AS item(name, position)
+WHERE trim(item.name) <> '';
+
+-- Search can no longer read the array, so it indexes a copy of the item
+-- names kept on the box by a trigger
+DROP INDEX IF EXISTS idx_boxes_search_text_trgm;
+DROP INDEX IF EXISTS idx_boxes_search_vector;
+ALTER TABLE boxes DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS box_search_document(TEXT, TEXT, TEXT, TEXT[]);
+DROP FUNCTION IF EXISTS box_search_text(TEXT, TEXT, TEXT, TEXT[]);
+
+ALTER TABLE boxes ADD COLUMN IF NOT EXISTS items_text TEXT NOT NULL DEFAULT '';
+
+UPDATE boxes SET items_text = coalesce((
+	SELECT string_agg(items.name, ' ' ORDER BY items.position)
+	FROM items
+	WHERE items.box_id = boxes.id
+), '');
+
+ALTER TABLE boxes DROP COLUMN IF EXISTS items;
+
+CREATE OR REPLACE FUNCTION box_search_text(name TEXT, description TEXT, room TEXT, items_text TEXT)
+RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+	SELECT lower(concat_ws(' ', name, items_text, room, description))
+$$;
+
+CREATE OR REPLACE FUNCTION box_search_document(name TEXT, description TEXT, room TEXT, items_text TEXT)
+RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+	SELECT setweight(to_tsvector('simple', coalesce(name, '')), 'A')
+		|| setweight(to_tsvector('simple', coalesce(items_text, '')), 'B')
+		|| setweight(to_tsvector('simple', coalesce(room, '')), 'C')
+		|| setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+$$;
+
+ALTER TABLE boxes ADD COLUMN search_vector tsvector
+	GENERATED ALWAYS AS (box_search_document(name, description, room, items_text)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_boxes_search_vector ON boxes USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_boxes_search_text_trgm ON boxes
+	USING GIN (box_search_text(name, description, room, items_text) gin_trgm_ops);
+
+CREATE OR REPLACE FUNCTION refresh_box_items_text() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		UPDATE boxes SET items_text = coalesce((
+			SELECT string_agg(name, ' ' ORDER BY position) FROM items WHERE box_id = OLD.box_id
+		), '') WHERE id = OLD.box_id;
+	END IF;
+
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		UPDATE boxes SET items_text = coalesce((
+			SELECT string_agg(name, ' ' ORDER BY position) FROM items WHERE box_id = NEW.box_id
+		), '') WHERE id = NEW.box_id;
+	END IF;
+
+	RETURN NULL;
+END
+$$;
+
+CREATE TRIGGER items_refresh_box_items_text
+	AFTER INSERT OR UPDATE OR DELETE ON items
+	FOR EACH ROW EXECUTE FUNCTION refresh_box_items_text();
@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS unit TEXT;
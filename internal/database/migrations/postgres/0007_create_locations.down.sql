@@ -0,0 +1,5 @@
+-- room already holds each box's breadcrumb, so it survives the rollback
+DROP INDEX IF EXISTS idx_boxes_location_id;
+ALTER TABLE boxes DROP COLUMN IF EXISTS location_id;
+
+DROP TABLE IF EXISTS locations;
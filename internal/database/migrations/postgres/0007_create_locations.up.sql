@@ -0,0 +1,39 @@
+-- Locations form a tree per user: buildings, rooms, shelves, bins
+CREATE TABLE IF NOT EXISTS locations (
+	id UUID PRIMARY KEY,
+	user_id VARCHAR(255) NOT NULL,
+	parent_id UUID REFERENCES locations(id) ON DELETE RESTRICT,
+	name VARCHAR(100) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_locations_user_id ON locations(user_id);
+
+-- Sibling names are unique regardless of case, so "Garage" and "garage" are
+-- the same place
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_sibling_name ON locations(
+	user_id,
+	COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	lower(name)
+);
+
+ALTER TABLE boxes ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_boxes_location_id ON boxes(location_id);
+
+-- Each distinct room becomes a top-level location. Rooms that differ only in
+-- case or surrounding spaces are merged.
+INSERT INTO locations (id, user_id, name, created_at, updated_at)
+SELECT gen_random_uuid(), user_id, min(trim(room)), coalesce(min(created_at), NOW()), NOW()
+FROM boxes
+WHERE trim(coalesce(room, '')) <> ''
+GROUP BY user_id, lower(trim(room));
+
+-- room now caches the location breadcrumb for search, sorting and filtering
+UPDATE boxes
+SET location_id = locations.id, room = locations.name
+FROM locations
+WHERE locations.user_id = boxes.user_id
+	AND locations.parent_id IS NULL
+	AND lower(locations.name) = lower(trim(boxes.room));
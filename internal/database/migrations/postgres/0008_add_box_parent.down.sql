@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_boxes_parent_box_id;
+ALTER TABLE boxes DROP COLUMN IF EXISTS parent_box_id;
@@ -0,0 +1,5 @@
+-- Boxes can sit inside other boxes; removing the outer box leaves the inner
+-- ones at the top level
+ALTER TABLE boxes ADD COLUMN IF NOT EXISTS parent_box_id UUID REFERENCES boxes(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_boxes_parent_box_id ON boxes(parent_box_id);
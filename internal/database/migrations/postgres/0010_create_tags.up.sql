@@ -0,0 +1,20 @@
+-- User-defined tags such as "winter" or "fragile", each with a colour
+CREATE TABLE IF NOT EXISTS tags (
+	id UUID PRIMARY KEY,
+	user_id VARCHAR(255) NOT NULL,
+	name VARCHAR(50) NOT NULL,
+	color VARCHAR(7) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Tag names are unique per user regardless of case
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(user_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS box_tags (
+	box_id UUID NOT NULL REFERENCES boxes(id) ON DELETE CASCADE,
+	tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (box_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_box_tags_tag_id ON box_tags(tag_id);
@@ -0,0 +1,22 @@
+-- Rows keep their creator in user_id, so ownership falls back to it. Names
+-- that were only unique within a shared workspace may clash again.
+DROP INDEX IF EXISTS idx_tags_workspace_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(user_id, lower(name));
+
+DROP INDEX IF EXISTS idx_locations_sibling_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_sibling_name ON locations(
+	user_id,
+	COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	lower(name)
+);
+
+DROP INDEX IF EXISTS idx_locations_workspace_id;
+DROP INDEX IF EXISTS idx_boxes_workspace_id;
+
+ALTER TABLE tags DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE locations DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE boxes DROP COLUMN IF EXISTS workspace_id;
+
+DROP TABLE IF EXISTS workspace_invitations;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,73 @@
+-- Workspaces (households) own boxes, locations and tags and are shared by
+-- their members. user_id on those tables now records who created the row.
+CREATE TABLE IF NOT EXISTS workspaces (
+	id UUID PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	created_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+	workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+	user_id VARCHAR(255) NOT NULL,
+	joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members(user_id);
+
+-- Invitations are accepted with their code. Those addressed to an email can
+-- only be accepted by a user with that address.
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+	id UUID PRIMARY KEY,
+	workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+	code VARCHAR(16) NOT NULL UNIQUE,
+	email VARCHAR(255),
+	invited_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	accepted_by VARCHAR(255),
+	accepted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_invitations_email ON workspace_invitations(lower(email));
+
+-- Every existing user gets a personal workspace holding what they own
+INSERT INTO workspaces (id, name, created_by, created_at, updated_at)
+SELECT gen_random_uuid(), 'My boxes', user_id, min(created_at), NOW()
+FROM (
+	SELECT user_id, created_at FROM boxes
+	UNION ALL SELECT user_id, created_at FROM locations
+	UNION ALL SELECT user_id, created_at FROM tags
+) owners
+GROUP BY user_id;
+
+INSERT INTO workspace_members (workspace_id, user_id, joined_at)
+SELECT id, created_by, created_at FROM workspaces;
+
+ALTER TABLE boxes ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id);
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id);
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id);
+
+UPDATE boxes SET workspace_id = (SELECT id FROM workspaces WHERE created_by = boxes.user_id);
+UPDATE locations SET workspace_id = (SELECT id FROM workspaces WHERE created_by = locations.user_id);
+UPDATE tags SET workspace_id = (SELECT id FROM workspaces WHERE created_by = tags.user_id);
+
+ALTER TABLE boxes ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE locations ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE tags ALTER COLUMN workspace_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_boxes_workspace_id ON boxes(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_locations_workspace_id ON locations(workspace_id);
+
+-- Names are now unique within a workspace rather than per user
+DROP INDEX IF EXISTS idx_locations_sibling_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_sibling_name ON locations(
+	workspace_id,
+	COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	lower(name)
+);
+
+DROP INDEX IF EXISTS idx_tags_user_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_workspace_name ON tags(workspace_id, lower(name));
@@ -0,0 +1,2 @@
+ALTER TABLE workspace_invitations DROP COLUMN IF EXISTS role;
+ALTER TABLE workspace_members DROP COLUMN IF EXISTS role;
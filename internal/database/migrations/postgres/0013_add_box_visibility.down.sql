@@ -0,0 +1,2 @@
+ALTER TABLE boxes DROP COLUMN IF EXISTS pin_hash;
+ALTER TABLE boxes DROP COLUMN IF EXISTS visibility;
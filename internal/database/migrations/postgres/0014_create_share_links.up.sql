@@ -0,0 +1,16 @@
+-- Share links give people outside a workspace access to one box, the boxes
+-- in a location, or the boxes with a tag until they expire. Revoking a link
+-- sets revoked_at; the row stays so its token keeps being refused.
+CREATE TABLE IF NOT EXISTS share_links (
+	id UUID PRIMARY KEY,
+	workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+	scope VARCHAR(20) NOT NULL,
+	target_id UUID NOT NULL,
+	can_edit BOOLEAN NOT NULL DEFAULT FALSE,
+	created_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_workspace_id ON share_links(workspace_id);
@@ -0,0 +1,5 @@
+-- Short codes such as K7F-3QX can be typed in when a label is torn and give
+-- smaller QR codes. Boxes created before this get theirs when the server starts.
+ALTER TABLE boxes ADD COLUMN IF NOT EXISTS short_code VARCHAR(7);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_boxes_short_code ON boxes(short_code);
@@ -0,0 +1,18 @@
+-- SQLite support arrived after the description and room columns, so the
+-- initial table already includes them and there are no 0002/0003 migrations.
+-- Items are a JSON array in a TEXT column because SQLite has no array type.
+CREATE TABLE IF NOT EXISTS boxes (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT,
+	room TEXT,
+	items TEXT NOT NULL DEFAULT '[]',
+	qr_code TEXT NOT NULL,
+	qr_code_url TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_boxes_user_id ON boxes(user_id);
+CREATE INDEX IF NOT EXISTS idx_boxes_created_at ON boxes(created_at);
@@ -0,0 +1,9 @@
+ALTER TABLE boxes ADD COLUMN items TEXT NOT NULL DEFAULT '[]';
+
+UPDATE boxes SET items = coalesce((
+	SELECT json_group_array(name) FROM (
+		SELECT name FROM items WHERE items.box_id = boxes.id ORDER BY position, created_at
+	)
+), '[]');
+
+DROP TABLE IF EXISTS items;
@@ -0,0 +1,29 @@
+-- Items become rows with a stable ID instead of a JSON array on the box
+CREATE TABLE IF NOT EXISTS items (
+	id TEXT PRIMARY KEY,
+	box_id TEXT NOT NULL REFERENCES boxes(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	notes TEXT,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_items_box_id ON items(box_id, position);
+
+-- Split the existing arrays into rows, keeping their order. SQLite has no
+-- UUID function, so build random version 4 UUIDs by hand.
+INSERT INTO items (id, box_id, name, quantity, position, created_at)
+SELECT
+	lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2)
+		|| '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2)
+		|| '-' || hex(randomblob(6))),
+	boxes.id,
+	trim(item.value),
+	1,
+	item.key + 1,
+	boxes.created_at
+FROM boxes, json_each(boxes.items) AS item
+WHERE trim(item.value) <> '';
+
+ALTER TABLE boxes DROP COLUMN items;
@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN unit TEXT;
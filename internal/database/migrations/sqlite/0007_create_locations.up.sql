@@ -0,0 +1,56 @@
+-- Locations form a tree per user: buildings, rooms, shelves, bins
+CREATE TABLE IF NOT EXISTS locations (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	parent_id TEXT REFERENCES locations(id) ON DELETE RESTRICT,
+	name TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_locations_user_id ON locations(user_id);
+
+-- Sibling names are unique regardless of case, so "Garage" and "garage" are
+-- the same place
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_sibling_name ON locations(
+	user_id,
+	coalesce(parent_id, ''),
+	lower(name)
+);
+
+-- SQLite cannot drop a column that is part of a foreign key, so location_id
+-- has no REFERENCES clause; the repository detaches boxes when a location is
+-- deleted
+ALTER TABLE boxes ADD COLUMN location_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_boxes_location_id ON boxes(location_id);
+
+-- Each distinct room becomes a top-level location. Rooms that differ only in
+-- case or surrounding spaces are merged.
+INSERT INTO locations (id, user_id, name, created_at, updated_at)
+SELECT
+	lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2)
+		|| '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2)
+		|| '-' || hex(randomblob(6))),
+	user_id,
+	min(trim(room)),
+	coalesce(min(created_at), CURRENT_TIMESTAMP),
+	CURRENT_TIMESTAMP
+FROM boxes
+WHERE trim(coalesce(room, '')) <> ''
+GROUP BY user_id, lower(trim(room));
+
+-- room now caches the location breadcrumb for search, sorting and filtering
+UPDATE boxes
+SET location_id = (
+	SELECT locations.id
+	FROM locations
+	WHERE locations.user_id = boxes.user_id
+		AND locations.parent_id IS NULL
+		AND lower(locations.name) = lower(trim(boxes.room))
+)
+WHERE trim(coalesce(room, '')) <> '';
+
+UPDATE boxes
+SET room = (SELECT name FROM locations WHERE locations.id = boxes.location_id)
+WHERE location_id IS NOT NULL;
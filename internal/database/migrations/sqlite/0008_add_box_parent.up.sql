@@ -0,0 +1,6 @@
+-- Boxes can sit inside other boxes. Like location_id, the column has no
+-- REFERENCES clause so it can be dropped again; the repository clears it on
+-- the inner boxes when the outer box is deleted.
+ALTER TABLE boxes ADD COLUMN parent_box_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_boxes_parent_box_id ON boxes(parent_box_id);
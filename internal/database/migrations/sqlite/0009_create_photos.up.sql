@@ -0,0 +1,17 @@
+-- Photos of a box or of one of its items. The files live in the blob store;
+-- these rows only point at them. Deleting an item keeps its photos on the box.
+CREATE TABLE IF NOT EXISTS photos (
+	id TEXT PRIMARY KEY,
+	box_id TEXT NOT NULL REFERENCES boxes(id) ON DELETE CASCADE,
+	item_id TEXT REFERENCES items(id) ON DELETE SET NULL,
+	storage_key TEXT NOT NULL,
+	thumbnail_key TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size_bytes INTEGER NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_photos_box_id ON photos(box_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_photos_item_id ON photos(item_id);
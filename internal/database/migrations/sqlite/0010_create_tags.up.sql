@@ -0,0 +1,20 @@
+-- User-defined tags such as "winter" or "fragile", each with a colour
+CREATE TABLE IF NOT EXISTS tags (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	color TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tag names are unique per user regardless of case
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(user_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS box_tags (
+	box_id TEXT NOT NULL REFERENCES boxes(id) ON DELETE CASCADE,
+	tag_id TEXT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (box_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_box_tags_tag_id ON box_tags(tag_id);
@@ -0,0 +1,78 @@
+-- Workspaces (households) own boxes, locations and tags and are shared by
+-- their members. user_id on those tables now records who created the row.
+CREATE TABLE IF NOT EXISTS workspaces (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	created_by TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+	workspace_id TEXT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL,
+	joined_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members(user_id);
+
+-- Invitations are accepted with their code. Those addressed to an email can
+-- only be accepted by a user with that address.
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+	id TEXT PRIMARY KEY,
+	workspace_id TEXT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+	code TEXT NOT NULL UNIQUE,
+	email TEXT,
+	invited_by TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	accepted_by TEXT,
+	accepted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_invitations_email ON workspace_invitations(lower(email));
+
+-- Every existing user gets a personal workspace holding what they own
+INSERT INTO workspaces (id, name, created_by, created_at, updated_at)
+SELECT
+	lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2)
+		|| '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2)
+		|| '-' || hex(randomblob(6))),
+	'My boxes',
+	user_id,
+	coalesce(min(created_at), CURRENT_TIMESTAMP),
+	CURRENT_TIMESTAMP
+FROM (
+	SELECT user_id, created_at FROM boxes
+	UNION ALL SELECT user_id, created_at FROM locations
+	UNION ALL SELECT user_id, created_at FROM tags
+)
+GROUP BY user_id;
+
+INSERT INTO workspace_members (workspace_id, user_id, joined_at)
+SELECT id, created_by, created_at FROM workspaces;
+
+-- As with location_id, the columns have no REFERENCES clause so they can be
+-- dropped again
+ALTER TABLE boxes ADD COLUMN workspace_id TEXT;
+ALTER TABLE locations ADD COLUMN workspace_id TEXT;
+ALTER TABLE tags ADD COLUMN workspace_id TEXT;
+
+UPDATE boxes SET workspace_id = (SELECT id FROM workspaces WHERE created_by = boxes.user_id);
+UPDATE locations SET workspace_id = (SELECT id FROM workspaces WHERE created_by = locations.user_id);
+UPDATE tags SET workspace_id = (SELECT id FROM workspaces WHERE created_by = tags.user_id);
+
+CREATE INDEX IF NOT EXISTS idx_boxes_workspace_id ON boxes(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_locations_workspace_id ON locations(workspace_id);
+
+-- Names are now unique within a workspace rather than per user
+DROP INDEX IF EXISTS idx_locations_sibling_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_sibling_name ON locations(
+	workspace_id,
+	coalesce(parent_id, ''),
+	lower(name)
+);
+
+DROP INDEX IF EXISTS idx_tags_user_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_workspace_name ON tags(workspace_id, lower(name));
@@ -0,0 +1,36 @@
+-- SQLite has no DROP COLUMN IF EXISTS, so both tables are rebuilt without the
+-- role column. Copying the remaining columns works whether or not it exists.
+CREATE TABLE workspace_members_without_roles (
+	workspace_id TEXT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL,
+	joined_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (workspace_id, user_id)
+);
+
+INSERT INTO workspace_members_without_roles (workspace_id, user_id, joined_at)
+SELECT workspace_id, user_id, joined_at FROM workspace_members;
+
+DROP TABLE workspace_members;
+ALTER TABLE workspace_members_without_roles RENAME TO workspace_members;
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members(user_id);
+
+CREATE TABLE workspace_invitations_without_roles (
+	id TEXT PRIMARY KEY,
+	workspace_id TEXT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+	code TEXT NOT NULL UNIQUE,
+	email TEXT,
+	invited_by TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	accepted_by TEXT,
+	accepted_at TIMESTAMP
+);
+
+INSERT INTO workspace_invitations_without_roles (id, workspace_id, code, email, invited_by, created_at, expires_at, accepted_by, accepted_at)
+SELECT id, workspace_id, code, email, invited_by, created_at, expires_at, accepted_by, accepted_at FROM workspace_invitations;
+
+DROP TABLE workspace_invitations;
+ALTER TABLE workspace_invitations_without_roles RENAME TO workspace_invitations;
+
+CREATE INDEX IF NOT EXISTS idx_workspace_invitations_email ON workspace_invitations(lower(email));
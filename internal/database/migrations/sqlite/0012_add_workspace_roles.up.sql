@@ -0,0 +1,20 @@
+-- Members are owners, editors or viewers. The creator of each workspace owns
+-- it and everyone who joined so far keeps editing rights.
+ALTER TABLE workspace_members ADD COLUMN role TEXT NOT NULL DEFAULT 'editor';
+
+UPDATE workspace_members SET role = 'owner'
+WHERE user_id = (SELECT created_by FROM workspaces WHERE workspaces.id = workspace_members.workspace_id);
+
+-- A workspace whose creator has left is owned by its longest-standing member
+UPDATE workspace_members SET role = 'owner'
+WHERE NOT EXISTS (
+		SELECT 1 FROM workspace_members owners
+		WHERE owners.workspace_id = workspace_members.workspace_id AND owners.role = 'owner'
+	)
+	AND joined_at = (
+		SELECT MIN(joined_at) FROM workspace_members earliest
+		WHERE earliest.workspace_id = workspace_members.workspace_id
+	);
+
+-- The role someone gets when they accept the invitation
+ALTER TABLE workspace_invitations ADD COLUMN role TEXT NOT NULL DEFAULT 'editor';
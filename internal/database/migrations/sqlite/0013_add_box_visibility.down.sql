@@ -0,0 +1,2 @@
+ALTER TABLE boxes DROP COLUMN pin_hash;
+ALTER TABLE boxes DROP COLUMN visibility;
@@ -0,0 +1,5 @@
+-- Controls what the public scan page shows of a box: public, name_only, pin
+-- or private. Existing boxes stay fully public as before. pin_hash holds the
+-- bcrypt hash of the PIN that unlocks a pin box.
+ALTER TABLE boxes ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public';
+ALTER TABLE boxes ADD COLUMN pin_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_boxes_short_code;
+ALTER TABLE boxes DROP COLUMN short_code;
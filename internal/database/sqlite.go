@@ -0,0 +1,27 @@
+package database
+
+import (
+	"strings"
+)
+
+// sqliteDSN turns a sqlite:// DATABASE_URL into a go-sqlite3 connection string
+func sqliteDSN(databaseURL string) string {
+	dsn := databaseURL
+	for _, prefix := range []string{"sqlite3://", "sqlite://", "sqlite3:", "sqlite:"} {
+		if strings.HasPrefix(dsn, prefix) {
+			dsn = strings.TrimPrefix(dsn, prefix)
+			break
+		}
+	}
+
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+
+	return dsn + separator + "_foreign_keys=on&_busy_timeout=5000"
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/qr-boxes/backend/internal/middleware"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/services"
+	"github.com/qr-boxes/backend/pkg/utils"
+)
+
+type ItemHandler struct {
+	itemService *services.ItemService
+}
+
+func NewItemHandler(itemService *services.ItemService) *ItemHandler {
+	return &ItemHandler{
+		itemService: itemService,
+	}
+}
+
+// CreateItem adds a new item to one of the user's boxes
+func (h *ItemHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("ItemHandler.CreateItem: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("ItemHandler.CreateItem: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.BoxID == "" {
+		utils.BadRequestError(w, "Box ID is required")
+		return
+	}
+
+	if !validateItemFields(w, &request.Name, &request.Quantity, &request.Unit, &request.Notes) {
+		return
+	}
+
+	log.Printf("ItemHandler.CreateItem: Adding item to box %s for user %s", request.BoxID, userID)
+
+	item, err := h.itemService.CreateItem(userID, &request)
+	if err != nil {
+		log.Printf("ItemHandler.CreateItem: Failed to create item: %v", err)
+		writeItemError(w, err, "Failed to create item")
+		return
+	}
+
+	log.Printf("ItemHandler.CreateItem: Successfully created item %s in box %s", item.ID, item.BoxID)
+	utils.CreatedResponse(w, item)
+}
+
+// GetItem returns a single item by ID
+func (h *ItemHandler) GetItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("ItemHandler.GetItem: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	itemID := r.URL.Query().Get("id")
+	if itemID == "" {
+		utils.BadRequestError(w, "Item ID is required")
+		return
+	}
+
+	item, err := h.itemService.GetItem(userID, itemID)
+	if err != nil {
+		log.Printf("ItemHandler.GetItem: Failed to fetch item: %v", err)
+		writeItemError(w, err, "Failed to fetch item")
+		return
+	}
+
+	utils.SuccessResponse(w, item)
+}
+
+// UpdateItem renames an item or changes its quantity, unit or notes
+func (h *ItemHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("ItemHandler.UpdateItem: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	itemID := r.URL.Query().Get("id")
+	if itemID == "" {
+		utils.BadRequestError(w, "Item ID is required")
+		return
+	}
+
+	// Parse request body
+	var request models.UpdateItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("ItemHandler.UpdateItem: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if !validateItemFields(w, request.Name, request.Quantity, request.Unit, request.Notes) {
+		return
+	}
+
+	log.Printf("ItemHandler.UpdateItem: Updating item %s for user %s", itemID, userID)
+
+	item, err := h.itemService.UpdateItem(userID, itemID, &request)
+	if err != nil {
+		log.Printf("ItemHandler.UpdateItem: Failed to update item: %v", err)
+		writeItemError(w, err, "Failed to update item")
+		return
+	}
+
+	log.Printf("ItemHandler.UpdateItem: Successfully updated item %s", itemID)
+	utils.SuccessResponse(w, item)
+}
+
+// MoveItem moves an item into another box owned by the same user
+func (h *ItemHandler) MoveItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("ItemHandler.MoveItem: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.MoveItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("ItemHandler.MoveItem: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.ItemID == "" {
+		utils.BadRequestError(w, "Item ID is required")
+		return
+	}
+
+	if request.BoxID == "" {
+		utils.BadRequestError(w, "Box ID is required")
+		return
+	}
+
+	log.Printf("ItemHandler.MoveItem: Moving item %s to box %s for user %s", request.ItemID, request.BoxID, userID)
+
+	item, err := h.itemService.MoveItem(userID, &request)
+	if err != nil {
+		log.Printf("ItemHandler.MoveItem: Failed to move item: %v", err)
+		writeItemError(w, err, "Failed to move item")
+		return
+	}
+
+	log.Printf("ItemHandler.MoveItem: Successfully moved item %s to box %s", item.ID, item.BoxID)
+	utils.SuccessResponse(w, item)
+}
+
+// AdjustItemQuantity increments or decrements an item's quantity by a delta
+func (h *ItemHandler) AdjustItemQuantity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("ItemHandler.AdjustItemQuantity: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.AdjustItemQuantityRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("ItemHandler.AdjustItemQuantity: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.ItemID == "" {
+		utils.BadRequestError(w, "Item ID is required")
+		return
+	}
+
+	if request.Delta == 0 {
+		utils.BadRequestError(w, "Delta must not be zero")
+		return
+	}
+
+	if request.Delta > models.MaxItemQuantity || request.Delta < -models.MaxItemQuantity {
+		utils.BadRequestError(w, "Delta must be between -1000000 and 1000000")
+		return
+	}
+
+	log.Printf("ItemHandler.AdjustItemQuantity: Adjusting item %s by %d for user %s", request.ItemID, request.Delta, userID)
+
+	item, err := h.itemService.AdjustItemQuantity(userID, &request)
+	if err != nil {
+		log.Printf("ItemHandler.AdjustItemQuantity: Failed to adjust item: %v", err)
+		writeItemError(w, err, "Failed to adjust item quantity")
+		return
+	}
+
+	log.Printf("ItemHandler.AdjustItemQuantity: Item %s now has quantity %d", item.ID, item.Quantity)
+	utils.SuccessResponse(w, item)
+}
+
+// DeleteItem removes an item by ID
+func (h *ItemHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("ItemHandler.DeleteItem: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	itemID := r.URL.Query().Get("id")
+	if itemID == "" {
+		utils.BadRequestError(w, "Item ID is required")
+		return
+	}
+
+	log.Printf("ItemHandler.DeleteItem: Deleting item %s for user %s", itemID, userID)
+
+	if err := h.itemService.DeleteItem(userID, itemID); err != nil {
+		log.Printf("ItemHandler.DeleteItem: Failed to delete item: %v", err)
+		writeItemError(w, err, "Failed to delete item")
+		return
+	}
+
+	log.Printf("ItemHandler.DeleteItem: Successfully deleted item %s", itemID)
+	utils.SuccessResponse(w, map[string]string{"message": "Item deleted successfully"})
+}
+
+// validateItemFields checks the fields shared by item requests, trimming the
+// name in place. Nil fields are skipped. It writes the error response itself.
+func validateItemFields(w http.ResponseWriter, name *string, quantity *int, unit *string, notes *string) bool {
+	if name != nil {
+		*name = strings.TrimSpace(*name)
+		if *name == "" {
+			utils.BadRequestError(w, "Item name is required")
+			return false
+		}
+		if len(*name) > 100 {
+			utils.BadRequestError(w, "Item name must be less than 100 characters")
+			return false
+		}
+	}
+
+	if quantity != nil && *quantity < 0 {
+		utils.BadRequestError(w, "Quantity cannot be negative")
+		return false
+	}
+
+	if quantity != nil && *quantity > models.MaxItemQuantity {
+		utils.BadRequestError(w, "Quantity must be at most 1000000")
+		return false
+	}
+
+	if unit != nil && len(strings.TrimSpace(*unit)) > 20 {
+		utils.BadRequestError(w, "Unit must be less than 20 characters")
+		return false
+	}
+
+	if notes != nil && len(*notes) > 500 {
+		utils.BadRequestError(w, "Notes must be less than 500 characters")
+		return false
+	}
+
+	return true
+}
+
+// writeItemError maps item service errors to HTTP responses
+func writeItemError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "item not found":
+		utils.NotFoundError(w, "Item not found")
+	case "quantity cannot be negative":
+		utils.BadRequestError(w, "Quantity cannot be negative")
+	case "quantity too large":
+		utils.BadRequestError(w, "Quantity must be at most 1000000")
+	case "box not found":
+		utils.NotFoundError(w, "Box not found")
+	case "forbidden":
+		utils.ForbiddenError(w, forbiddenMessage)
+	default:
+		utils.InternalServerError(w, fallback)
+	}
+}
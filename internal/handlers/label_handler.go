@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/middleware"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/services"
+	"github.com/qr-boxes/backend/pkg/utils"
+)
+
+// tooManyLabelsMessage is returned whichever way the request selected too many boxes
+var tooManyLabelsMessage = fmt.Sprintf("Cannot print more than %d labels at once", services.MaxLabelBoxes)
+
+type LabelHandler struct {
+	labelService *services.LabelService
+}
+
+func NewLabelHandler(labelService *services.LabelService) *LabelHandler {
+	return &LabelHandler{
+		labelService: labelService,
+	}
+}
+
+// GetLabelSheet returns a printable PDF of QR labels for a list of boxes, a whole room or a location
+func (h *LabelHandler) GetLabelSheet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("LabelHandler.GetLabelSheet: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.LabelSheetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("LabelHandler.GetLabelSheet: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if len(request.BoxIDs) == 0 && request.Room == "" && request.LocationID == "" {
+		utils.BadRequestError(w, "Either box IDs, a room or a location is required")
+		return
+	}
+
+	if request.LocationID != "" {
+		if _, err := uuid.Parse(request.LocationID); err != nil {
+			utils.BadRequestError(w, "Invalid location ID")
+			return
+		}
+	}
+
+	if len(request.BoxIDs) > services.MaxLabelBoxes {
+		utils.BadRequestError(w, tooManyLabelsMessage)
+		return
+	}
+
+	if len(request.Room) > 100 {
+		utils.BadRequestError(w, "Room must be less than 100 characters")
+		return
+	}
+
+	log.Printf("LabelHandler.GetLabelSheet: Generating labels for user %s", userID)
+
+	pdf, err := h.labelService.GenerateLabelSheet(userID, &request)
+	if err != nil {
+		log.Printf("LabelHandler.GetLabelSheet: Failed to generate labels: %v", err)
+		switch err.Error() {
+		case "unknown label template":
+			utils.BadRequestError(w, "Unknown label template")
+		case "too many boxes":
+			utils.BadRequestError(w, tooManyLabelsMessage)
+		case "no boxes to print":
+			utils.NotFoundError(w, "No boxes found to print")
+		case "box not found":
+			utils.NotFoundError(w, "Box not found")
+		case "forbidden":
+			utils.ForbiddenError(w, forbiddenMessage)
+		default:
+			utils.InternalServerError(w, "Failed to generate labels")
+		}
+		return
+	}
+
+	filename := fmt.Sprintf("box-labels-%s.pdf", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdf)
+
+	log.Printf("LabelHandler.GetLabelSheet: Successfully generated labels for user %s", userID)
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/qr-boxes/backend/internal/middleware"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/services"
+	"github.com/qr-boxes/backend/pkg/utils"
+)
+
+type LocationHandler struct {
+	locationService *services.LocationService
+}
+
+func NewLocationHandler(locationService *services.LocationService) *LocationHandler {
+	return &LocationHandler{
+		locationService: locationService,
+	}
+}
+
+// CreateLocation adds a building, room, shelf or bin to the user's location tree
+func (h *LocationHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("LocationHandler.CreateLocation: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("LocationHandler.CreateLocation: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if !validateLocationName(w, request.Name) {
+		return
+	}
+
+	log.Printf("LocationHandler.CreateLocation: Creating location %q for user %s", request.Name, userID)
+
+	location, err := h.locationService.CreateLocation(userID, &request)
+	if err != nil {
+		log.Printf("LocationHandler.CreateLocation: Failed to create location: %v", err)
+		writeLocationError(w, err, "Failed to create location")
+		return
+	}
+
+	log.Printf("LocationHandler.CreateLocation: Successfully created location %s for user %s", location.ID, userID)
+	utils.CreatedResponse(w, location)
+}
+
+// GetUserLocations returns the user's locations as a flat list ordered by breadcrumb
+func (h *LocationHandler) GetUserLocations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("LocationHandler.GetUserLocations: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	locations, err := h.locationService.GetUserLocations(userID)
+	if err != nil {
+		log.Printf("LocationHandler.GetUserLocations: Failed to fetch locations: %v", err)
+		utils.InternalServerError(w, "Failed to fetch locations")
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"locations": locations,
+		"count":     len(locations),
+	})
+}
+
+// GetLocation returns a single location with its breadcrumb
+func (h *LocationHandler) GetLocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("LocationHandler.GetLocation: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	locationID := r.URL.Query().Get("id")
+	if locationID == "" {
+		utils.BadRequestError(w, "Location ID is required")
+		return
+	}
+
+	location, err := h.locationService.GetLocation(userID, locationID)
+	if err != nil {
+		log.Printf("LocationHandler.GetLocation: Failed to fetch location: %v", err)
+		writeLocationError(w, err, "Failed to fetch location")
+		return
+	}
+
+	utils.SuccessResponse(w, location)
+}
+
+// UpdateLocation renames a location or moves it under another parent
+func (h *LocationHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("LocationHandler.UpdateLocation: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	locationID := r.URL.Query().Get("id")
+	if locationID == "" {
+		utils.BadRequestError(w, "Location ID is required")
+		return
+	}
+
+	// Parse request body
+	var request models.UpdateLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("LocationHandler.UpdateLocation: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.Name != nil && !validateLocationName(w, *request.Name) {
+		return
+	}
+
+	log.Printf("LocationHandler.UpdateLocation: Updating location %s for user %s", locationID, userID)
+
+	location, err := h.locationService.UpdateLocation(userID, locationID, &request)
+	if err != nil {
+		log.Printf("LocationHandler.UpdateLocation: Failed to update location: %v", err)
+		writeLocationError(w, err, "Failed to update location")
+		return
+	}
+
+	log.Printf("LocationHandler.UpdateLocation: Successfully updated location %s", locationID)
+	utils.SuccessResponse(w, location)
+}
+
+// DeleteLocation removes an empty branch of the location tree. Boxes in the
+// location are kept but no longer have a location.
+func (h *LocationHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("LocationHandler.DeleteLocation: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	locationID := r.URL.Query().Get("id")
+	if locationID == "" {
+		utils.BadRequestError(w, "Location ID is required")
+		return
+	}
+
+	log.Printf("LocationHandler.DeleteLocation: Deleting location %s for user %s", locationID, userID)
+
+	if err := h.locationService.DeleteLocation(userID, locationID); err != nil {
+		log.Printf("LocationHandler.DeleteLocation: Failed to delete location: %v", err)
+		writeLocationError(w, err, "Failed to delete location")
+		return
+	}
+
+	log.Printf("LocationHandler.DeleteLocation: Successfully deleted location %s", locationID)
+	utils.SuccessResponse(w, map[string]string{"message": "Location deleted successfully"})
+}
+
+// validateLocationName checks a location name and writes the error response itself
+func validateLocationName(w http.ResponseWriter, name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		utils.BadRequestError(w, "Location name is required")
+		return false
+	}
+
+	if len(name) > 100 {
+		utils.BadRequestError(w, "Location name must be less than 100 characters")
+		return false
+	}
+
+	return true
+}
+
+// writeLocationError maps location service errors to HTTP responses
+func writeLocationError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "location not found":
+		utils.NotFoundError(w, "Location not found")
+	case "parent location not found":
+		utils.BadRequestError(w, "Parent location not found")
+	case "location already exists":
+		utils.BadRequestError(w, "A location with this name already exists here")
+	case "location cannot be moved inside itself":
+		utils.BadRequestError(w, "A location cannot be moved inside itself")
+	case "location has child locations":
+		utils.BadRequestError(w, "Delete or move the locations inside this one first")
+	case "forbidden":
+		utils.ForbiddenError(w, forbiddenMessage)
+	case "workspace not found":
+		utils.BadRequestError(w, "Workspace not found")
+	default:
+		utils.InternalServerError(w, fallback)
+	}
+}
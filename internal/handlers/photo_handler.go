@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/qr-boxes/backend/internal/middleware"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/services"
+	"github.com/qr-boxes/backend/pkg/utils"
+)
+
+// maxPhotoUploadSize is the largest photo accepted, in bytes
+const maxPhotoUploadSize = 10 << 20
+
+type PhotoHandler struct {
+	photoService *services.PhotoService
+}
+
+func NewPhotoHandler(photoService *services.PhotoService) *PhotoHandler {
+	return &PhotoHandler{
+		photoService: photoService,
+	}
+}
+
+// UploadPhoto attaches an image to a box, or to an item in it, from a
+// multipart form with boxId, an optional itemId and the file as "photo"
+func (h *PhotoHandler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("PhotoHandler.UploadPhoto: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Leave room for the other form fields and multipart boundaries
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotoUploadSize+1<<20)
+	if err := r.ParseMultipartForm(maxPhotoUploadSize); err != nil {
+		log.Printf("PhotoHandler.UploadPhoto: Failed to parse form: %v", err)
+		utils.BadRequestError(w, "Invalid upload, photos must be smaller than 10MB")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	boxID := strings.TrimSpace(r.FormValue("boxId"))
+	if boxID == "" {
+		utils.BadRequestError(w, "Box ID is required")
+		return
+	}
+	itemID := strings.TrimSpace(r.FormValue("itemId"))
+
+	file, _, err := r.FormFile("photo")
+	if err != nil {
+		utils.BadRequestError(w, "Photo file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxPhotoUploadSize+1))
+	if err != nil {
+		log.Printf("PhotoHandler.UploadPhoto: Failed to read photo: %v", err)
+		utils.BadRequestError(w, "Invalid upload")
+		return
+	}
+
+	if len(data) > maxPhotoUploadSize {
+		utils.BadRequestError(w, "Photos must be smaller than 10MB")
+		return
+	}
+
+	log.Printf("PhotoHandler.UploadPhoto: Uploading %d byte photo to box %s for user %s", len(data), boxID, userID)
+
+	photo, err := h.photoService.UploadPhoto(userID, boxID, itemID, data)
+	if err != nil {
+		log.Printf("PhotoHandler.UploadPhoto: Failed to upload photo: %v", err)
+		writePhotoError(w, err, "Failed to upload photo")
+		return
+	}
+
+	log.Printf("PhotoHandler.UploadPhoto: Successfully uploaded photo %s to box %s", photo.ID, boxID)
+	utils.CreatedResponse(w, photo)
+}
+
+// DeletePhoto removes a photo and its stored files
+func (h *PhotoHandler) DeletePhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("PhotoHandler.DeletePhoto: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	photoID := r.URL.Query().Get("id")
+	if photoID == "" {
+		utils.BadRequestError(w, "Photo ID is required")
+		return
+	}
+
+	log.Printf("PhotoHandler.DeletePhoto: Deleting photo %s for user %s", photoID, userID)
+
+	if err := h.photoService.DeletePhoto(userID, photoID); err != nil {
+		log.Printf("PhotoHandler.DeletePhoto: Failed to delete photo: %v", err)
+		writePhotoError(w, err, "Failed to delete photo")
+		return
+	}
+
+	log.Printf("PhotoHandler.DeletePhoto: Successfully deleted photo %s", photoID)
+	utils.SuccessResponse(w, map[string]string{"message": "Photo deleted successfully"})
+}
+
+// GetPhoto streams a photo, or its thumbnail with size=thumbnail. Photos of
+// public boxes need no authentication. Other photos need a workspace member's
+// token, or the share token or PIN grant that the box's page added to the URL.
+func (h *PhotoHandler) GetPhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	photoID := query.Get("id")
+	if photoID == "" {
+		utils.BadRequestError(w, "Photo ID is required")
+		return
+	}
+
+	thumbnail := query.Get("size") == "thumbnail"
+
+	// Authentication is optional here, so a missing user just means anonymous
+	userID, _ := middleware.GetUserID(r.Context())
+	access := models.PhotoAccess{UserID: userID, ShareToken: query.Get("share"), Grant: query.Get("grant")}
+
+	file, contentType, err := h.photoService.OpenPhoto(photoID, thumbnail, access)
+	if err != nil {
+		log.Printf("PhotoHandler.GetPhoto: Failed to open photo %s: %v", photoID, err)
+		writePhotoError(w, err, "Failed to fetch photo")
+		return
+	}
+	defer file.Close()
+
+	// A photo ID always refers to the same file, but whether it may be seen can
+	// change with the box's visibility, so clients revalidate every time
+	etag := `"` + photoID + `"`
+	if thumbnail {
+		etag = `"` + photoID + `-thumbnail"`
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Vary", "Authorization")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("PhotoHandler.GetPhoto: Failed to send photo %s: %v", photoID, err)
+	}
+}
+
+// writePhotoError maps photo service errors to HTTP responses
+func writePhotoError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "box not found":
+		utils.NotFoundError(w, "Box not found")
+	case "forbidden":
+		utils.ForbiddenError(w, forbiddenMessage)
+	case "item not found":
+		utils.BadRequestError(w, "Item not found in this box")
+	case "photo not found", "blob not found":
+		utils.NotFoundError(w, "Photo not found")
+	case "unsupported image type":
+		utils.BadRequestError(w, "Photos must be JPEG, PNG, GIF or WebP images")
+	case "invalid image":
+		utils.BadRequestError(w, "The photo could not be read as an image")
+	case "image is too large":
+		utils.BadRequestError(w, "The photo's dimensions are too large")
+	default:
+		utils.InternalServerError(w, fallback)
+	}
+}
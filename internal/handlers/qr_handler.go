@@ -1,10 +1,19 @@
 package handlers
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/qr-boxes/backend/internal/middleware"
 	"github.com/qr-boxes/backend/internal/models"
 	"github.com/qr-boxes/backend/internal/services"
@@ -12,12 +21,16 @@ import (
 )
 
 type QRHandler struct {
-	qrService *services.QRService
+	qrService    *services.QRService
+	labelService *services.LabelService
+	photoService *services.PhotoService
 }
 
-func NewQRHandler(qrService *services.QRService) *QRHandler {
+func NewQRHandler(qrService *services.QRService, labelService *services.LabelService, photoService *services.PhotoService) *QRHandler {
 	return &QRHandler{
-		qrService: qrService,
+		qrService:    qrService,
+		labelService: labelService,
+		photoService: photoService,
 	}
 }
 
@@ -44,18 +57,67 @@ func (h *QRHandler) CreateBox(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate request
-	if request.Name == "" {
-		utils.BadRequestError(w, "Box name is required")
+	if problem := createBoxProblem(&request); problem != "" {
+		utils.BadRequestError(w, problem)
 		return
 	}
 
-	if len(request.Name) > 100 {
-		utils.BadRequestError(w, "Box name must be less than 100 characters")
+	log.Printf("QRHandler.CreateBox: Creating box for user %s with name: %s", userID, request.Name)
+
+	// Create box with QR code
+	response, err := h.qrService.CreateBox(userID, &request)
+	if err != nil {
+		log.Printf("QRHandler.CreateBox: Failed to create box: %v", err)
+		writeCreateBoxError(w, err, "Failed to create box")
 		return
 	}
 
-	if len(request.Description) > 500 {
-		utils.BadRequestError(w, "Description must be less than 500 characters")
+	log.Printf("QRHandler.CreateBox: Successfully created box %s for user %s", response.Box.ID, userID)
+	utils.CreatedResponse(w, response)
+}
+
+// CreateBoxes creates a batch of boxes in one go, from a list of boxes or a
+// count of numbered empty ones, optionally with a PDF label sheet for them
+func (h *QRHandler) CreateBoxes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("QRHandler.CreateBoxes: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateBoxesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("QRHandler.CreateBoxes: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if (len(request.Boxes) > 0) == (request.Count > 0) {
+		utils.BadRequestError(w, "Either a list of boxes or a count is required")
+		return
+	}
+
+	if len(request.Boxes) > maxBatchBoxes || request.Count > maxBatchBoxes {
+		utils.BadRequestError(w, fmt.Sprintf("Cannot create more than %d boxes at once", maxBatchBoxes))
+		return
+	}
+
+	if request.Count < 0 {
+		utils.BadRequestError(w, "Count must be a positive number")
+		return
+	}
+
+	if len(request.NamePrefix) > 90 {
+		utils.BadRequestError(w, "Name prefix must be less than 90 characters")
 		return
 	}
 
@@ -64,25 +126,166 @@ func (h *QRHandler) CreateBox(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(request.Items) > 1000 {
-		utils.BadRequestError(w, "Items list must be less than 1000 characters")
+	if len(request.TagIDs) > maxBoxTags {
+		utils.BadRequestError(w, fmt.Sprintf("A box can have at most %d tags", maxBoxTags))
 		return
 	}
 
-	log.Printf("QRHandler.CreateBox: Creating box for user %s with name: %s", userID, request.Name)
+	for i := range request.Boxes {
+		if problem := createBoxProblem(&request.Boxes[i]); problem != "" {
+			utils.BadRequestError(w, fmt.Sprintf("Box %d: %s", i+1, problem))
+			return
+		}
+	}
 
-	// Create box with QR code
-	response, err := h.qrService.CreateBox(userID, &request)
+	// Check the label template before any box is created
+	var template models.LabelTemplate
+	if request.LabelTemplate != "" {
+		if template, err = services.GetLabelTemplate(request.LabelTemplate); err != nil {
+			utils.BadRequestError(w, "Unknown label template")
+			return
+		}
+	}
+
+	log.Printf("QRHandler.CreateBoxes: Creating %d boxes for user %s", max(len(request.Boxes), request.Count), userID)
+
+	response, err := h.qrService.CreateBoxes(userID, &request)
 	if err != nil {
-		log.Printf("QRHandler.CreateBox: Failed to create box: %v", err)
-		utils.InternalServerError(w, "Failed to create box")
+		log.Printf("QRHandler.CreateBoxes: Failed to create boxes: %v", err)
+		writeCreateBoxError(w, err, "Failed to create boxes")
 		return
 	}
 
-	log.Printf("QRHandler.CreateBox: Successfully created box %s for user %s", response.Box.ID, userID)
+	if request.LabelTemplate != "" {
+		// The boxes exist by now, so a failed sheet is reported without
+		// failing the request; it can be printed again from /api/boxes/labels
+		if response.LabelSheet, err = h.labelService.RenderLabels(template, response.Boxes); err != nil {
+			log.Printf("QRHandler.CreateBoxes: Failed to render label sheet: %v", err)
+			response.Message += ", but the label sheet could not be generated"
+		}
+	}
+
+	for _, box := range response.Boxes {
+		omitQRCode(box)
+	}
+
+	log.Printf("QRHandler.CreateBoxes: Successfully created %d boxes for user %s", response.Count, userID)
 	utils.CreatedResponse(w, response)
 }
 
+// createBoxProblem checks the fields of a box request and describes the first
+// problem found, or returns an empty string if there is none
+func createBoxProblem(request *models.CreateBoxRequest) string {
+	switch {
+	case request.Name == "":
+		return "Box name is required"
+	case len(request.Name) > 100:
+		return "Box name must be less than 100 characters"
+	case len(request.Description) > 500:
+		return "Description must be less than 500 characters"
+	case len(request.Room) > 100:
+		return "Room must be less than 100 characters"
+	case len(request.Items) > 1000:
+		return "Items list must be less than 1000 characters"
+	case len(request.TagIDs) > maxBoxTags:
+		return fmt.Sprintf("A box can have at most %d tags", maxBoxTags)
+	}
+	return ""
+}
+
+// writeCreateBoxError maps box creation errors to HTTP responses
+func writeCreateBoxError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "location not found":
+		utils.BadRequestError(w, "Location not found")
+	case "parent box not found":
+		utils.BadRequestError(w, "Parent box not found")
+	case "tag not found":
+		utils.BadRequestError(w, "Tag not found")
+	case "workspace not found":
+		utils.BadRequestError(w, "Workspace not found")
+	case "forbidden":
+		utils.ForbiddenError(w, forbiddenMessage)
+	case "invalid visibility":
+		utils.BadRequestError(w, "Visibility must be public, name_only, pin or private")
+	case "invalid pin":
+		utils.BadRequestError(w, "PIN must be 4 to 12 digits")
+	case "pin required":
+		utils.BadRequestError(w, "A PIN is required for PIN-protected boxes")
+	case "pin requires pin visibility":
+		utils.BadRequestError(w, "A PIN can only be set on PIN-protected boxes")
+	case "invalid box batch":
+		utils.BadRequestError(w, "Either a list of boxes or a count is required")
+	default:
+		utils.InternalServerError(w, fallback)
+	}
+}
+
+// maxListLimit caps the page size a client can request from GetUserBoxes
+const maxListLimit = 200
+
+// maxBatchBoxes caps how many boxes CreateBoxes makes in one request
+const maxBatchBoxes = 200
+
+// maxBoxTags caps the tags on one box and the tags in one filter
+const maxBoxTags = 50
+
+// maxLogoUploadSize is the largest QR label logo accepted, in bytes
+const maxLogoUploadSize = 1 << 20
+
+// forbiddenMessage is returned when the user's workspace role doesn't allow a request
+const forbiddenMessage = "Your role in this workspace does not allow this"
+
+// parseTagFilter reads the comma-separated tags parameter and tagMatch (all or
+// any) and writes the error response itself
+func parseTagFilter(w http.ResponseWriter, r *http.Request) (models.TagFilter, bool) {
+	query := r.URL.Query()
+	filter := models.TagFilter{Match: query.Get("tagMatch")}
+
+	for _, tagID := range strings.Split(query.Get("tags"), ",") {
+		tagID = strings.TrimSpace(tagID)
+		if tagID == "" {
+			continue
+		}
+		if _, err := uuid.Parse(tagID); err != nil {
+			utils.BadRequestError(w, "Invalid tag ID")
+			return filter, false
+		}
+		filter.TagIDs = append(filter.TagIDs, tagID)
+	}
+
+	if len(filter.TagIDs) > maxBoxTags {
+		utils.BadRequestError(w, fmt.Sprintf("Filter by at most %d tags", maxBoxTags))
+		return filter, false
+	}
+
+	return filter, true
+}
+
+// parseBoxFields reads the comma-separated fields parameter, which brings back
+// fields box lists leave out, and writes the error response itself
+func parseBoxFields(w http.ResponseWriter, r *http.Request) (includeQRCode bool, ok bool) {
+	for _, field := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		switch strings.TrimSpace(field) {
+		case "":
+		case models.BoxFieldQRCode:
+			includeQRCode = true
+		default:
+			utils.BadRequestError(w, fmt.Sprintf("Unknown field, fields can only include %s", models.BoxFieldQRCode))
+			return false, false
+		}
+	}
+	return includeQRCode, true
+}
+
+// omitQRCode drops the base64 QR code from a box in a list, where it would
+// repeat for every box; GetBoxQRImage serves it instead
+func omitQRCode(box *models.Box) {
+	box.QRCode = ""
+}
+
+// GetUserBoxes lists the user's boxes. Without a limit every matching box is
+// returned; with one, nextCursor in the response fetches the following page.
 func (h *QRHandler) GetUserBoxes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		utils.BadRequestError(w, "Method not allowed")
@@ -97,22 +300,164 @@ func (h *QRHandler) GetUserBoxes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Parse paging, sorting and filter parameters
+	query := r.URL.Query()
+	options := models.BoxListOptions{
+		Cursor: query.Get("cursor"),
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+		Room:   query.Get("room"),
+	}
+
+	if locationID := query.Get("locationId"); locationID != "" {
+		if _, err := uuid.Parse(locationID); err != nil {
+			utils.BadRequestError(w, "Invalid location ID")
+			return
+		}
+		options.LocationID = locationID
+	}
+
+	if workspaceID := query.Get("workspaceId"); workspaceID != "" {
+		if _, err := uuid.Parse(workspaceID); err != nil {
+			utils.BadRequestError(w, "Invalid workspace ID")
+			return
+		}
+		options.WorkspaceID = workspaceID
+	}
+
+	tags, ok := parseTagFilter(w, r)
+	if !ok {
+		return
+	}
+	options.Tags = tags
+
+	includeQRCode, ok := parseBoxFields(w, r)
+	if !ok {
+		return
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		options.Limit, err = strconv.Atoi(limit)
+		if err != nil || options.Limit < 1 || options.Limit > maxListLimit {
+			utils.BadRequestError(w, fmt.Sprintf("Limit must be between 1 and %d", maxListLimit))
+			return
+		}
+	}
+
+	if since := query.Get("updatedSince"); since != "" {
+		updatedSince, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			utils.BadRequestError(w, "updatedSince must be an RFC 3339 timestamp")
+			return
+		}
+		options.UpdatedSince = &updatedSince
+	}
+
 	log.Printf("QRHandler.GetUserBoxes: Fetching boxes for user %s", userID)
 
 	// Get user's boxes
-	boxes, err := h.qrService.GetUserBoxes(userID)
+	page, err := h.qrService.ListUserBoxes(userID, options)
 	if err != nil {
 		log.Printf("QRHandler.GetUserBoxes: Failed to fetch boxes: %v", err)
-		utils.InternalServerError(w, "Failed to fetch boxes")
+		switch err.Error() {
+		case "invalid sort field":
+			utils.BadRequestError(w, "Sort must be one of name, room, created or updated")
+		case "invalid sort order":
+			utils.BadRequestError(w, "Order must be asc or desc")
+		case "invalid cursor":
+			utils.BadRequestError(w, "Invalid cursor")
+		case "invalid tag match":
+			utils.BadRequestError(w, "tagMatch must be all or any")
+		default:
+			utils.InternalServerError(w, "Failed to fetch boxes")
+		}
+		return
+	}
+
+	if !includeQRCode {
+		for _, box := range page.Boxes {
+			omitQRCode(box)
+		}
+	}
+
+	log.Printf("QRHandler.GetUserBoxes: Successfully fetched %d of %d boxes for user %s", page.Count, page.Total, userID)
+	utils.SuccessResponse(w, page)
+}
+
+// SearchBoxes runs a ranked full-text search over the user's boxes
+func (h *QRHandler) SearchBoxes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("QRHandler.SearchBoxes: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		utils.BadRequestError(w, "Search query is required")
+		return
+	}
+
+	if len(query) > 200 {
+		utils.BadRequestError(w, "Search query must be less than 200 characters")
+		return
+	}
+
+	limit := 20
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > 100 {
+			utils.BadRequestError(w, "Limit must be between 1 and 100")
+			return
+		}
+	}
+
+	tags, ok := parseTagFilter(w, r)
+	if !ok {
+		return
+	}
+
+	includeQRCode, ok := parseBoxFields(w, r)
+	if !ok {
 		return
 	}
 
+	log.Printf("QRHandler.SearchBoxes: Searching boxes for user %s", userID)
+
+	results, err := h.qrService.SearchBoxes(userID, query, limit, tags)
+	if err != nil {
+		log.Printf("QRHandler.SearchBoxes: Failed to search boxes: %v", err)
+		switch err.Error() {
+		case "empty search query":
+			utils.BadRequestError(w, "Search query must contain letters or numbers")
+		case "invalid tag match":
+			utils.BadRequestError(w, "tagMatch must be all or any")
+		default:
+			utils.InternalServerError(w, "Failed to search boxes")
+		}
+		return
+	}
+
+	if !includeQRCode {
+		for _, result := range results {
+			omitQRCode(result.Box)
+		}
+	}
+
 	response := map[string]interface{}{
-		"boxes": boxes,
-		"count": len(boxes),
+		"query":   query,
+		"results": results,
+		"count":   len(results),
 	}
 
-	log.Printf("QRHandler.GetUserBoxes: Successfully fetched %d boxes for user %s", len(boxes), userID)
+	log.Printf("QRHandler.SearchBoxes: Found %d boxes for user %s", len(results), userID)
 	utils.SuccessResponse(w, response)
 }
 
@@ -122,6 +467,14 @@ func (h *QRHandler) GetBoxByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("QRHandler.GetBoxByID: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
 	// Extract box ID from URL path
 	// For now, we'll expect it as a query parameter
 	boxID := r.URL.Query().Get("id")
@@ -130,12 +483,16 @@ func (h *QRHandler) GetBoxByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("QRHandler.GetBoxByID: Fetching box %s", boxID)
+	log.Printf("QRHandler.GetBoxByID: Fetching box %s for user %s", boxID, userID)
 
-	// Get box by ID
-	box, err := h.qrService.GetBoxByID(boxID)
+	// Get box by ID, checking the user may view it
+	box, err := h.qrService.GetUserBox(userID, boxID)
 	if err != nil {
 		log.Printf("QRHandler.GetBoxByID: Failed to fetch box: %v", err)
+		if err.Error() == "forbidden" {
+			utils.ForbiddenError(w, forbiddenMessage)
+			return
+		}
 		utils.NotFoundError(w, "Box not found")
 		return
 	}
@@ -194,15 +551,39 @@ func (h *QRHandler) UpdateBox(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if request.TagIDs != nil && len(*request.TagIDs) > maxBoxTags {
+		utils.BadRequestError(w, fmt.Sprintf("A box can have at most %d tags", maxBoxTags))
+		return
+	}
+
 	log.Printf("QRHandler.UpdateBox: Updating box %s for user %s", boxID, userID)
 
 	// Update box
 	box, err := h.qrService.UpdateBox(userID, boxID, &request)
 	if err != nil {
 		log.Printf("QRHandler.UpdateBox: Failed to update box: %v", err)
-		if err.Error() == "unauthorized" {
-			utils.UnauthorizedError(w, "Box not found or unauthorized")
-		} else {
+		switch err.Error() {
+		case "box not found":
+			utils.NotFoundError(w, "Box not found")
+		case "forbidden":
+			utils.ForbiddenError(w, forbiddenMessage)
+		case "location not found":
+			utils.BadRequestError(w, "Location not found")
+		case "parent box not found":
+			utils.BadRequestError(w, "Parent box not found")
+		case "box cannot be placed inside itself":
+			utils.BadRequestError(w, "A box cannot be placed inside itself or a box it contains")
+		case "tag not found":
+			utils.BadRequestError(w, "Tag not found")
+		case "invalid visibility":
+			utils.BadRequestError(w, "Visibility must be public, name_only, pin or private")
+		case "invalid pin":
+			utils.BadRequestError(w, "PIN must be 4 to 12 digits")
+		case "pin required":
+			utils.BadRequestError(w, "A PIN is required for PIN-protected boxes")
+		case "pin requires pin visibility":
+			utils.BadRequestError(w, "A PIN can only be set on PIN-protected boxes")
+		default:
 			utils.InternalServerError(w, "Failed to update box")
 		}
 		return
@@ -239,9 +620,12 @@ func (h *QRHandler) DeleteBox(w http.ResponseWriter, r *http.Request) {
 	err = h.qrService.DeleteBox(userID, boxID)
 	if err != nil {
 		log.Printf("QRHandler.DeleteBox: Failed to delete box: %v", err)
-		if err.Error() == "box not found or unauthorized" {
-			utils.NotFoundError(w, "Box not found or unauthorized")
-		} else {
+		switch err.Error() {
+		case "box not found", "box not found or unauthorized":
+			utils.NotFoundError(w, "Box not found")
+		case "forbidden":
+			utils.ForbiddenError(w, forbiddenMessage)
+		default:
 			utils.InternalServerError(w, "Failed to delete box")
 		}
 		return
@@ -275,8 +659,32 @@ func (h *QRHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Total quantities per item name across all boxes
+	itemTotals, err := h.qrService.GetItemTotals(userID)
+	if err != nil {
+		log.Printf("QRHandler.GetUserStats: Failed to fetch item totals: %v", err)
+		utils.InternalServerError(w, "Failed to fetch user statistics")
+		return
+	}
+
+	// Number of boxes carrying each tag
+	tagCounts, err := h.qrService.GetTagCounts(userID)
+	if err != nil {
+		log.Printf("QRHandler.GetUserStats: Failed to fetch tag counts: %v", err)
+		utils.InternalServerError(w, "Failed to fetch user statistics")
+		return
+	}
+
+	totalItems := 0
+	for _, total := range itemTotals {
+		totalItems += total.Quantity
+	}
+
 	stats := map[string]interface{}{
 		"totalBoxes": boxCount,
+		"totalItems": totalItems,
+		"itemTotals": itemTotals,
+		"tagCounts":  tagCounts,
 		"userID":     userID,
 	}
 
@@ -284,8 +692,10 @@ func (h *QRHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
 	utils.SuccessResponse(w, stats)
 }
 
+// GetBoxQR returns a box with its stored QR code, or renders the code as a
+// file with the requested options. POST a logo to put it in the middle.
 func (h *QRHandler) GetBoxQR(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
 		utils.BadRequestError(w, "Method not allowed")
 		return
 	}
@@ -305,30 +715,322 @@ func (h *QRHandler) GetBoxQR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A label's logo is posted as a multipart form
+	var logo []byte
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxLogoUploadSize+1<<20)
+		if err := r.ParseMultipartForm(maxLogoUploadSize); err != nil {
+			log.Printf("QRHandler.GetBoxQR: Failed to parse form: %v", err)
+			utils.BadRequestError(w, "Invalid upload, logos must be smaller than 1MB")
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		file, _, err := r.FormFile("logo")
+		if err != nil {
+			utils.BadRequestError(w, "Logo file is required")
+			return
+		}
+		defer file.Close()
+
+		if logo, err = io.ReadAll(io.LimitReader(file, maxLogoUploadSize+1)); err != nil {
+			log.Printf("QRHandler.GetBoxQR: Failed to read logo: %v", err)
+			utils.BadRequestError(w, "Invalid upload")
+			return
+		}
+	}
+
+	// A format, caption or logo renders the QR code on demand as a file
+	// instead of the stored base64 PNG
+	query := r.URL.Query()
+	if query.Get("format") != "" || query.Get("caption") != "" || logo != nil {
+		h.renderBoxQR(w, userID, boxID, query, logo)
+		return
+	}
+
 	log.Printf("QRHandler.GetBoxQR: Fetching QR for box %s, user %s", boxID, userID)
 
-	// Get box to verify ownership and retrieve QR data
-	box, err := h.qrService.GetBoxByID(boxID)
+	// Get box, verifying membership, to retrieve QR data
+	box, err := h.qrService.GetUserBox(userID, boxID)
 	if err != nil {
+		if err.Error() == "forbidden" {
+			log.Printf("QRHandler.GetBoxQR: Forbidden access attempt for box %s by user %s", boxID, userID)
+			utils.ForbiddenError(w, forbiddenMessage)
+			return
+		}
 		log.Printf("QRHandler.GetBoxQR: Failed to fetch box: %v", err)
 		utils.NotFoundError(w, "Box not found")
 		return
 	}
 
-	// Verify ownership
-	if box.UserID != userID {
-		log.Printf("QRHandler.GetBoxQR: Unauthorized access attempt for box %s by user %s", boxID, userID)
-		utils.UnauthorizedError(w, "Unauthorized access to box")
-		return
-	}
-
 	// Return box with QR data
 	log.Printf("QRHandler.GetBoxQR: Successfully retrieved QR for box %s", boxID)
 	utils.SuccessResponse(w, box)
 }
 
+// qrImageMaxAge is how long clients may use a QR image before checking its ETag
+const qrImageMaxAge = 24 * time.Hour
+
+// GetBoxQRImage serves a box's QR code as a PNG or SVG image rather than
+// base64 inside JSON. The ETag lets clients revalidate cheaply; it only
+// changes when the code is regenerated.
+func (h *QRHandler) GetBoxQRImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("QRHandler.GetBoxQRImage: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	boxID := r.URL.Query().Get("id")
+	if boxID == "" {
+		utils.BadRequestError(w, "Box ID is required")
+		return
+	}
+
+	image, err := h.qrService.BoxQRImage(userID, boxID, r.URL.Query().Get("format"))
+	if err != nil {
+		log.Printf("QRHandler.GetBoxQRImage: Failed to render QR for box %s: %v", boxID, err)
+		switch err.Error() {
+		case "box not found":
+			utils.NotFoundError(w, "Box not found")
+		case "forbidden":
+			utils.ForbiddenError(w, forbiddenMessage)
+		case "invalid qr image format":
+			utils.BadRequestError(w, "Format must be png or svg")
+		default:
+			utils.InternalServerError(w, "Failed to render QR code")
+		}
+		return
+	}
+
+	// The image is private to the box's workspace, so only the browser may cache it
+	sum := sha256.Sum256(image.Data)
+	etag := fmt.Sprintf(`"%x"`, sum[:16])
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(qrImageMaxAge.Seconds())))
+	w.Header().Set("Vary", "Authorization")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", image.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(image.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(image.Data)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly as the header requires
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// RedirectToBox sends a scanned QR code at /q/{code} on to the box's page on
+// the current frontend (no authentication required). The redirect is temporary
+// so that browsers ask again after the frontend moves.
+func (h *QRHandler) RedirectToBox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	code := strings.TrimPrefix(r.URL.Path, "/q/")
+	if code == "" {
+		utils.BadRequestError(w, "Box code is required")
+		return
+	}
+
+	target, err := h.qrService.BoxPageURL(code)
+	if err != nil {
+		log.Printf("QRHandler.RedirectToBox: Failed to resolve box %s: %v", code, err)
+		utils.NotFoundError(w, "Box not found")
+		return
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// ScanPhoto reads the QR codes in an uploaded photo, sent as "photo" in a
+// multipart form, and returns the boxes they lead to that the user can view
+// along with the codes it didn't recognise
+func (h *QRHandler) ScanPhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("QRHandler.ScanPhoto: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotoUploadSize+1<<20)
+	if err := r.ParseMultipartForm(maxPhotoUploadSize); err != nil {
+		log.Printf("QRHandler.ScanPhoto: Failed to parse form: %v", err)
+		utils.BadRequestError(w, "Invalid upload, photos must be smaller than 10MB")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("photo")
+	if err != nil {
+		utils.BadRequestError(w, "Photo file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxPhotoUploadSize+1))
+	if err != nil {
+		log.Printf("QRHandler.ScanPhoto: Failed to read photo: %v", err)
+		utils.BadRequestError(w, "Invalid upload")
+		return
+	}
+
+	if len(data) > maxPhotoUploadSize {
+		utils.BadRequestError(w, "Photos must be smaller than 10MB")
+		return
+	}
+
+	log.Printf("QRHandler.ScanPhoto: Scanning %d byte photo for user %s", len(data), userID)
+
+	result, err := h.qrService.ScanPhoto(r.Context(), userID, data)
+	if err != nil {
+		log.Printf("QRHandler.ScanPhoto: Failed to scan photo: %v", err)
+		switch err.Error() {
+		case "invalid image":
+			utils.BadRequestError(w, "The photo could not be read as an image")
+		case "image is too large":
+			utils.BadRequestError(w, "The photo's dimensions are too large")
+		case "no qr code found":
+			utils.ErrorResponse(w, http.StatusUnprocessableEntity, "No QR code could be read in the photo")
+		case "scan timed out":
+			utils.ErrorResponse(w, http.StatusUnprocessableEntity, "No QR code could be read in the photo in time, try one taken closer to the code")
+		default:
+			utils.InternalServerError(w, "Failed to scan photo")
+		}
+		return
+	}
+
+	for _, box := range result.Boxes {
+		omitQRCode(box)
+	}
+
+	log.Printf("QRHandler.ScanPhoto: Found %d boxes and %d unrecognised codes for user %s", len(result.Boxes), len(result.Unrecognised), userID)
+	utils.SuccessResponse(w, result)
+}
+
+// renderBoxQR writes a box's QR code rendered with the options in query:
+// format, size, dpi, level, fg, bg, border and caption, and with logo over
+// its middle when there is one
+func (h *QRHandler) renderBoxQR(w http.ResponseWriter, userID, boxID string, query url.Values, logo []byte) {
+	options := models.QRRenderOptions{
+		Format:     query.Get("format"),
+		Level:      query.Get("level"),
+		Foreground: query.Get("fg"),
+		Background: query.Get("bg"),
+		Caption:    query.Get("caption"),
+		Logo:       logo,
+	}
+
+	var err error
+	if value := query.Get("size"); value != "" {
+		if options.Size, err = strconv.Atoi(value); err != nil {
+			utils.BadRequestError(w, "Size must be a number of pixels")
+			return
+		}
+	}
+
+	if value := query.Get("dpi"); value != "" {
+		if options.DPI, err = strconv.Atoi(value); err != nil {
+			utils.BadRequestError(w, "DPI must be a number")
+			return
+		}
+	}
+
+	if value := query.Get("border"); value != "" {
+		border, err := strconv.Atoi(value)
+		if err != nil {
+			utils.BadRequestError(w, "Border must be a number of modules")
+			return
+		}
+		options.Border = &border
+	}
+
+	log.Printf("QRHandler.GetBoxQR: Rendering %s QR for box %s, user %s", options.Format, boxID, userID)
+
+	image, err := h.qrService.RenderBoxQR(userID, boxID, options)
+	if err != nil {
+		log.Printf("QRHandler.GetBoxQR: Failed to render QR: %v", err)
+		switch err.Error() {
+		case "box not found":
+			utils.NotFoundError(w, "Box not found")
+		case "forbidden":
+			utils.ForbiddenError(w, forbiddenMessage)
+		case "invalid qr format":
+			utils.BadRequestError(w, "Format must be png, svg, pdf or eps")
+		case "invalid qr size":
+			utils.BadRequestError(w, "Size must be between 64 and 4096 pixels")
+		case "invalid qr dpi":
+			utils.BadRequestError(w, "DPI must be between 1 and 2400")
+		case "invalid qr error correction level":
+			utils.BadRequestError(w, "Error correction level must be L, M, Q or H")
+		case "invalid qr border":
+			utils.BadRequestError(w, "Border must be between 0 and 16 modules")
+		case "invalid qr color":
+			utils.BadRequestError(w, "Colors must be hex values such as 1a2b3c")
+		case "qr colors lack contrast":
+			utils.BadRequestError(w, "The foreground must be much darker than the background for the code to scan")
+		case "qr size too small":
+			utils.BadRequestError(w, "Size is too small for this code, use at least one pixel per module")
+		case "invalid qr caption":
+			utils.BadRequestError(w, "Caption must be name or code")
+		case "invalid qr label format":
+			utils.BadRequestError(w, "Labels with a caption or logo can only be png or svg")
+		case "qr logo requires level H":
+			utils.BadRequestError(w, "A logo needs error correction level H")
+		case "qr logo too large":
+			utils.BadRequestError(w, "Logos must be smaller than 1MB")
+		case "invalid qr logo":
+			utils.BadRequestError(w, "Logo must be a PNG, JPEG, GIF or WebP image")
+		case "qr label does not decode":
+			utils.ErrorResponse(w, http.StatusUnprocessableEntity, "The label would not scan with this logo, try a simpler or smaller logo or a larger size")
+		default:
+			utils.InternalServerError(w, "Failed to render QR code")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", image.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="box-qr.%s"`, image.Extension))
+	w.Header().Set("Content-Length", strconv.Itoa(len(image.Data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(image.Data)
+}
+
 // GetPublicBoxDetails returns box details for public access (no authentication required)
-// This is used when someone scans a QR code
+// This is used when someone scans a QR code. What is shown follows the box's
+// visibility: name-only and PIN boxes show just their name, private boxes are not found.
 func (h *QRHandler) GetPublicBoxDetails(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		utils.BadRequestError(w, "Method not allowed")
@@ -345,27 +1047,135 @@ func (h *QRHandler) GetPublicBoxDetails(w http.ResponseWriter, r *http.Request)
 	log.Printf("QRHandler.GetPublicBoxDetails: Fetching public box details for %s", boxID)
 
 	// Get box by ID (public access)
-	box, err := h.qrService.GetBoxByID(boxID)
+	box, unlocked, err := h.qrService.GetPublicBox(boxID)
 	if err != nil {
 		log.Printf("QRHandler.GetPublicBoxDetails: Failed to fetch box: %v", err)
 		utils.NotFoundError(w, "Box not found")
 		return
 	}
 
-	// Create a public response without sensitive data
-	publicBox := map[string]interface{}{
-		"id":          box.ID,
-		"name":        box.Name,
-		"description": box.Description,
-		"room":        box.Room,
-		"items":       box.Items,
-		"createdAt":   box.CreatedAt,
+	if !unlocked {
+		log.Printf("QRHandler.GetPublicBoxDetails: Showing only the name of %s box %s", box.Visibility, boxID)
+		utils.SuccessResponse(w, map[string]interface{}{
+			"id":         box.ID,
+			"name":       box.Name,
+			"visibility": box.Visibility,
+			"locked":     box.Visibility == models.VisibilityPIN,
+		})
+		return
+	}
+
+	publicBox, err := publicBoxDetails(h.qrService, box)
+	if err != nil {
+		log.Printf("QRHandler.GetPublicBoxDetails: Failed to fetch box contents: %v", err)
+		utils.InternalServerError(w, "Failed to fetch box details")
+		return
 	}
 
 	log.Printf("QRHandler.GetPublicBoxDetails: Successfully fetched public box details for %s", boxID)
 	utils.SuccessResponse(w, publicBox)
 }
 
+// UnlockPublicBox returns the public details of a PIN-protected box when the
+// right PIN is given (no authentication required). Wrong guesses are rate
+// limited per client and slow down further guesses on the box.
+func (h *QRHandler) UnlockPublicBox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	var request struct {
+		ID  string `json:"id"`
+		PIN string `json:"pin"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("QRHandler.UnlockPublicBox: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	if request.ID == "" {
+		utils.BadRequestError(w, "Box ID is required")
+		return
+	}
+
+	if request.PIN == "" {
+		utils.BadRequestError(w, "PIN is required")
+		return
+	}
+
+	client := clientAddress(r)
+	log.Printf("QRHandler.UnlockPublicBox: Unlock attempt for box %s from %s", request.ID, client)
+
+	box, retryAfter, err := h.qrService.UnlockPublicBox(request.ID, request.PIN, client)
+	if err != nil {
+		log.Printf("QRHandler.UnlockPublicBox: Failed to unlock box %s: %v", request.ID, err)
+		switch err.Error() {
+		case "box not found":
+			utils.NotFoundError(w, "Box not found")
+		case "box is not pin protected":
+			utils.BadRequestError(w, "This box is not PIN protected")
+		case "incorrect pin":
+			utils.ForbiddenError(w, "Incorrect PIN")
+		case "too many attempts":
+			// Whole seconds, rounded up so the next try isn't refused again
+			w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+			utils.ErrorResponse(w, http.StatusTooManyRequests, "Too many PIN attempts, try again later")
+		default:
+			utils.InternalServerError(w, "Failed to unlock box")
+		}
+		return
+	}
+
+	// The box's photos aren't public, so their links carry proof of the unlock
+	h.photoService.GrantPhotos(box)
+
+	publicBox, err := publicBoxDetails(h.qrService, box)
+	if err != nil {
+		log.Printf("QRHandler.UnlockPublicBox: Failed to fetch box contents: %v", err)
+		utils.InternalServerError(w, "Failed to fetch box details")
+		return
+	}
+
+	log.Printf("QRHandler.UnlockPublicBox: Unlocked box %s", request.ID)
+	utils.SuccessResponse(w, publicBox)
+}
+
+// publicBoxDetails builds the public scan page response for a box whose
+// details may be shown, without sensitive data
+func publicBoxDetails(qrService *services.QRService, box *models.Box) (map[string]interface{}, error) {
+	// Include the boxes packed inside, all the way down
+	contents, err := qrService.GetPublicBoxContents(box)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"id":           box.ID,
+		"name":         box.Name,
+		"visibility":   box.Visibility,
+		"description":  box.Description,
+		"room":         box.Room,
+		"locationPath": box.LocationPath,
+		"items":        box.Items,
+		"photos":       box.Photos,
+		"boxes":        contents.Boxes,
+		"createdAt":    box.CreatedAt,
+	}, nil
+}
+
+// clientAddress identifies the caller by their IP address, which
+// middleware.ClientIP takes from the proxy when there is one
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // AddItemToBox adds a single item to an existing box
 func (h *QRHandler) AddItemToBox(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -415,8 +1225,10 @@ func (h *QRHandler) AddItemToBox(w http.ResponseWriter, r *http.Request) {
 	box, err := h.qrService.AddItemToBox(userID, request.BoxID, request.Item)
 	if err != nil {
 		log.Printf("QRHandler.AddItemToBox: Failed to add item: %v", err)
-		if err.Error() == "unauthorized" || err.Error() == "box not found" {
-			utils.UnauthorizedError(w, "Box not found or unauthorized")
+		if err.Error() == "box not found" {
+			utils.NotFoundError(w, "Box not found")
+		} else if err.Error() == "forbidden" {
+			utils.ForbiddenError(w, forbiddenMessage)
 		} else {
 			utils.InternalServerError(w, "Failed to add item to box")
 		}
@@ -427,6 +1239,76 @@ func (h *QRHandler) AddItemToBox(w http.ResponseWriter, r *http.Request) {
 	utils.SuccessResponse(w, box)
 }
 
+// maxMoveItems caps how many items a single MoveItems request can move
+const maxMoveItems = 500
+
+// MoveItems moves one or more items from one box to another atomically
+func (h *QRHandler) MoveItems(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("QRHandler.MoveItems: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.MoveItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("QRHandler.MoveItems: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.FromBoxID == "" || request.ToBoxID == "" {
+		utils.BadRequestError(w, "Source and destination box IDs are required")
+		return
+	}
+
+	if request.FromBoxID == request.ToBoxID {
+		utils.BadRequestError(w, "Source and destination boxes must differ")
+		return
+	}
+
+	count := len(request.ItemIDs) + len(request.Items)
+	if count == 0 {
+		utils.BadRequestError(w, "At least one item is required")
+		return
+	}
+
+	if count > maxMoveItems {
+		utils.BadRequestError(w, fmt.Sprintf("Cannot move more than %d items at once", maxMoveItems))
+		return
+	}
+
+	log.Printf("QRHandler.MoveItems: Moving %d items from box %s to box %s for user %s", count, request.FromBoxID, request.ToBoxID, userID)
+
+	response, err := h.qrService.MoveItems(userID, &request)
+	if err != nil {
+		log.Printf("QRHandler.MoveItems: Failed to move items: %v", err)
+		switch err.Error() {
+		case "box not found":
+			utils.NotFoundError(w, "Box not found")
+		case "forbidden":
+			utils.ForbiddenError(w, forbiddenMessage)
+		case "item not found":
+			utils.NotFoundError(w, "Item not found in source box")
+		default:
+			utils.InternalServerError(w, "Failed to move items")
+		}
+		return
+	}
+
+	log.Printf("QRHandler.MoveItems: Successfully moved %d items to box %s for user %s", count, request.ToBoxID, userID)
+	utils.SuccessResponse(w, response)
+}
+
 // RemoveItemFromBox removes a single item from an existing box
 func (h *QRHandler) RemoveItemFromBox(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
@@ -471,8 +1353,10 @@ func (h *QRHandler) RemoveItemFromBox(w http.ResponseWriter, r *http.Request) {
 	box, err := h.qrService.RemoveItemFromBox(userID, request.BoxID, request.Item)
 	if err != nil {
 		log.Printf("QRHandler.RemoveItemFromBox: Failed to remove item: %v", err)
-		if err.Error() == "unauthorized" || err.Error() == "box not found" {
-			utils.UnauthorizedError(w, "Box not found or unauthorized")
+		if err.Error() == "box not found" {
+			utils.NotFoundError(w, "Box not found")
+		} else if err.Error() == "forbidden" {
+			utils.ForbiddenError(w, forbiddenMessage)
 		} else if err.Error() == "item not found" {
 			utils.BadRequestError(w, "Item not found in box")
 		} else {
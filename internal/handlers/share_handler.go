@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/qr-boxes/backend/internal/middleware"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/services"
+	"github.com/qr-boxes/backend/pkg/utils"
+)
+
+type ShareHandler struct {
+	shareService *services.ShareService
+	qrService    *services.QRService
+}
+
+func NewShareHandler(shareService *services.ShareService, qrService *services.QRService) *ShareHandler {
+	return &ShareHandler{
+		shareService: shareService,
+		qrService:    qrService,
+	}
+}
+
+// sharedItemRequest adds or removes an item through a share link
+type sharedItemRequest struct {
+	Token string `json:"token"`
+	BoxID string `json:"boxId"`
+	Item  string `json:"item"`
+}
+
+// CreateShare creates an expiring share link for a box, location or tag
+func (h *ShareHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("ShareHandler.CreateShare: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("ShareHandler.CreateShare: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.TargetID == "" {
+		utils.BadRequestError(w, "Target ID is required")
+		return
+	}
+
+	if request.ExpiresAt.IsZero() {
+		utils.BadRequestError(w, "Expiry date is required")
+		return
+	}
+
+	log.Printf("ShareHandler.CreateShare: Sharing %s %s for user %s until %s", request.Scope, request.TargetID, userID, request.ExpiresAt)
+
+	share, err := h.shareService.CreateShare(userID, &request)
+	if err != nil {
+		log.Printf("ShareHandler.CreateShare: Failed to create share link: %v", err)
+		writeShareError(w, err, "Failed to create share link")
+		return
+	}
+
+	log.Printf("ShareHandler.CreateShare: Successfully created share link %s for user %s", share.ID, userID)
+	utils.CreatedResponse(w, share)
+}
+
+// GetShares returns the share links of the workspaces the user may edit
+func (h *ShareHandler) GetShares(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("ShareHandler.GetShares: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	shares, err := h.shareService.GetShares(userID)
+	if err != nil {
+		log.Printf("ShareHandler.GetShares: Failed to fetch share links: %v", err)
+		utils.InternalServerError(w, "Failed to fetch share links")
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"shares": shares,
+		"count":  len(shares),
+	})
+}
+
+// RevokeShare puts a share link on the revocation list so its token stops working
+func (h *ShareHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("ShareHandler.RevokeShare: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	shareID := r.URL.Query().Get("id")
+	if shareID == "" {
+		utils.BadRequestError(w, "Share ID is required")
+		return
+	}
+
+	log.Printf("ShareHandler.RevokeShare: Revoking share link %s for user %s", shareID, userID)
+
+	if err := h.shareService.RevokeShare(userID, shareID); err != nil {
+		log.Printf("ShareHandler.RevokeShare: Failed to revoke share link: %v", err)
+		writeShareError(w, err, "Failed to revoke share link")
+		return
+	}
+
+	log.Printf("ShareHandler.RevokeShare: Successfully revoked share link %s", shareID)
+	utils.SuccessResponse(w, map[string]string{
+		"message": "Share link revoked successfully",
+	})
+}
+
+// ResolveShare returns the boxes a share link covers, in the same shape as
+// the public scan page (no authentication required)
+func (h *ShareHandler) ResolveShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		utils.BadRequestError(w, "Share token is required")
+		return
+	}
+
+	share, boxes, err := h.shareService.ResolveShare(token)
+	if err != nil {
+		log.Printf("ShareHandler.ResolveShare: Failed to resolve share link: %v", err)
+		writeShareError(w, err, "Failed to open share link")
+		return
+	}
+
+	log.Printf("ShareHandler.ResolveShare: Resolving share link %s with %d boxes", share.ID, len(boxes))
+
+	publicBoxes := make([]map[string]interface{}, 0, len(boxes))
+	for _, box := range boxes {
+		publicBox, err := publicBoxDetails(h.qrService, box)
+		if err != nil {
+			log.Printf("ShareHandler.ResolveShare: Failed to fetch box contents: %v", err)
+			utils.InternalServerError(w, "Failed to open share link")
+			return
+		}
+		publicBoxes = append(publicBoxes, publicBox)
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"share": map[string]interface{}{
+			"scope":      share.Scope,
+			"targetName": share.TargetName,
+			"canEdit":    share.CanEdit,
+			"expiresAt":  share.ExpiresAt,
+		},
+		"boxes": publicBoxes,
+		"count": len(publicBoxes),
+	})
+}
+
+// AddSharedItem adds an item to a shared box through a link with edit rights
+// (no authentication required)
+func (h *ShareHandler) AddSharedItem(w http.ResponseWriter, r *http.Request) {
+	h.editSharedItem(w, r, "AddSharedItem", h.shareService.AddSharedItem)
+}
+
+// RemoveSharedItem removes an item from a shared box through a link with edit
+// rights (no authentication required)
+func (h *ShareHandler) RemoveSharedItem(w http.ResponseWriter, r *http.Request) {
+	h.editSharedItem(w, r, "RemoveSharedItem", h.shareService.RemoveSharedItem)
+}
+
+// editSharedItem decodes a sharedItemRequest, applies edit and responds with
+// the box in its public shape
+func (h *ShareHandler) editSharedItem(w http.ResponseWriter, r *http.Request, name string, edit func(token, boxID, item string) (*models.Box, error)) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	var request sharedItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("ShareHandler.%s: Failed to decode request: %v", name, err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.Token == "" {
+		utils.BadRequestError(w, "Share token is required")
+		return
+	}
+
+	if request.BoxID == "" {
+		utils.BadRequestError(w, "Box ID is required")
+		return
+	}
+
+	if request.Item == "" {
+		utils.BadRequestError(w, "Item is required")
+		return
+	}
+
+	if len(request.Item) > 100 {
+		utils.BadRequestError(w, "Item must be less than 100 characters")
+		return
+	}
+
+	log.Printf("ShareHandler.%s: Editing items of box %s through a share link", name, request.BoxID)
+
+	box, err := edit(request.Token, request.BoxID, request.Item)
+	if err != nil {
+		log.Printf("ShareHandler.%s: Failed to edit items: %v", name, err)
+		writeShareError(w, err, "Failed to update box")
+		return
+	}
+
+	publicBox, err := publicBoxDetails(h.qrService, box)
+	if err != nil {
+		log.Printf("ShareHandler.%s: Failed to fetch box contents: %v", name, err)
+		utils.InternalServerError(w, "Failed to update box")
+		return
+	}
+
+	log.Printf("ShareHandler.%s: Successfully edited items of box %s", name, request.BoxID)
+	utils.SuccessResponse(w, publicBox)
+}
+
+// writeShareError maps share service errors to HTTP responses
+func writeShareError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "share not found":
+		utils.NotFoundError(w, "Share link not found")
+	case "share expired":
+		utils.ErrorResponse(w, http.StatusGone, "This share link has expired")
+	case "share revoked":
+		utils.ErrorResponse(w, http.StatusGone, "This share link has been revoked")
+	case "invalid share scope":
+		utils.BadRequestError(w, "Scope must be box, location or tag")
+	case "share expiry must be in the future":
+		utils.BadRequestError(w, "Expiry date must be in the future")
+	case "share expiry too far ahead":
+		utils.BadRequestError(w, "Share links can last at most a year")
+	case "box not found":
+		utils.NotFoundError(w, "Box not found")
+	case "location not found":
+		utils.NotFoundError(w, "Location not found")
+	case "tag not found":
+		utils.NotFoundError(w, "Tag not found")
+	case "item not found":
+		utils.NotFoundError(w, "Item not found")
+	case "share is read only":
+		utils.ForbiddenError(w, "This share link does not allow changes")
+	case "forbidden":
+		utils.ForbiddenError(w, forbiddenMessage)
+	default:
+		utils.InternalServerError(w, fallback)
+	}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/qr-boxes/backend/internal/middleware"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/services"
+	"github.com/qr-boxes/backend/pkg/utils"
+)
+
+type TagHandler struct {
+	tagService *services.TagService
+}
+
+func NewTagHandler(tagService *services.TagService) *TagHandler {
+	return &TagHandler{
+		tagService: tagService,
+	}
+}
+
+// CreateTag adds a coloured tag the user can put on boxes
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("TagHandler.CreateTag: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("TagHandler.CreateTag: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if !validateTagName(w, request.Name) {
+		return
+	}
+
+	log.Printf("TagHandler.CreateTag: Creating tag %q for user %s", request.Name, userID)
+
+	tag, err := h.tagService.CreateTag(userID, &request)
+	if err != nil {
+		log.Printf("TagHandler.CreateTag: Failed to create tag: %v", err)
+		writeTagError(w, err, "Failed to create tag")
+		return
+	}
+
+	log.Printf("TagHandler.CreateTag: Successfully created tag %s for user %s", tag.ID, userID)
+	utils.CreatedResponse(w, tag)
+}
+
+// GetUserTags returns the user's tags with the number of boxes carrying each
+func (h *TagHandler) GetUserTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("TagHandler.GetUserTags: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	tags, err := h.tagService.GetUserTags(userID)
+	if err != nil {
+		log.Printf("TagHandler.GetUserTags: Failed to fetch tags: %v", err)
+		utils.InternalServerError(w, "Failed to fetch tags")
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"tags":  tags,
+		"count": len(tags),
+	})
+}
+
+// UpdateTag renames or recolours a tag
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("TagHandler.UpdateTag: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	tagID := r.URL.Query().Get("id")
+	if tagID == "" {
+		utils.BadRequestError(w, "Tag ID is required")
+		return
+	}
+
+	// Parse request body
+	var request models.UpdateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("TagHandler.UpdateTag: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.Name != nil && !validateTagName(w, *request.Name) {
+		return
+	}
+
+	log.Printf("TagHandler.UpdateTag: Updating tag %s for user %s", tagID, userID)
+
+	tag, err := h.tagService.UpdateTag(userID, tagID, &request)
+	if err != nil {
+		log.Printf("TagHandler.UpdateTag: Failed to update tag: %v", err)
+		writeTagError(w, err, "Failed to update tag")
+		return
+	}
+
+	log.Printf("TagHandler.UpdateTag: Successfully updated tag %s", tagID)
+	utils.SuccessResponse(w, tag)
+}
+
+// MergeTags folds one or more tags into another, moving their boxes across
+func (h *TagHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("TagHandler.MergeTags: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.MergeTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("TagHandler.MergeTags: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.TargetID == "" {
+		utils.BadRequestError(w, "Target tag ID is required")
+		return
+	}
+
+	if len(request.SourceIDs) == 0 {
+		utils.BadRequestError(w, "At least one tag to merge is required")
+		return
+	}
+
+	if len(request.SourceIDs) > maxBoxTags {
+		utils.BadRequestError(w, "Too many tags to merge at once")
+		return
+	}
+
+	log.Printf("TagHandler.MergeTags: Merging %d tags into %s for user %s", len(request.SourceIDs), request.TargetID, userID)
+
+	tag, err := h.tagService.MergeTags(userID, &request)
+	if err != nil {
+		log.Printf("TagHandler.MergeTags: Failed to merge tags: %v", err)
+		writeTagError(w, err, "Failed to merge tags")
+		return
+	}
+
+	log.Printf("TagHandler.MergeTags: Successfully merged tags into %s", tag.ID)
+	utils.SuccessResponse(w, tag)
+}
+
+// DeleteTag removes a tag from every box and deletes it
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("TagHandler.DeleteTag: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	tagID := r.URL.Query().Get("id")
+	if tagID == "" {
+		utils.BadRequestError(w, "Tag ID is required")
+		return
+	}
+
+	log.Printf("TagHandler.DeleteTag: Deleting tag %s for user %s", tagID, userID)
+
+	if err := h.tagService.DeleteTag(userID, tagID); err != nil {
+		log.Printf("TagHandler.DeleteTag: Failed to delete tag: %v", err)
+		writeTagError(w, err, "Failed to delete tag")
+		return
+	}
+
+	log.Printf("TagHandler.DeleteTag: Successfully deleted tag %s", tagID)
+	utils.SuccessResponse(w, map[string]string{"message": "Tag deleted successfully"})
+}
+
+// validateTagName checks a tag name and writes the error response itself
+func validateTagName(w http.ResponseWriter, name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		utils.BadRequestError(w, "Tag name is required")
+		return false
+	}
+
+	if len(name) > 50 {
+		utils.BadRequestError(w, "Tag name must be less than 50 characters")
+		return false
+	}
+
+	return true
+}
+
+// writeTagError maps tag service errors to HTTP responses
+func writeTagError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "tag not found":
+		utils.NotFoundError(w, "Tag not found")
+	case "tag already exists":
+		utils.BadRequestError(w, "A tag with this name already exists, merge the tags instead")
+	case "invalid tag color":
+		utils.BadRequestError(w, "Tag colour must be a hex colour like #ff8800")
+	case "tag cannot be merged into itself":
+		utils.BadRequestError(w, "A tag cannot be merged into itself")
+	case "forbidden":
+		utils.ForbiddenError(w, forbiddenMessage)
+	case "workspace not found":
+		utils.BadRequestError(w, "Workspace not found")
+	default:
+		utils.InternalServerError(w, fallback)
+	}
+}
@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/qr-boxes/backend/internal/middleware"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/services"
+	"github.com/qr-boxes/backend/pkg/utils"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *services.WorkspaceService
+	userService      *services.UserService
+}
+
+func NewWorkspaceHandler(workspaceService *services.WorkspaceService, userService *services.UserService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+		userService:      userService,
+	}
+}
+
+// CreateWorkspace adds a household the user can share boxes in
+func (h *WorkspaceHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("WorkspaceHandler.CreateWorkspace: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("WorkspaceHandler.CreateWorkspace: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if !validateWorkspaceName(w, request.Name) {
+		return
+	}
+
+	log.Printf("WorkspaceHandler.CreateWorkspace: Creating workspace %q for user %s", request.Name, userID)
+
+	workspace, err := h.workspaceService.CreateWorkspace(userID, &request)
+	if err != nil {
+		log.Printf("WorkspaceHandler.CreateWorkspace: Failed to create workspace: %v", err)
+		writeWorkspaceError(w, err, "Failed to create workspace")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.CreateWorkspace: Successfully created workspace %s for user %s", workspace.ID, userID)
+	utils.CreatedResponse(w, workspace)
+}
+
+// GetUserWorkspaces returns the workspaces the user belongs to with their members
+func (h *WorkspaceHandler) GetUserWorkspaces(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("WorkspaceHandler.GetUserWorkspaces: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	workspaces, err := h.workspaceService.GetUserWorkspaces(userID)
+	if err != nil {
+		log.Printf("WorkspaceHandler.GetUserWorkspaces: Failed to fetch workspaces: %v", err)
+		utils.InternalServerError(w, "Failed to fetch workspaces")
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"workspaces": workspaces,
+		"count":      len(workspaces),
+	})
+}
+
+// UpdateWorkspace renames a workspace
+func (h *WorkspaceHandler) UpdateWorkspace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("WorkspaceHandler.UpdateWorkspace: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	workspaceID := r.URL.Query().Get("id")
+	if workspaceID == "" {
+		utils.BadRequestError(w, "Workspace ID is required")
+		return
+	}
+
+	// Parse request body
+	var request models.UpdateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("WorkspaceHandler.UpdateWorkspace: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if !validateWorkspaceName(w, request.Name) {
+		return
+	}
+
+	log.Printf("WorkspaceHandler.UpdateWorkspace: Renaming workspace %s for user %s", workspaceID, userID)
+
+	workspace, err := h.workspaceService.UpdateWorkspace(userID, workspaceID, &request)
+	if err != nil {
+		log.Printf("WorkspaceHandler.UpdateWorkspace: Failed to update workspace: %v", err)
+		writeWorkspaceError(w, err, "Failed to update workspace")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.UpdateWorkspace: Successfully updated workspace %s", workspaceID)
+	utils.SuccessResponse(w, workspace)
+}
+
+// CreateInvitation invites someone to a workspace by email or with a code
+// that can be shared by hand
+func (h *WorkspaceHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("WorkspaceHandler.CreateInvitation: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("WorkspaceHandler.CreateInvitation: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if request.WorkspaceID == "" {
+		utils.BadRequestError(w, "Workspace ID is required")
+		return
+	}
+
+	if email := strings.TrimSpace(request.Email); email != "" {
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			utils.BadRequestError(w, "Invalid email address")
+			return
+		}
+	}
+
+	log.Printf("WorkspaceHandler.CreateInvitation: Inviting to workspace %s for user %s", request.WorkspaceID, userID)
+
+	invitation, err := h.workspaceService.CreateInvitation(userID, &request)
+	if err != nil {
+		log.Printf("WorkspaceHandler.CreateInvitation: Failed to create invitation: %v", err)
+		writeWorkspaceError(w, err, "Failed to create invitation")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.CreateInvitation: Successfully created invitation %s", invitation.ID)
+	utils.CreatedResponse(w, invitation)
+}
+
+// GetPendingInvitations lists the open invitations sent to the user's email addresses
+func (h *WorkspaceHandler) GetPendingInvitations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("WorkspaceHandler.GetPendingInvitations: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	emails, err := h.userService.GetUserEmails(r.Context(), userID)
+	if err != nil {
+		log.Printf("WorkspaceHandler.GetPendingInvitations: Failed to fetch user emails: %v", err)
+		utils.InternalServerError(w, "Failed to fetch invitations")
+		return
+	}
+
+	invitations, err := h.workspaceService.GetPendingInvitations(emails)
+	if err != nil {
+		log.Printf("WorkspaceHandler.GetPendingInvitations: Failed to fetch invitations: %v", err)
+		utils.InternalServerError(w, "Failed to fetch invitations")
+		return
+	}
+
+	utils.SuccessResponse(w, map[string]interface{}{
+		"invitations": invitations,
+		"count":       len(invitations),
+	})
+}
+
+// JoinWorkspace accepts an invitation by its code
+func (h *WorkspaceHandler) JoinWorkspace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("WorkspaceHandler.JoinWorkspace: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	// Parse request body
+	var request models.JoinWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("WorkspaceHandler.JoinWorkspace: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(request.Code) == "" {
+		utils.BadRequestError(w, "Invitation code is required")
+		return
+	}
+
+	// Codes without an email still work if Clerk can't be reached
+	emails, err := h.userService.GetUserEmails(r.Context(), userID)
+	if err != nil {
+		log.Printf("WorkspaceHandler.JoinWorkspace: Failed to fetch user emails: %v", err)
+	}
+
+	log.Printf("WorkspaceHandler.JoinWorkspace: User %s joining with an invitation code", userID)
+
+	workspace, err := h.workspaceService.JoinWorkspace(userID, emails, request.Code)
+	if err != nil {
+		log.Printf("WorkspaceHandler.JoinWorkspace: Failed to join workspace: %v", err)
+		writeWorkspaceError(w, err, "Failed to join workspace")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.JoinWorkspace: User %s joined workspace %s", userID, workspace.ID)
+	utils.SuccessResponse(w, workspace)
+}
+
+// LeaveWorkspace removes the user from a workspace
+func (h *WorkspaceHandler) LeaveWorkspace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("WorkspaceHandler.LeaveWorkspace: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	workspaceID := r.URL.Query().Get("id")
+	if workspaceID == "" {
+		utils.BadRequestError(w, "Workspace ID is required")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.LeaveWorkspace: User %s leaving workspace %s", userID, workspaceID)
+
+	if err := h.workspaceService.LeaveWorkspace(userID, workspaceID); err != nil {
+		log.Printf("WorkspaceHandler.LeaveWorkspace: Failed to leave workspace: %v", err)
+		writeWorkspaceError(w, err, "Failed to leave workspace")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.LeaveWorkspace: User %s left workspace %s", userID, workspaceID)
+	utils.SuccessResponse(w, map[string]string{"message": "Left workspace successfully"})
+}
+
+// UpdateMemberRole makes a member a viewer, editor or owner
+func (h *WorkspaceHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("WorkspaceHandler.UpdateMemberRole: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	workspaceID := r.URL.Query().Get("id")
+	memberID := r.URL.Query().Get("userId")
+	if workspaceID == "" || memberID == "" {
+		utils.BadRequestError(w, "Workspace ID and user ID are required")
+		return
+	}
+
+	// Parse request body
+	var request models.UpdateMemberRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("WorkspaceHandler.UpdateMemberRole: Failed to decode request: %v", err)
+		utils.BadRequestError(w, "Invalid request body")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.UpdateMemberRole: Setting role of %s in workspace %s to %q for user %s", memberID, workspaceID, request.Role, userID)
+
+	workspace, err := h.workspaceService.UpdateMemberRole(userID, workspaceID, memberID, &request)
+	if err != nil {
+		log.Printf("WorkspaceHandler.UpdateMemberRole: Failed to update role: %v", err)
+		writeWorkspaceError(w, err, "Failed to update member role")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.UpdateMemberRole: Successfully updated role of %s in workspace %s", memberID, workspaceID)
+	utils.SuccessResponse(w, workspace)
+}
+
+// RemoveMember takes someone out of a workspace
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.BadRequestError(w, "Method not allowed")
+		return
+	}
+
+	// Get user ID from the authenticated context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		log.Printf("WorkspaceHandler.RemoveMember: Failed to get user ID: %v", err)
+		utils.UnauthorizedError(w, "Invalid authentication")
+		return
+	}
+
+	workspaceID := r.URL.Query().Get("id")
+	memberID := r.URL.Query().Get("userId")
+	if workspaceID == "" || memberID == "" {
+		utils.BadRequestError(w, "Workspace ID and user ID are required")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.RemoveMember: Removing %s from workspace %s for user %s", memberID, workspaceID, userID)
+
+	if err := h.workspaceService.RemoveMember(userID, workspaceID, memberID); err != nil {
+		log.Printf("WorkspaceHandler.RemoveMember: Failed to remove member: %v", err)
+		writeWorkspaceError(w, err, "Failed to remove member")
+		return
+	}
+
+	log.Printf("WorkspaceHandler.RemoveMember: Successfully removed %s from workspace %s", memberID, workspaceID)
+	utils.SuccessResponse(w, map[string]string{"message": "Member removed successfully"})
+}
+
+// validateWorkspaceName checks a workspace name and writes the error response itself
+func validateWorkspaceName(w http.ResponseWriter, name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		utils.BadRequestError(w, "Workspace name is required")
+		return false
+	}
+
+	if len(name) > 100 {
+		utils.BadRequestError(w, "Workspace name must be less than 100 characters")
+		return false
+	}
+
+	return true
+}
+
+// writeWorkspaceError maps workspace service errors to HTTP responses
+func writeWorkspaceError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "workspace not found":
+		utils.NotFoundError(w, "Workspace not found")
+	case "invitation not found":
+		utils.NotFoundError(w, "Invitation not found")
+	case "invitation already used":
+		utils.BadRequestError(w, "This invitation has already been used")
+	case "invitation expired":
+		utils.BadRequestError(w, "This invitation has expired")
+	case "invitation is for another email":
+		utils.BadRequestError(w, "This invitation was sent to a different email address")
+	case "last member cannot leave":
+		utils.BadRequestError(w, "The last member of a workspace cannot leave it")
+	case "workspace must keep an owner":
+		utils.BadRequestError(w, "A workspace must keep at least one owner")
+	case "member not found":
+		utils.NotFoundError(w, "Member not found")
+	case "invalid role":
+		utils.BadRequestError(w, "Role must be viewer, editor or owner")
+	case "forbidden":
+		utils.ForbiddenError(w, "Only workspace owners can do this")
+	default:
+		utils.InternalServerError(w, fallback)
+	}
+}
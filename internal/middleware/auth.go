@@ -83,6 +83,19 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware validates the Clerk session token of requests that
+// carry one and lets the others through without a user
+func OptionalAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	authenticated := AuthMiddleware(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	}
+}
+
 // GetUserID retrieves the user ID from the context
 func GetUserID(ctx context.Context) (string, error) {
 	claims, ok := ctx.Value("user_claims").(*clerk.SessionClaims)
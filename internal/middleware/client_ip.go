@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP replaces the request's remote address with the client address that
+// a reverse proxy passes in header, such as Fly-Client-IP or X-Forwarded-For.
+// It must only be used behind a proxy that sets the header, since clients can
+// send it themselves. With an empty header the connection's address is kept.
+func ClientIP(header string, next http.Handler) http.Handler {
+	if header == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := forwardedIP(r.Header.Get(header)); ip != "" {
+			_, port, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				port = "0"
+			}
+			r.RemoteAddr = net.JoinHostPort(ip, port)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedIP returns the address a proxy header names, or "" when it holds
+// none. In a list the proxy appends the address it saw, so the last one is
+// taken; earlier ones came from the client and can't be trusted.
+func forwardedIP(value string) string {
+	addresses := strings.Split(value, ",")
+	ip := net.ParseIP(strings.TrimSpace(addresses[len(addresses)-1]))
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
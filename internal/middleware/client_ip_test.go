@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   string
+	}{
+		{"no proxy configured", "", "203.0.113.7", "10.0.0.1:4321"},
+		{"fly client ip", "Fly-Client-IP", "203.0.113.7", "203.0.113.7:4321"},
+		{"ipv6 client", "Fly-Client-IP", "2001:db8::7", "[2001:db8::7]:4321"},
+		{"forwarded list takes the proxy's entry", "X-Forwarded-For", "198.51.100.1, 203.0.113.7", "203.0.113.7:4321"},
+		{"missing header", "Fly-Client-IP", "", "10.0.0.1:4321"},
+		{"garbage header", "Fly-Client-IP", "not an address", "10.0.0.1:4321"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := ClientIP(tt.header, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = "10.0.0.1:4321"
+			if tt.value != "" {
+				request.Header.Set("Fly-Client-IP", tt.value)
+				request.Header.Set("X-Forwarded-For", tt.value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+// MaxItemQuantity is the largest quantity an item can have. It keeps
+// quantities, and the deltas that adjust them, well inside the INTEGER column.
+const MaxItemQuantity = 1_000_000
+
+// CreateItemRequest represents the request to add an item to a box
+type CreateItemRequest struct {
+	BoxID    string `json:"boxId" validate:"required"`
+	Name     string `json:"name" validate:"required,min=1,max=100"`
+	Quantity int    `json:"quantity,omitempty" validate:"min=0"`
+	Unit     string `json:"unit,omitempty" validate:"max=20"`
+	Notes    string `json:"notes,omitempty" validate:"max=500"`
+}
+
+// UpdateItemRequest represents the request to change an item. Fields left
+// out of the JSON body are not changed.
+type UpdateItemRequest struct {
+	Name     *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Quantity *int    `json:"quantity,omitempty" validate:"omitempty,min=0"`
+	Unit     *string `json:"unit,omitempty" validate:"omitempty,max=20"`
+	Notes    *string `json:"notes,omitempty" validate:"omitempty,max=500"`
+}
+
+// MoveItemRequest represents the request to move an item to another box
+type MoveItemRequest struct {
+	ItemID string `json:"itemId" validate:"required"`
+	BoxID  string `json:"boxId" validate:"required"`
+}
+
+// MoveItemsRequest represents the request to move several items from one box
+// to another in a single step. Items can be picked by ID or, like the
+// add-item and remove-item endpoints, by name.
+type MoveItemsRequest struct {
+	FromBoxID string   `json:"fromBoxId" validate:"required"`
+	ToBoxID   string   `json:"toBoxId" validate:"required"`
+	ItemIDs   []string `json:"itemIds,omitempty"`
+	Items     []string `json:"items,omitempty"`
+}
+
+// MoveItemsResponse holds both boxes after a move
+type MoveItemsResponse struct {
+	FromBox *Box `json:"fromBox"`
+	ToBox   *Box `json:"toBox"`
+}
+
+// AdjustItemQuantityRequest represents the request to increment or decrement
+// an item's quantity. A negative delta decrements.
+type AdjustItemQuantityRequest struct {
+	ItemID string `json:"itemId" validate:"required"`
+	Delta  int    `json:"delta" validate:"required"`
+}
+
+// ItemTotal is the combined quantity of one kind of item across a user's boxes
+type ItemTotal struct {
+	Name     string `json:"name"`
+	Unit     string `json:"unit,omitempty"`
+	Quantity int    `json:"quantity"`
+	Boxes    int    `json:"boxes"`
+}
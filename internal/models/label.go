@@ -0,0 +1,29 @@
+package models
+
+// LabelSheetRequest represents the request to print QR labels for several boxes
+type LabelSheetRequest struct {
+	BoxIDs     []string `json:"boxIds,omitempty"`
+	Room       string   `json:"room,omitempty" validate:"max=100"`
+	LocationID string   `json:"locationId,omitempty"`
+	Template   string   `json:"template,omitempty"`
+}
+
+// LabelTemplate describes the geometry of a sheet of label stock in millimetres
+type LabelTemplate struct {
+	Name         string
+	PageSize     string
+	Columns      int
+	Rows         int
+	LabelWidth   float64
+	LabelHeight  float64
+	MarginTop    float64
+	MarginLeft   float64
+	PitchX       float64
+	PitchY       float64
+	ItemsPerCard int
+}
+
+// LabelsPerPage returns how many labels fit on a single sheet
+func (t LabelTemplate) LabelsPerPage() int {
+	return t.Columns * t.Rows
+}
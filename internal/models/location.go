@@ -0,0 +1,51 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Location is a place boxes are kept, such as a building, room, shelf or bin.
+// Locations form a tree per workspace; top-level locations have no parent.
+type Location struct {
+	ID          string       `json:"id"`
+	UserID      string       `json:"userId"`
+	WorkspaceID string       `json:"workspaceId"`
+	ParentID    string       `json:"parentId,omitempty"`
+	Name        string       `json:"name"`
+	Path        LocationPath `json:"path"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+}
+
+// LocationCrumb is one step of a location breadcrumb
+type LocationCrumb struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// LocationPath is a breadcrumb from a top-level location down to a location
+type LocationPath []LocationCrumb
+
+// String joins the location names, as in "Garage / Shelf 3"
+func (p LocationPath) String() string {
+	names := make([]string, len(p))
+	for i, crumb := range p {
+		names[i] = crumb.Name
+	}
+	return strings.Join(names, " / ")
+}
+
+// CreateLocationRequest represents the request to create a location
+type CreateLocationRequest struct {
+	WorkspaceID string `json:"workspaceId,omitempty"`
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	ParentID    string `json:"parentId,omitempty"`
+}
+
+// UpdateLocationRequest represents the request to rename or move a location.
+// Fields left out are not changed; an empty parentId moves it to the top level.
+type UpdateLocationRequest struct {
+	Name     *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	ParentID *string `json:"parentId,omitempty"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"net/url"
+	"time"
+)
+
+// photoPath is the public endpoint that serves photo files
+const photoPath = "/api/public/photo"
+
+// Photo is an image attached to a box, or to one of the items in it
+type Photo struct {
+	ID           string    `json:"id"`
+	BoxID        string    `json:"boxId"`
+	ItemID       string    `json:"itemId,omitempty"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailUrl"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// SetURLs fills in the paths clients use to download the photo and its thumbnail
+func (p *Photo) SetURLs() {
+	query := url.Values{"id": {p.ID}}
+	p.URL = photoPath + "?" + query.Encode()
+
+	query.Set("size", "thumbnail")
+	p.ThumbnailURL = photoPath + "?" + query.Encode()
+}
+
+// AddURLParam adds a query parameter, such as a share token, to both photo URLs
+func (p *Photo) AddURLParam(name, value string) {
+	suffix := "&" + url.Values{name: {value}}.Encode()
+	p.URL += suffix
+	p.ThumbnailURL += suffix
+}
+
+// PhotoAccess is what a request for a photo file presents to be allowed to
+// see it. Photos of public boxes need none of it.
+type PhotoAccess struct {
+	UserID     string // a member of the box's workspace
+	ShareToken string // a share link covering the box
+	Grant      string // issued when a PIN box is unlocked
+}
@@ -6,24 +6,86 @@ import (
 
 // Box represents a physical box with QR code
 type Box struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"userId"`
-	Name        string    `json:"name"`
-	Description string    `json:"description,omitempty"`
-	Room        string    `json:"room,omitempty"`
-	Items       []string  `json:"items,omitempty"`
-	QRCode      string    `json:"qrCode"`
-	QRCodeURL   string    `json:"qrCodeUrl"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID           string       `json:"id"`
+	ShortCode    string       `json:"shortCode"`
+	UserID       string       `json:"userId"`
+	WorkspaceID  string       `json:"workspaceId"`
+	Name         string       `json:"name"`
+	Description  string       `json:"description,omitempty"`
+	Room         string       `json:"room,omitempty"`
+	LocationID   string       `json:"locationId,omitempty"`
+	LocationPath LocationPath `json:"locationPath"`
+	ParentBoxID  string       `json:"parentBoxId,omitempty"`
+	Visibility   string       `json:"visibility"`
+	PINHash      string       `json:"-"`
+	Tags         []BoxTag     `json:"tags"`
+	Items        []Item       `json:"items,omitempty"`
+	Photos       []Photo      `json:"photos"`
+	QRCode       string       `json:"qrCode,omitempty"`
+	QRCodeURL    string       `json:"qrCodeUrl"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	UpdatedAt    time.Time    `json:"updatedAt"`
+}
+
+// What the public scan page shows of a box. Private boxes look as if they
+// don't exist; a pin box shows its name until it is unlocked with its PIN.
+const (
+	VisibilityPublic   = "public"
+	VisibilityNameOnly = "name_only"
+	VisibilityPIN      = "pin"
+	VisibilityPrivate  = "private"
+)
+
+// Item is a single thing stored in a box
+type Item struct {
+	ID        string    `json:"id"`
+	BoxID     string    `json:"boxId"`
+	Name      string    `json:"name"`
+	Quantity  int       `json:"quantity"`
+	Unit      string    `json:"unit,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// QRRenderOptions selects how a box's QR code is rendered on demand. Zero
+// values, and a nil Border, pick the defaults.
+type QRRenderOptions struct {
+	Format     string // png, svg, pdf or eps
+	Size       int    // width and height in pixels
+	DPI        int    // print resolution, making the image Size/DPI inches wide
+	Level      string // error correction level: L, M, Q or H
+	Foreground string // hex colour of the dark modules
+	Background string // hex colour of the light modules
+	Border     *int   // quiet zone around the code, in modules
+	Caption    string // text printed under the code: name or code
+	Logo       []byte // image drawn over the middle of the code
+}
+
+// QR label captions: the box's name or its short code
+const (
+	QRCaptionName = "name"
+	QRCaptionCode = "code"
+)
+
+// QRImage is a rendered QR code file
+type QRImage struct {
+	Data        []byte
+	ContentType string
+	Extension   string
 }
 
 // CreateBoxRequest represents the request to create a new box
 type CreateBoxRequest struct {
-	Name        string `json:"name" validate:"required,min=1,max=100"`
-	Description string `json:"description,omitempty" validate:"max=500"`
-	Room        string `json:"room,omitempty" validate:"max=100"`
-	Items       string `json:"items,omitempty" validate:"max=1000"`
+	WorkspaceID string   `json:"workspaceId,omitempty"`
+	Name        string   `json:"name" validate:"required,min=1,max=100"`
+	Description string   `json:"description,omitempty" validate:"max=500"`
+	Room        string   `json:"room,omitempty" validate:"max=100"`
+	LocationID  string   `json:"locationId,omitempty"`
+	ParentBoxID string   `json:"parentBoxId,omitempty"`
+	TagIDs      []string `json:"tagIds,omitempty"`
+	Items       string   `json:"items,omitempty" validate:"max=1000"`
+	Visibility  string   `json:"visibility,omitempty"`
+	PIN         string   `json:"pin,omitempty"`
 }
 
 // CreateBoxResponse represents the response when creating a box
@@ -33,10 +95,114 @@ type CreateBoxResponse struct {
 	Message   string `json:"message"`
 }
 
-// UpdateBoxRequest represents the request to update an existing box
+// CreateBoxesRequest creates several boxes at once: each box in Boxes, or
+// Count empty boxes named "Box 1" to "Box N", or after NamePrefix, that share
+// the workspace, room, location and tags given here. A LabelTemplate also
+// returns a label sheet for the new boxes.
+type CreateBoxesRequest struct {
+	Boxes         []CreateBoxRequest `json:"boxes,omitempty"`
+	Count         int                `json:"count,omitempty"`
+	NamePrefix    string             `json:"namePrefix,omitempty" validate:"max=90"`
+	WorkspaceID   string             `json:"workspaceId,omitempty"`
+	Room          string             `json:"room,omitempty" validate:"max=100"`
+	LocationID    string             `json:"locationId,omitempty"`
+	TagIDs        []string           `json:"tagIds,omitempty"`
+	LabelTemplate string             `json:"labelTemplate,omitempty"`
+}
+
+// CreateBoxesResponse lists the boxes created together, in request order
+type CreateBoxesResponse struct {
+	Boxes      []*Box `json:"boxes"`
+	Count      int    `json:"count"`
+	LabelSheet []byte `json:"labelSheet,omitempty"` // PDF, base64 encoded in JSON
+	Message    string `json:"message"`
+}
+
+// UpdateBoxRequest represents the request to update an existing box. A nil
+// LocationID, ParentBoxID or TagIDs leaves it unchanged and an empty one clears it.
+// An empty Visibility or PIN leaves it unchanged.
 type UpdateBoxRequest struct {
-	Name        string `json:"name,omitempty" validate:"max=100"`
-	Description string `json:"description,omitempty" validate:"max=500"`
-	Room        string `json:"room,omitempty" validate:"max=100"`
-	Items       string `json:"items,omitempty" validate:"max=1000"`
+	Name        string    `json:"name,omitempty" validate:"max=100"`
+	Description string    `json:"description,omitempty" validate:"max=500"`
+	Room        string    `json:"room,omitempty" validate:"max=100"`
+	LocationID  *string   `json:"locationId,omitempty"`
+	ParentBoxID *string   `json:"parentBoxId,omitempty"`
+	TagIDs      *[]string `json:"tagIds,omitempty"`
+	Items       string    `json:"items,omitempty" validate:"max=1000"`
+	Visibility  string    `json:"visibility,omitempty"`
+	PIN         string    `json:"pin,omitempty"`
+}
+
+// BoxFieldQRCode selects the base64 QR code, which box lists leave out unless
+// asked for it
+const BoxFieldQRCode = "qrCode"
+
+// Sort fields accepted when listing boxes
+const (
+	BoxSortName    = "name"
+	BoxSortRoom    = "room"
+	BoxSortCreated = "created"
+	BoxSortUpdated = "updated"
+)
+
+// Sort directions accepted when listing boxes
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
+
+// BoxListOptions controls paging, ordering and filtering of a user's boxes.
+// A zero Limit returns every matching box.
+type BoxListOptions struct {
+	Limit        int
+	Cursor       string
+	WorkspaceID  string
+	Sort         string
+	Order        string
+	Room         string
+	LocationID   string
+	Tags         TagFilter
+	UpdatedSince *time.Time
+}
+
+// BoxPage is one page of a user's boxes. NextCursor is empty on the last page.
+type BoxPage struct {
+	Boxes      []*Box `json:"boxes"`
+	Count      int    `json:"count"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// ScanResult is what the QR codes in a photo lead to: the boxes the user can
+// view and the text of the codes that aren't one of them
+type ScanResult struct {
+	Boxes        []*Box   `json:"boxes"`
+	Unrecognised []string `json:"unrecognised"`
+}
+
+// BoxSearchResult is one ranked search hit with the parts of the box that matched
+type BoxSearchResult struct {
+	Box           *Box       `json:"box"`
+	Rank          float64    `json:"rank"`
+	MatchedFields []string   `json:"matchedFields"`
+	MatchedItems  []Item     `json:"matchedItems,omitempty"`
+	ContainedIn   []BoxCrumb `json:"containedIn"`
+}
+
+// BoxCrumb names one box in a containment chain
+type BoxCrumb struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// BoxContents is a box together with everything packed inside it, including
+// the contents of the boxes it holds. On the public scan page a Locked box
+// shows only its name.
+type BoxContents struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Locked      bool           `json:"locked,omitempty"`
+	Items       []Item         `json:"items"`
+	Boxes       []*BoxContents `json:"boxes"`
 }
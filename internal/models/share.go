@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// What a share link can cover: one box, every box in a location and the
+// locations below it, or every box with a tag
+const (
+	ShareScopeBox      = "box"
+	ShareScopeLocation = "location"
+	ShareScopeTag      = "tag"
+)
+
+// ShareLink gives anyone holding its token access to some of a workspace's
+// boxes until it expires or is revoked. With CanEdit they can also add and
+// remove items.
+type ShareLink struct {
+	ID          string     `json:"id"`
+	WorkspaceID string     `json:"workspaceId"`
+	Scope       string     `json:"scope"`
+	TargetID    string     `json:"targetId"`
+	TargetName  string     `json:"targetName"`
+	CanEdit     bool       `json:"canEdit"`
+	CreatedBy   string     `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	Token       string     `json:"token,omitempty"`
+	URL         string     `json:"url,omitempty"`
+}
+
+// CreateShareRequest represents the request to share a box, location or tag
+type CreateShareRequest struct {
+	Scope     string    `json:"scope" validate:"required,oneof=box location tag"`
+	TargetID  string    `json:"targetId" validate:"required"`
+	ExpiresAt time.Time `json:"expiresAt" validate:"required"`
+	CanEdit   bool      `json:"canEdit,omitempty"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Tag is a user-defined label such as "winter" or "fragile" that can be put on
+// any number of boxes
+type Tag struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"userId"`
+	WorkspaceID string    `json:"workspaceId"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	BoxCount    int       `json:"boxCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// BoxTag is a tag as shown on a box
+type BoxTag struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// CreateTagRequest represents the request to create a tag. Color is a hex
+// colour like #ff8800; a default is used when it is empty.
+type CreateTagRequest struct {
+	WorkspaceID string `json:"workspaceId,omitempty"`
+	Name        string `json:"name" validate:"required,min=1,max=50"`
+	Color       string `json:"color,omitempty"`
+}
+
+// UpdateTagRequest renames or recolours a tag. Nil fields are left unchanged.
+type UpdateTagRequest struct {
+	Name  *string `json:"name,omitempty" validate:"omitempty,min=1,max=50"`
+	Color *string `json:"color,omitempty"`
+}
+
+// MergeTagsRequest folds the source tags into the target: boxes tagged with
+// any source end up tagged with the target and the sources are deleted
+type MergeTagsRequest struct {
+	SourceIDs []string `json:"sourceIds" validate:"required,min=1"`
+	TargetID  string   `json:"targetId" validate:"required"`
+}
+
+// Ways of combining several tags in a box filter
+const (
+	TagMatchAll = "all"
+	TagMatchAny = "any"
+)
+
+// TagFilter restricts boxes to those carrying the given tags. With Match "all"
+// a box needs every tag, with "any" one of them is enough.
+type TagFilter struct {
+	TagIDs []string
+	Match  string
+}
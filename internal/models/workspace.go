@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Workspace is a household or other group that shares boxes, locations and
+// tags between its members
+type Workspace struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	CreatedBy string            `json:"createdBy"`
+	Members   []WorkspaceMember `json:"members"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// Roles a workspace member can have. Viewers can only look, editors can also
+// change contents and owners can also delete boxes and manage members.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+// WorkspaceMember is a user who belongs to a workspace
+type WorkspaceMember struct {
+	UserID   string    `json:"userId"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// WorkspaceInvitation lets someone join a workspace with its code. When Email
+// is set only a user with that address can accept it.
+type WorkspaceInvitation struct {
+	ID            string     `json:"id"`
+	WorkspaceID   string     `json:"workspaceId"`
+	WorkspaceName string     `json:"workspaceName"`
+	Code          string     `json:"code"`
+	Email         string     `json:"email,omitempty"`
+	Role          string     `json:"role"`
+	InvitedBy     string     `json:"invitedBy"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ExpiresAt     time.Time  `json:"expiresAt"`
+	AcceptedBy    string     `json:"acceptedBy,omitempty"`
+	AcceptedAt    *time.Time `json:"acceptedAt,omitempty"`
+}
+
+// CreateWorkspaceRequest represents the request to create a workspace
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+// UpdateWorkspaceRequest represents the request to rename a workspace
+type UpdateWorkspaceRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+// CreateInvitationRequest invites someone to a workspace, by email or, with
+// no email, with a code anyone can use. Role defaults to editor.
+type CreateInvitationRequest struct {
+	WorkspaceID string `json:"workspaceId" validate:"required"`
+	Email       string `json:"email,omitempty" validate:"omitempty,email"`
+	Role        string `json:"role,omitempty" validate:"omitempty,oneof=viewer editor owner"`
+}
+
+// UpdateMemberRoleRequest changes a member's role
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=viewer editor owner"`
+}
+
+// JoinWorkspaceRequest accepts an invitation by its code
+type JoinWorkspaceRequest struct {
+	Code string `json:"code" validate:"required"`
+}
@@ -0,0 +1,137 @@
+package qrdecode
+
+import (
+	"image"
+	"image/color"
+)
+
+// bitmap is a thresholded image, true for dark pixels
+type bitmap struct {
+	width, height int
+	dark          []bool
+}
+
+func (b *bitmap) at(x, y int) bool {
+	return b.dark[y*b.width+x]
+}
+
+// grayImage is the luminance of an image, one byte per pixel
+type grayImage struct {
+	width, height int
+	pix           []uint8
+}
+
+// toGray reads the luminance of every pixel. Transparent pixels count as
+// white. Photos decode as YCbCr, whose Y plane is used as it is.
+func toGray(img image.Image) *grayImage {
+	bounds := img.Bounds()
+	gray := &grayImage{width: bounds.Dx(), height: bounds.Dy()}
+	gray.pix = make([]uint8, gray.width*gray.height)
+
+	if ycbcr, ok := img.(*image.YCbCr); ok {
+		for y := 0; y < gray.height; y++ {
+			offset := ycbcr.YOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(gray.pix[y*gray.width:(y+1)*gray.width], ycbcr.Y[offset:offset+gray.width])
+		}
+		return gray
+	}
+
+	for y := 0; y < gray.height; y++ {
+		for x := 0; x < gray.width; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// Composite over white: the colour is premultiplied by alpha
+			white := 0xffff - a
+			gray.pix[y*gray.width+x] = color.GrayModel.Convert(color.RGBA64{R: uint16(r + white), G: uint16(g + white), B: uint16(b + white), A: 0xffff}).(color.Gray).Y
+		}
+	}
+	return gray
+}
+
+// binarize thresholds an image halfway between its darkest and lightest
+// pixels, which suits rendered codes
+func binarize(gray *grayImage) *bitmap {
+	var darkest, lightest uint8 = 255, 0
+	for _, value := range gray.pix {
+		darkest = min(darkest, value)
+		lightest = max(lightest, value)
+	}
+
+	threshold := (int(darkest) + int(lightest) + 1) / 2
+	dark := make([]bool, len(gray.pix))
+	for i, value := range gray.pix {
+		dark[i] = int(value) < threshold
+	}
+
+	return &bitmap{width: gray.width, height: gray.height, dark: dark}
+}
+
+// Block thresholding of photos
+const (
+	// thresholdBlock is the side in pixels of the blocks a photo is measured in
+	thresholdBlock = 8
+
+	// minBlockContrast is the smallest spread of a block that can hold both
+	// dark and light modules; flatter blocks take their neighbours' threshold
+	minBlockContrast = 24
+)
+
+// binarizeLocal thresholds each block of a photo at the average of the
+// blocks around it, so that shadows and uneven light don't swallow half of
+// a code the way one global threshold would
+func binarizeLocal(gray *grayImage) *bitmap {
+	columns := (gray.width + thresholdBlock - 1) / thresholdBlock
+	rows := (gray.height + thresholdBlock - 1) / thresholdBlock
+
+	averages := make([]int, columns*rows)
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			sum, count, darkest, lightest := 0, 0, 255, 0
+			for y := row * thresholdBlock; y < min((row+1)*thresholdBlock, gray.height); y++ {
+				for x := column * thresholdBlock; x < min((column+1)*thresholdBlock, gray.width); x++ {
+					value := int(gray.pix[y*gray.width+x])
+					sum += value
+					count++
+					darkest = min(darkest, value)
+					lightest = max(lightest, value)
+				}
+			}
+
+			average := sum / count
+			if lightest-darkest <= minBlockContrast {
+				// A flat block is taken to be background, unless the blocks
+				// before it show it is darker than theirs
+				average = darkest / 2
+				if row > 0 && column > 0 {
+					neighbours := (averages[(row-1)*columns+column] + 2*averages[row*columns+column-1] + averages[(row-1)*columns+column-1]) / 4
+					if darkest < neighbours {
+						average = neighbours
+					}
+				}
+			}
+			averages[row*columns+column] = average
+		}
+	}
+
+	dark := make([]bool, len(gray.pix))
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			// Average the 5x5 blocks centred here, clamped to the image
+			sum, count := 0, 0
+			for r := max(0, row-2); r <= min(rows-1, row+2); r++ {
+				for c := max(0, column-2); c <= min(columns-1, column+2); c++ {
+					sum += averages[r*columns+c]
+					count++
+				}
+			}
+			threshold := sum / count
+
+			for y := row * thresholdBlock; y < min((row+1)*thresholdBlock, gray.height); y++ {
+				for x := column * thresholdBlock; x < min((column+1)*thresholdBlock, gray.width); x++ {
+					dark[y*gray.width+x] = int(gray.pix[y*gray.width+x]) <= threshold
+				}
+			}
+		}
+	}
+
+	return &bitmap{width: gray.width, height: gray.height, dark: dark}
+}
@@ -0,0 +1,178 @@
+package qrdecode
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// alphanumericCharacters are the 45 characters of alphanumeric mode
+const alphanumericCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// Segment modes
+const (
+	modeTerminator       = 0
+	modeNumeric          = 1
+	modeAlphanumeric     = 2
+	modeStructuredAppend = 3
+	modeByte             = 4
+	modeFNC1First        = 5
+	modeECI              = 7
+	modeKanji            = 8
+	modeFNC1Second       = 9
+)
+
+// bitReader reads big-endian bit fields from the data codewords
+type bitReader struct {
+	data     []byte
+	position int
+}
+
+func (r *bitReader) remaining() int {
+	return len(r.data)*8 - r.position
+}
+
+func (r *bitReader) read(count int) (int, error) {
+	if count > r.remaining() {
+		return 0, fmt.Errorf("truncated data")
+	}
+
+	value := 0
+	for i := 0; i < count; i++ {
+		bit := r.data[r.position/8] >> (7 - r.position%8) & 1
+		value = value<<1 | int(bit)
+		r.position++
+	}
+	return value, nil
+}
+
+// countBits returns the width of a segment's character count for a version
+func countBits(mode, version int) int {
+	sizes := map[int][3]int{
+		modeNumeric:      {10, 12, 14},
+		modeAlphanumeric: {9, 11, 13},
+		modeByte:         {8, 16, 16},
+		modeKanji:        {8, 10, 12},
+	}[mode]
+
+	switch {
+	case version <= 9:
+		return sizes[0]
+	case version <= 26:
+		return sizes[1]
+	}
+	return sizes[2]
+}
+
+// parseData decodes the segments of the data codewords into text. Byte
+// segments are read as UTF-8 when valid and as ISO 8859-1 otherwise.
+func parseData(data []byte, version int) (string, error) {
+	reader := &bitReader{data: data}
+	var raw []byte
+
+	for reader.remaining() >= 4 {
+		mode, _ := reader.read(4)
+		switch mode {
+		case modeTerminator:
+			return text(raw), nil
+
+		case modeNumeric:
+			count, err := reader.read(countBits(mode, version))
+			if err != nil {
+				return "", err
+			}
+			for count > 0 {
+				digits := min(count, 3)
+				value, err := reader.read(digits*3 + 1)
+				if err != nil {
+					return "", err
+				}
+				raw = fmt.Appendf(raw, "%0*d", digits, value)
+				count -= digits
+			}
+
+		case modeAlphanumeric:
+			count, err := reader.read(countBits(mode, version))
+			if err != nil {
+				return "", err
+			}
+			for ; count >= 2; count -= 2 {
+				value, err := reader.read(11)
+				if err != nil || value >= 45*45 {
+					return "", fmt.Errorf("invalid alphanumeric data")
+				}
+				raw = append(raw, alphanumericCharacters[value/45], alphanumericCharacters[value%45])
+			}
+			if count == 1 {
+				value, err := reader.read(6)
+				if err != nil || value >= 45 {
+					return "", fmt.Errorf("invalid alphanumeric data")
+				}
+				raw = append(raw, alphanumericCharacters[value])
+			}
+
+		case modeByte:
+			count, err := reader.read(countBits(mode, version))
+			if err != nil {
+				return "", err
+			}
+			for i := 0; i < count; i++ {
+				value, err := reader.read(8)
+				if err != nil {
+					return "", err
+				}
+				raw = append(raw, byte(value))
+			}
+
+		case modeECI:
+			// The character set designator takes one to three bytes; the
+			// text is read as UTF-8 whatever it says
+			first, err := reader.read(8)
+			if err != nil {
+				return "", err
+			}
+			switch {
+			case first&0x80 == 0:
+			case first&0xc0 == 0x80:
+				_, err = reader.read(8)
+			case first&0xe0 == 0xc0:
+				_, err = reader.read(16)
+			default:
+				err = fmt.Errorf("invalid ECI designator")
+			}
+			if err != nil {
+				return "", err
+			}
+
+		case modeStructuredAppend:
+			if _, err := reader.read(16); err != nil {
+				return "", err
+			}
+
+		case modeFNC1First:
+
+		case modeFNC1Second:
+			if _, err := reader.read(8); err != nil {
+				return "", err
+			}
+
+		default:
+			return "", fmt.Errorf("unsupported qr data mode %d", mode)
+		}
+	}
+
+	return text(raw), nil
+}
+
+// text converts decoded bytes to a string, reading invalid UTF-8 as ISO 8859-1
+func text(raw []byte) string {
+	if utf8.Valid(raw) {
+		return string(raw)
+	}
+
+	var latin strings.Builder
+	for _, b := range raw {
+		latin.WriteRune(rune(b))
+	}
+	return latin.String()
+}
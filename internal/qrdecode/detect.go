@@ -0,0 +1,509 @@
+package qrdecode
+
+import (
+	"math"
+	"sort"
+)
+
+// point is a position in image or module coordinates
+type point struct {
+	x, y float64
+}
+
+func distance(a, b point) float64 {
+	return math.Hypot(a.x-b.x, a.y-b.y)
+}
+
+// finder is a candidate finder pattern centre and the size of its modules
+type finder struct {
+	point
+	moduleSize float64
+	count      int
+}
+
+// matchesRatio reports whether run lengths look like the 1:1:3:1:1
+// dark-light-dark-light-dark profile through the middle of a finder pattern
+func matchesRatio(runs [5]int) bool {
+	total := 0
+	for _, run := range runs {
+		if run == 0 {
+			return false
+		}
+		total += run
+	}
+	if total < 7 {
+		return false
+	}
+
+	module := float64(total) / 7
+	tolerance := module / 2
+	return math.Abs(module-float64(runs[0])) < tolerance &&
+		math.Abs(module-float64(runs[1])) < tolerance &&
+		math.Abs(3*module-float64(runs[2])) < 3*tolerance &&
+		math.Abs(module-float64(runs[3])) < tolerance &&
+		math.Abs(module-float64(runs[4])) < tolerance
+}
+
+// centreFromEnd returns the middle of the centre run of a finder profile that
+// ends at end
+func centreFromEnd(runs [5]int, end int) float64 {
+	return float64(end-runs[4]-runs[3]) - float64(runs[2])/2
+}
+
+// findFinders scans every row for finder pattern profiles, confirms each by
+// crossing it vertically and horizontally, and merges the hits on the same
+// pattern
+func findFinders(b *bitmap) []*finder {
+	var finders []*finder
+
+	for y := 0; y < b.height; y++ {
+		var runs [5]int
+		state := 0
+		for x := 0; x < b.width; x++ {
+			if b.at(x, y) {
+				// Dark pixel: moving on from a light run starts the next dark one
+				if state%2 == 1 {
+					state++
+				}
+				runs[state]++
+				continue
+			}
+
+			if state%2 == 1 {
+				runs[state]++
+				continue
+			}
+
+			if state < 4 {
+				state++
+				runs[state]++
+				continue
+			}
+
+			if matchesRatio(runs) && confirmFinder(b, &finders, runs, x, y) {
+				runs = [5]int{}
+				state = 0
+				continue
+			}
+
+			// Keep the last dark-light-dark as the start of the next profile
+			runs = [5]int{runs[2], runs[3], runs[4], 1, 0}
+			state = 3
+		}
+
+		if state == 4 && matchesRatio(runs) {
+			confirmFinder(b, &finders, runs, b.width, y)
+		}
+	}
+
+	return finders
+}
+
+// confirmFinder crosses a horizontal profile ending at end on row y and
+// records the finder it belongs to
+func confirmFinder(b *bitmap, finders *[]*finder, runs [5]int, end, y int) bool {
+	total := runs[0] + runs[1] + runs[2] + runs[3] + runs[4]
+	centreX := centreFromEnd(runs, end)
+
+	centreY, ok := crossCheck(b, int(centreX), y, runs[2], total, true)
+	if !ok {
+		return false
+	}
+	centreX, ok = crossCheck(b, int(centreX), int(centreY), runs[2], total, false)
+	if !ok {
+		return false
+	}
+
+	moduleSize := float64(total) / 7
+	for _, f := range *finders {
+		if math.Abs(f.x-centreX) <= moduleSize && math.Abs(f.y-centreY) <= moduleSize &&
+			math.Abs(f.moduleSize-moduleSize) <= max(1, f.moduleSize) {
+			// Average the hits, weighted by how many there have been
+			n := float64(f.count)
+			f.x = (f.x*n + centreX) / (n + 1)
+			f.y = (f.y*n + centreY) / (n + 1)
+			f.moduleSize = (f.moduleSize*n + moduleSize) / (n + 1)
+			f.count++
+			return true
+		}
+	}
+
+	*finders = append(*finders, &finder{point: point{centreX, centreY}, moduleSize: moduleSize, count: 1})
+	return true
+}
+
+// crossCheck follows a line through x, y, vertically or horizontally, and
+// returns the centre of the finder profile along it. The profile has to be
+// about as long as the one it crosses.
+func crossCheck(b *bitmap, x, y, maxRun, originalTotal int, vertical bool) (float64, bool) {
+	start, limit := x, b.width
+	at := func(i int) bool { return b.at(i, y) }
+	if vertical {
+		start, limit = y, b.height
+		at = func(i int) bool { return b.at(x, i) }
+	}
+
+	var runs [5]int
+	i := start
+	for i >= 0 && at(i) {
+		runs[2]++
+		i--
+	}
+	if i < 0 {
+		return 0, false
+	}
+	for i >= 0 && !at(i) && runs[1] <= maxRun {
+		runs[1]++
+		i--
+	}
+	if i < 0 || runs[1] > maxRun {
+		return 0, false
+	}
+	for i >= 0 && at(i) && runs[0] <= maxRun {
+		runs[0]++
+		i--
+	}
+	if runs[0] > maxRun {
+		return 0, false
+	}
+
+	i = start + 1
+	for i < limit && at(i) {
+		runs[2]++
+		i++
+	}
+	if i == limit {
+		return 0, false
+	}
+	for i < limit && !at(i) && runs[3] < maxRun {
+		runs[3]++
+		i++
+	}
+	if i == limit || runs[3] >= maxRun {
+		return 0, false
+	}
+	for i < limit && at(i) && runs[4] < maxRun {
+		runs[4]++
+		i++
+	}
+	if runs[4] >= maxRun {
+		return 0, false
+	}
+
+	total := runs[0] + runs[1] + runs[2] + runs[3] + runs[4]
+	if 5*abs(total-originalTotal) >= 2*originalTotal || !matchesRatio(runs) {
+		return 0, false
+	}
+	return centreFromEnd(runs, i), true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// orderFinders arranges three finders as top left, top right and bottom left.
+// The top left one is opposite the longest side, and going from top right to
+// bottom left turns clockwise around it in image coordinates.
+func orderFinders(a, b, c *finder) (topLeft, topRight, bottomLeft *finder) {
+	ab, bc, ac := distance(a.point, b.point), distance(b.point, c.point), distance(a.point, c.point)
+	switch {
+	case bc >= ab && bc >= ac:
+		topLeft, topRight, bottomLeft = a, b, c
+	case ac >= ab && ac >= bc:
+		topLeft, topRight, bottomLeft = b, a, c
+	default:
+		topLeft, topRight, bottomLeft = c, a, b
+	}
+
+	cross := (topRight.x-topLeft.x)*(bottomLeft.y-topLeft.y) - (topRight.y-topLeft.y)*(bottomLeft.x-topLeft.x)
+	if cross < 0 {
+		topRight, bottomLeft = bottomLeft, topRight
+	}
+	return topLeft, topRight, bottomLeft
+}
+
+// plausibleTriple reports whether three finders could be the corners of one
+// code: similar module sizes and a roughly right-angled isosceles triangle,
+// allowing for the perspective of a photo
+func plausibleTriple(topLeft, topRight, bottomLeft *finder) bool {
+	sizes := []float64{topLeft.moduleSize, topRight.moduleSize, bottomLeft.moduleSize}
+	sort.Float64s(sizes)
+	if sizes[2] > 2*sizes[0] {
+		return false
+	}
+
+	top, left := distance(topLeft.point, topRight.point), distance(topLeft.point, bottomLeft.point)
+	diagonal := distance(topRight.point, bottomLeft.point)
+	if math.Abs(top-left) > 0.4*max(top, left) {
+		return false
+	}
+	return math.Abs(diagonal*diagonal-top*top-left*left) < 0.5*diagonal*diagonal
+}
+
+// moduleSizeBetween measures the modules of two finders along the line
+// joining them, which perspective can make differ from their size across
+// the rows they were found on
+func moduleSizeBetween(b *bitmap, from, to *finder) float64 {
+	measure := func(f *finder, towards point) float64 {
+		length := distance(f.point, towards)
+		dx, dy := (towards.x-f.x)/length, (towards.y-f.y)/length
+		forward, ok := finderRadius(b, f.point, dx, dy)
+		if !ok {
+			return f.moduleSize
+		}
+		backward, ok := finderRadius(b, f.point, -dx, -dy)
+		if !ok {
+			return f.moduleSize
+		}
+		return (forward + backward) / 7
+	}
+
+	return (measure(from, to.point) + measure(to, from.point)) / 2
+}
+
+// finderRadius walks from a finder's centre in direction dx, dy across its
+// dark centre, light ring and dark ring, and returns the distance to the
+// light beyond, three and a half modules
+func finderRadius(b *bitmap, centre point, dx, dy float64) (float64, bool) {
+	state := 0
+	for step := 0.0; ; step++ {
+		x, y := int(centre.x+dx*step), int(centre.y+dy*step)
+		if x < 0 || y < 0 || x >= b.width || y >= b.height {
+			return 0, false
+		}
+
+		// Dark, light and dark again: each change of colour moves on a state
+		if b.at(x, y) == (state%2 == 1) {
+			state++
+			if state == 3 {
+				return step, true
+			}
+		}
+	}
+}
+
+// dimensions returns the likely module counts across a code from the spacing
+// of its finders, nearest first. Sizes are always 4n+1.
+func dimensions(b *bitmap, topLeft, topRight, bottomLeft *finder) []int {
+	across := distance(topLeft.point, topRight.point) / moduleSizeBetween(b, topLeft, topRight)
+	down := distance(topLeft.point, bottomLeft.point) / moduleSizeBetween(b, topLeft, bottomLeft)
+	estimate := (across+down)/2 + 7
+
+	// The nearest 4n+1, then the sizes on either side of it
+	nearest := 4*int(math.Round((estimate-1)/4)) + 1
+	candidates := []int{nearest}
+	for offset := 4; offset <= 8; offset += 4 {
+		if estimate < float64(nearest) {
+			candidates = append(candidates, nearest-offset, nearest+offset)
+		} else {
+			candidates = append(candidates, nearest+offset, nearest-offset)
+		}
+	}
+
+	valid := candidates[:0]
+	for _, size := range candidates {
+		if size >= 21 && size <= 177 {
+			valid = append(valid, size)
+		}
+	}
+	return valid
+}
+
+// findAlignments looks around estimate for centres of alignment patterns,
+// dark modules ringed by light, and returns them nearest first. Data modules
+// can look the same, so there may be more than the one.
+func findAlignments(b *bitmap, estimate point, moduleSize float64) []point {
+	radius := int(math.Ceil(8 * moduleSize))
+	left, right := max(0, int(estimate.x)-radius), min(b.width, int(estimate.x)+radius+1)
+	top, bottom := max(0, int(estimate.y)-radius), min(b.height, int(estimate.y)+radius+1)
+
+	var found []point
+	for y := top; y < bottom; y++ {
+		// Look for light, dark, light runs each about a module long
+		var runs [3]int
+		state := 0
+		for x := left; x < right; x++ {
+			dark := b.at(x, y)
+			switch {
+			case state == 0 && !dark:
+				runs[0]++
+				continue
+			case state == 0:
+				if runs[0] > 0 {
+					state, runs[1] = 1, 1
+				}
+				continue
+			case state == 1 && dark:
+				runs[1]++
+				continue
+			case state == 1:
+				state, runs[2] = 2, 1
+				continue
+			case !dark:
+				runs[2]++
+				continue
+			}
+
+			// A dark pixel ends the second light run
+			if matchesModules(runs, moduleSize) {
+				centreX := float64(x-runs[2]) - float64(runs[1])/2
+				if centreY, ok := crossCheckAlignment(b, int(centreX), y, moduleSize); ok {
+					found = addAlignment(found, point{centreX, centreY}, moduleSize)
+				}
+			}
+			runs = [3]int{runs[2], 1, 0}
+			state = 1
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return distance(found[i], estimate) < distance(found[j], estimate)
+	})
+	return found
+}
+
+// addAlignment adds a pattern centre unless it is one already found on an
+// earlier row
+func addAlignment(found []point, centre point, moduleSize float64) []point {
+	for _, p := range found {
+		if distance(p, centre) <= moduleSize {
+			return found
+		}
+	}
+	return append(found, centre)
+}
+
+// matchesModules reports whether each run is about one module long
+func matchesModules(runs [3]int, moduleSize float64) bool {
+	for _, run := range runs {
+		if math.Abs(float64(run)-moduleSize) >= moduleSize/2 {
+			return false
+		}
+	}
+	return true
+}
+
+// crossCheckAlignment follows column x through y and returns the centre of
+// the dark module there if it is ringed by light above and below
+func crossCheckAlignment(b *bitmap, x, y int, moduleSize float64) (float64, bool) {
+	maxRun := int(math.Ceil(1.5 * moduleSize))
+	var runs [3]int
+
+	i := y
+	for i >= 0 && b.at(x, i) && runs[1] <= maxRun {
+		runs[1]++
+		i--
+	}
+	for i >= 0 && !b.at(x, i) && runs[0] <= maxRun {
+		runs[0]++
+		i--
+	}
+
+	i = y + 1
+	for i < b.height && b.at(x, i) && runs[1] <= maxRun {
+		runs[1]++
+		i++
+	}
+	for i < b.height && !b.at(x, i) && runs[2] <= maxRun {
+		runs[2]++
+		i++
+	}
+
+	if !matchesModules(runs, moduleSize) {
+		return 0, false
+	}
+	return float64(i-runs[2]) - float64(runs[1])/2, true
+}
+
+// sampleGrid reads the module matrix of a code of the given size whose finder
+// centres are at topLeft, topRight and bottomLeft. bottomRight is a fourth
+// reference point, at module corner across and down from the top left.
+func sampleGrid(b *bitmap, size int, topLeft, topRight, bottomLeft, bottomRight point, corner float64) ([][]bool, bool) {
+	far := float64(size) - 3.5
+	transform := quadToQuad(
+		[4]point{{3.5, 3.5}, {far, 3.5}, {corner, corner}, {3.5, far}},
+		[4]point{topLeft, topRight, bottomRight, bottomLeft},
+	)
+
+	matrix := make([][]bool, size)
+	for row := range matrix {
+		matrix[row] = make([]bool, size)
+		for column := range matrix[row] {
+			p := transform.apply(point{float64(column) + 0.5, float64(row) + 0.5})
+			x, y := int(math.Floor(p.x)), int(math.Floor(p.y))
+			if x < 0 || y < 0 || x >= b.width || y >= b.height {
+				return nil, false
+			}
+			matrix[row][column] = b.at(x, y)
+		}
+	}
+
+	return matrix, true
+}
+
+// homography maps points through a 3x3 projective transform
+type homography [3][3]float64
+
+func (h homography) apply(p point) point {
+	w := h[2][0]*p.x + h[2][1]*p.y + h[2][2]
+	return point{
+		(h[0][0]*p.x + h[0][1]*p.y + h[0][2]) / w,
+		(h[1][0]*p.x + h[1][1]*p.y + h[1][2]) / w,
+	}
+}
+
+func (h homography) multiply(o homography) homography {
+	var product homography
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				product[i][j] += h[i][k] * o[k][j]
+			}
+		}
+	}
+	return product
+}
+
+// inverse returns the adjugate, which inverts a projective transform up to
+// scale
+func (h homography) inverse() homography {
+	return homography{
+		{h[1][1]*h[2][2] - h[1][2]*h[2][1], h[0][2]*h[2][1] - h[0][1]*h[2][2], h[0][1]*h[1][2] - h[0][2]*h[1][1]},
+		{h[1][2]*h[2][0] - h[1][0]*h[2][2], h[0][0]*h[2][2] - h[0][2]*h[2][0], h[0][2]*h[1][0] - h[0][0]*h[1][2]},
+		{h[1][0]*h[2][1] - h[1][1]*h[2][0], h[0][1]*h[2][0] - h[0][0]*h[2][1], h[0][0]*h[1][1] - h[0][1]*h[1][0]},
+	}
+}
+
+// squareToQuad maps the unit square's corners (0,0), (1,0), (1,1) and (0,1)
+// to q in order
+func squareToQuad(q [4]point) homography {
+	dx3 := q[0].x - q[1].x + q[2].x - q[3].x
+	dy3 := q[0].y - q[1].y + q[2].y - q[3].y
+	if dx3 == 0 && dy3 == 0 {
+		return homography{
+			{q[1].x - q[0].x, q[2].x - q[1].x, q[0].x},
+			{q[1].y - q[0].y, q[2].y - q[1].y, q[0].y},
+			{0, 0, 1},
+		}
+	}
+
+	dx1, dx2 := q[1].x-q[2].x, q[3].x-q[2].x
+	dy1, dy2 := q[1].y-q[2].y, q[3].y-q[2].y
+	denominator := dx1*dy2 - dx2*dy1
+	g := (dx3*dy2 - dx2*dy3) / denominator
+	h := (dx1*dy3 - dx3*dy1) / denominator
+	return homography{
+		{q[1].x - q[0].x + g*q[1].x, q[3].x - q[0].x + h*q[3].x, q[0].x},
+		{q[1].y - q[0].y + g*q[1].y, q[3].y - q[0].y + h*q[3].y, q[0].y},
+		{g, h, 1},
+	}
+}
+
+// quadToQuad returns the transform taking each corner of from to the same
+// corner of to
+func quadToQuad(from, to [4]point) homography {
+	return squareToQuad(to).multiply(squareToQuad(from).inverse())
+}
@@ -0,0 +1,237 @@
+package qrdecode
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// decodeMatrix reads the text of a QR code from its module matrix, indexed
+// [row][column] with true for dark modules and without the quiet zone
+func decodeMatrix(matrix [][]bool) (string, error) {
+	size := len(matrix)
+	if size < 21 || size > 177 || size%4 != 1 {
+		return "", fmt.Errorf("invalid qr size")
+	}
+	version := (size - 17) / 4
+
+	level, mask, err := readFormat(matrix)
+	if err != nil {
+		return "", err
+	}
+
+	codewords := readCodewords(matrix, version, mask)
+
+	data, err := correctBlocks(codewords, layout(version, level))
+	if err != nil {
+		return "", err
+	}
+
+	return parseData(data, version)
+}
+
+// readFormat reads the error correction level and data mask from whichever
+// copy of the format information is closest to a valid codeword
+func readFormat(matrix [][]bool) (level, mask int, err error) {
+	size := len(matrix)
+	bit := func(x, y int) int {
+		if matrix[y][x] {
+			return 1
+		}
+		return 0
+	}
+
+	// The first copy wraps around the top left finder, the second is split
+	// between the other two
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= bit(8, i) << i
+	}
+	first |= bit(8, 7)<<6 | bit(8, 8)<<7 | bit(7, 8)<<8
+	for i := 9; i < 15; i++ {
+		first |= bit(14-i, 8) << i
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(size-1-i, 8) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(8, size-15+i) << i
+	}
+
+	best := 4
+	for candidateLevel := 0; candidateLevel < 4; candidateLevel++ {
+		for candidateMask := 0; candidateMask < 8; candidateMask++ {
+			codeword := formatCodeword(candidateLevel, candidateMask)
+			distance := min(bits.OnesCount(uint(codeword^first)), bits.OnesCount(uint(codeword^second)))
+			if distance < best {
+				best, level, mask = distance, candidateLevel, candidateMask
+			}
+		}
+	}
+
+	if best > 3 {
+		return 0, 0, fmt.Errorf("unreadable format information")
+	}
+	return level, mask, nil
+}
+
+// functionModules marks the finder, timing, alignment, format and version
+// modules, which carry no data
+func functionModules(version int) [][]bool {
+	size := version*4 + 17
+	function := make([][]bool, size)
+	for y := range function {
+		function[y] = make([]bool, size)
+	}
+
+	fill := func(x0, y0, width, height int) {
+		for y := y0; y < y0+height; y++ {
+			for x := x0; x < x0+width; x++ {
+				function[y][x] = true
+			}
+		}
+	}
+
+	// Finders with their separators and the format information beside them
+	fill(0, 0, 9, 9)
+	fill(size-8, 0, 8, 9)
+	fill(0, size-8, 9, 8)
+
+	// Timing patterns
+	fill(6, 0, 1, size)
+	fill(0, 6, size, 1)
+
+	centres := alignmentCentres(version)
+	for i, cy := range centres {
+		for j, cx := range centres {
+			last := len(centres) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			fill(cx-2, cy-2, 5, 5)
+		}
+	}
+
+	// Version information
+	if version >= 7 {
+		fill(size-11, 0, 3, 6)
+		fill(0, size-11, 6, 3)
+	}
+
+	return function
+}
+
+// masked reports whether data mask mask inverts the module at x, y
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// readCodewords unmasks the data modules and reads them as codewords in the
+// zigzag order they were placed in: upwards and downwards through column pairs
+// from the right
+func readCodewords(matrix [][]bool, version, mask int) []byte {
+	size := len(matrix)
+	function := functionModules(version)
+
+	var codewords []byte
+	var current byte
+	count := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vertical := 0; vertical < size; vertical++ {
+			y := vertical
+			if upward {
+				y = size - 1 - vertical
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if function[y][x] {
+					continue
+				}
+
+				current <<= 1
+				if matrix[y][x] != masked(mask, x, y) {
+					current |= 1
+				}
+				count++
+				if count == 8 {
+					codewords = append(codewords, current)
+					current, count = 0, 0
+				}
+			}
+		}
+	}
+
+	return codewords
+}
+
+// correctBlocks splits interleaved codewords into their blocks, corrects each
+// block and returns the data codewords in order
+func correctBlocks(codewords []byte, layout blockLayout) ([]byte, error) {
+	blocks := make([][]byte, layout.shortBlocks+layout.longBlocks)
+	total := 0
+	for i := range blocks {
+		dataLength := layout.shortData
+		if i >= layout.shortBlocks {
+			dataLength = layout.longData
+		}
+		blocks[i] = make([]byte, 0, dataLength+layout.ecc)
+		total += dataLength + layout.ecc
+	}
+
+	if len(codewords) < total {
+		return nil, fmt.Errorf("too few codewords")
+	}
+
+	// Data codewords are interleaved first, the long blocks' extra one last,
+	// then the error correction codewords
+	next := 0
+	longest := max(layout.shortData, layout.longData)
+	for i := 0; i < longest; i++ {
+		for b := range blocks {
+			dataLength := layout.shortData
+			if b >= layout.shortBlocks {
+				dataLength = layout.longData
+			}
+			if i < dataLength {
+				blocks[b] = append(blocks[b], codewords[next])
+				next++
+			}
+		}
+	}
+	for i := 0; i < layout.ecc; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[next])
+			next++
+		}
+	}
+
+	var data []byte
+	for _, block := range blocks {
+		if err := correct(block, layout.ecc); err != nil {
+			return nil, err
+		}
+		data = append(data, block[:len(block)-layout.ecc]...)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,185 @@
+// Package qrdecode finds and reads QR codes in images. It handles codes seen
+// flat, at an angle or in perspective, in every version and error correction
+// level, with numeric, alphanumeric and byte data. Photos are thresholded
+// block by block so that uneven light doesn't hide a code.
+package qrdecode
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Limits that keep the search quick on busy photos
+const (
+	// maxFinders caps the finder candidates tried in threes
+	maxFinders = 40
+
+	// maxAlignments caps the alignment pattern candidates tried for a code
+	maxAlignments = 4
+)
+
+// Decode returns the text of a QR code in img
+func Decode(img image.Image) (string, error) {
+	gray := toGray(img)
+	for _, b := range []*bitmap{binarize(gray), binarizeLocal(gray)} {
+		if texts, _ := decodeBitmap(context.Background(), b, true); len(texts) > 0 {
+			return texts[0], nil
+		}
+	}
+
+	return "", fmt.Errorf("qr code not found")
+}
+
+// DecodeAll returns the text of every QR code in img, once each, in the
+// order they were found. It returns none if there are no readable codes.
+func DecodeAll(img image.Image) []string {
+	texts, _ := DecodeAllContext(context.Background(), img)
+	return texts
+}
+
+// DecodeAllContext is DecodeAll giving up when ctx is done. It then returns
+// the codes read so far along with ctx's error.
+func DecodeAllContext(ctx context.Context, img image.Image) ([]string, error) {
+	gray := toGray(img)
+	texts := []string{}
+	seen := make(map[string]bool)
+	for _, threshold := range []func(*grayImage) *bitmap{binarize, binarizeLocal} {
+		if err := ctx.Err(); err != nil {
+			return texts, err
+		}
+
+		found, err := decodeBitmap(ctx, threshold(gray), false)
+		for _, text := range found {
+			if !seen[text] {
+				seen[text] = true
+				texts = append(texts, text)
+			}
+		}
+		if err != nil {
+			return texts, err
+		}
+	}
+
+	return texts, nil
+}
+
+// decodeBitmap reads the codes in a thresholded image, stopping at the first
+// when first is set. A finder is only used for one code. Trying every triple
+// of finders can take a while on busy photos, so it stops when ctx is done.
+func decodeBitmap(ctx context.Context, b *bitmap, first bool) ([]string, error) {
+	finders := findFinders(b)
+	sort.SliceStable(finders, func(i, j int) bool {
+		return finders[i].count > finders[j].count
+	})
+
+	// Stray matches in text or pictures are usually seen on fewer rows than
+	// real finders, so try the most seen first
+	if len(finders) > maxFinders {
+		finders = finders[:maxFinders]
+	}
+
+	var texts []string
+	used := make([]bool, len(finders))
+	for i := 0; i < len(finders); i++ {
+		for j := i + 1; j < len(finders) && !used[i]; j++ {
+			for k := j + 1; k < len(finders) && !used[i] && !used[j]; k++ {
+				if used[k] {
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					return texts, err
+				}
+				text, ok := decodeAt(b, finders[i], finders[j], finders[k])
+				if !ok {
+					continue
+				}
+				if first {
+					return []string{text}, nil
+				}
+				texts = append(texts, text)
+				used[i], used[j], used[k] = true, true, true
+			}
+		}
+	}
+
+	return texts, nil
+}
+
+// decodeAt reads the code whose finders are a, b and c, if they are one
+func decodeAt(b *bitmap, a, c, d *finder) (string, bool) {
+	topLeft, topRight, bottomLeft := orderFinders(a, c, d)
+	if !plausibleTriple(topLeft, topRight, bottomLeft) {
+		return "", false
+	}
+
+	// Without perspective the fourth corner completes the parallelogram
+	bottomRight := point{topRight.x + bottomLeft.x - topLeft.x, topRight.y + bottomLeft.y - topLeft.y}
+	moduleSize := (topLeft.moduleSize + topRight.moduleSize + bottomLeft.moduleSize) / 3
+
+	for _, size := range dimensions(b, topLeft, topRight, bottomLeft) {
+		// From version 2 on, the bottom right alignment pattern shows where
+		// perspective has moved that corner
+		if size > 21 {
+			between := float64(size - 7)
+			share := (between - 3) / between
+			estimate := point{topLeft.x + share*(bottomRight.x-topLeft.x), topLeft.y + share*(bottomRight.y-topLeft.y)}
+			alignments := findAlignments(b, estimate, moduleSize)
+			for _, alignment := range alignments[:min(len(alignments), maxAlignments)] {
+				if text, ok := decodeGrid(b, size, topLeft, topRight, bottomLeft, alignment, float64(size)-6.5); ok {
+					return text, true
+				}
+			}
+		}
+
+		if text, ok := decodeGrid(b, size, topLeft, topRight, bottomLeft, bottomRight, float64(size)-3.5); ok {
+			return text, true
+		}
+	}
+
+	// Version 1 has no alignment pattern, so in perspective its fourth corner
+	// has to be found by trying the places around the parallelogram's
+	for _, size := range dimensions(b, topLeft, topRight, bottomLeft) {
+		if size != 21 {
+			continue
+		}
+		for _, offset := range cornerOffsets {
+			corner := point{bottomRight.x + offset.x*moduleSize, bottomRight.y + offset.y*moduleSize}
+			if text, ok := decodeGrid(b, size, topLeft, topRight, bottomLeft, corner, float64(size)-3.5); ok {
+				return text, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// cornerOffsets are the moves, in modules, tried from the parallelogram's
+// fourth corner: half module steps up to three modules away, nearest first
+var cornerOffsets = func() []point {
+	var offsets []point
+	for dy := -3.0; dy <= 3; dy += 0.5 {
+		for dx := -3.0; dx <= 3; dx += 0.5 {
+			if (dx != 0 || dy != 0) && math.Hypot(dx, dy) <= 3 {
+				offsets = append(offsets, point{dx, dy})
+			}
+		}
+	}
+	sort.SliceStable(offsets, func(i, j int) bool {
+		return math.Hypot(offsets[i].x, offsets[i].y) < math.Hypot(offsets[j].x, offsets[j].y)
+	})
+	return offsets
+}()
+
+// decodeGrid samples a code of the given size and decodes it
+func decodeGrid(b *bitmap, size int, topLeft, topRight, bottomLeft *finder, bottomRight point, corner float64) (string, bool) {
+	matrix, ok := sampleGrid(b, size, topLeft.point, topRight.point, bottomLeft.point, bottomRight, corner)
+	if !ok {
+		return "", false
+	}
+
+	text, err := decodeMatrix(matrix)
+	return text, err == nil
+}
@@ -0,0 +1,218 @@
+package qrdecode
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// contents cover numeric, alphanumeric and byte mode, longest first
+var contents = []string{
+	"Caja de invierno: bufandas, guantes y gorros de lana",
+	"https://qrboxes.app/q/K7F-3QX",
+	"HTTP://BACKEND/Q/K7F-3QX",
+	"20261016",
+}
+
+// encode returns the longest of contents that fits the version and level,
+// encoded at exactly that version
+func encode(t *testing.T, version int, level qrcode.RecoveryLevel) *qrcode.QRCode {
+	t.Helper()
+
+	for _, content := range contents {
+		if code, err := qrcode.NewWithForcedVersion(content, version, level); err == nil {
+			return code
+		}
+	}
+	t.Fatalf("nothing fits version %d", version)
+	return nil
+}
+
+// render draws a bitmap with a quiet zone at scale pixels a module
+func render(bitmap [][]bool, scale int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(bitmap)*scale, len(bitmap)*scale))
+	for y, row := range bitmap {
+		for x, dark := range row {
+			colour := image.White
+			if dark {
+				colour = image.Black
+			}
+			draw.Draw(img, image.Rect(x*scale, y*scale, (x+1)*scale, (y+1)*scale), colour, image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+// rotate turns img by degrees about its centre onto a white canvas large
+// enough to hold it, blending neighbouring pixels as a camera would
+func rotate(img *image.Gray, degrees float64) *image.Gray {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	w, h := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	side := int(math.Ceil(math.Abs(w*cos)+math.Abs(h*sin))) + 2
+	out := image.NewGray(image.Rect(0, 0, side, side))
+
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= img.Bounds().Dx() || y >= img.Bounds().Dy() {
+			return 255
+		}
+		return float64(img.GrayAt(x, y).Y)
+	}
+
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			// Map back into the source image
+			dx, dy := float64(x)-float64(side)/2, float64(y)-float64(side)/2
+			sx, sy := cos*dx+sin*dy+w/2-0.5, -sin*dx+cos*dy+h/2-0.5
+			x0, y0 := int(math.Floor(sx)), int(math.Floor(sy))
+			fx, fy := sx-float64(x0), sy-float64(y0)
+			value := (at(x0, y0)*(1-fx)+at(x0+1, y0)*fx)*(1-fy) + (at(x0, y0+1)*(1-fx)+at(x0+1, y0+1)*fx)*fy
+			out.SetGray(x, y, color.Gray{Y: uint8(math.Round(value))})
+		}
+	}
+	return out
+}
+
+// addNoise adds gaussian noise with the given standard deviation
+func addNoise(img *image.Gray, deviation float64, seed int64) {
+	random := rand.New(rand.NewSource(seed))
+	for i, value := range img.Pix {
+		noisy := float64(value) + random.NormFloat64()*deviation
+		img.Pix[i] = uint8(math.Max(0, math.Min(255, math.Round(noisy))))
+	}
+}
+
+func TestDecodeMatrixEveryVersion(t *testing.T) {
+	for version := 1; version <= 40; version++ {
+		for name, level := range levels {
+			for _, content := range contents {
+				code, err := qrcode.NewWithForcedVersion(content, version, level)
+				if err != nil {
+					continue
+				}
+				code.DisableBorder = true
+
+				text, err := decodeMatrix(code.Bitmap())
+				if err != nil || text != content {
+					t.Errorf("version %d-%s %q: got %q, %v", version, name, content, text, err)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	transforms := []struct {
+		name    string
+		scale   int
+		degrees float64
+		noise   float64
+	}{
+		{"flat", 4, 0, 0},
+		{"small", 2, 0, 0},
+		{"large", 9, 0, 0},
+		{"quarter turn", 4, 90, 0},
+		{"upside down", 4, 180, 0},
+		{"three quarter turn", 4, 270, 0},
+		{"tilted", 5, 17, 0},
+		{"tilted back", 6, -38, 0},
+		{"noisy", 4, 0, 30},
+		{"tilted and noisy", 6, 131, 20},
+	}
+
+	for _, version := range []int{1, 2, 5, 7, 10, 15} {
+		for name, level := range levels {
+			code := encode(t, version, level)
+			bitmap := code.Bitmap()
+
+			for i, transform := range transforms {
+				t.Run(fmt.Sprintf("%d-%s/%s", version, name, transform.name), func(t *testing.T) {
+					img := render(bitmap, transform.scale)
+					if transform.degrees != 0 {
+						img = rotate(img, transform.degrees)
+					}
+					if transform.noise != 0 {
+						addNoise(img, transform.noise, int64(version*100+i))
+					}
+
+					text, err := Decode(img)
+					if err != nil || text != code.Content {
+						t.Errorf("got %q, %v; want %q", text, err, code.Content)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestDecodeAllFindsEveryCode(t *testing.T) {
+	want := []string{"https://qrboxes.app/q/AAA-AAA", "https://qrboxes.app/q/BBB-BBB", "https://qrboxes.app/q/CCC-CCC"}
+
+	sheet := image.NewGray(image.Rect(0, 0, 600, 200))
+	draw.Draw(sheet, sheet.Bounds(), image.White, image.Point{}, draw.Src)
+	for i, content := range want {
+		code, err := qrcode.New(content, qrcode.Medium)
+		if err != nil {
+			t.Fatalf("encode %q: %v", content, err)
+		}
+		img := render(code.Bitmap(), 5)
+		draw.Draw(sheet, img.Bounds().Add(image.Pt(i*200, 0)), img, image.Point{}, draw.Src)
+	}
+
+	found := make(map[string]bool)
+	for _, text := range DecodeAll(sheet) {
+		found[text] = true
+	}
+	for _, content := range want {
+		if !found[content] {
+			t.Errorf("%q not found", content)
+		}
+	}
+	if len(found) != len(want) {
+		t.Errorf("found %d codes, want %d", len(found), len(want))
+	}
+}
+
+func TestDecodeWithoutCode(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 200, 200))
+	draw.Draw(blank, blank.Bounds(), image.White, image.Point{}, draw.Src)
+	noise := image.NewGray(image.Rect(0, 0, 200, 200))
+	addNoise(noise, 100, 1)
+
+	for name, img := range map[string]*image.Gray{"blank": blank, "noise": noise} {
+		if text, err := Decode(img); err == nil {
+			t.Errorf("%s image decoded as %q", name, text)
+		}
+		if texts := DecodeAll(img); len(texts) != 0 {
+			t.Errorf("%s image gave %q", name, texts)
+		}
+	}
+}
+
+func TestDecodeAllContextStopsWhenDone(t *testing.T) {
+	code, err := qrcode.New("https://qrboxes.app/q/K7F-3QX", qrcode.Medium)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	texts, err := DecodeAllContext(ctx, render(code.Bitmap(), 4))
+	if err != context.Canceled || len(texts) != 0 {
+		t.Errorf("got %q, %v; want nothing and context.Canceled", texts, err)
+	}
+}
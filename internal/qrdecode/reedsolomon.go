@@ -0,0 +1,149 @@
+package qrdecode
+
+import "fmt"
+
+// Arithmetic in GF(256) with the QR code field polynomial x^8+x^4+x^3+x^2+1
+var gfExp, gfLog = func() (exp [512]byte, log [256]int) {
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+255-gfLog[b]]
+}
+
+// gfPow returns α^n
+func gfPow(n int) byte {
+	return gfExp[(n%255+255)%255]
+}
+
+// evaluate returns the value at x of a polynomial with coefficients from the
+// lowest degree up
+func evaluate(poly []byte, x byte) byte {
+	var y byte
+	for i := len(poly) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ poly[i]
+	}
+	return y
+}
+
+// correct fixes up to ecc/2 corrupted codewords of a block in place. The block
+// is its data codewords followed by ecc error correction codewords.
+func correct(block []byte, ecc int) error {
+	n := len(block)
+
+	// Syndromes S_j = C(α^j); all zero means the block is intact
+	syndromes := make([]byte, ecc)
+	intact := true
+	for j := range syndromes {
+		var s byte
+		for _, c := range block {
+			s = gfMul(s, gfPow(j)) ^ c
+		}
+		syndromes[j] = s
+		if s != 0 {
+			intact = false
+		}
+	}
+	if intact {
+		return nil
+	}
+
+	// Berlekamp-Massey finds the error locator polynomial
+	locator := []byte{1}
+	previous := []byte{1}
+	errors, shift := 0, 1
+	var lastDiscrepancy byte = 1
+	for step := 0; step < ecc; step++ {
+		discrepancy := syndromes[step]
+		for i := 1; i <= errors && i < len(locator); i++ {
+			discrepancy ^= gfMul(locator[i], syndromes[step-i])
+		}
+
+		if discrepancy == 0 {
+			shift++
+			continue
+		}
+
+		scale := gfDiv(discrepancy, lastDiscrepancy)
+		updated := make([]byte, max(len(locator), len(previous)+shift))
+		copy(updated, locator)
+		for i, c := range previous {
+			updated[i+shift] ^= gfMul(scale, c)
+		}
+
+		if 2*errors <= step {
+			previous = locator
+			errors = step + 1 - errors
+			lastDiscrepancy = discrepancy
+			shift = 1
+		} else {
+			shift++
+		}
+		locator = updated
+	}
+
+	if 2*errors > ecc {
+		return fmt.Errorf("too many errors")
+	}
+
+	// Error evaluator Ω(x) = S(x)Λ(x) mod x^ecc
+	evaluator := make([]byte, ecc)
+	for i, s := range syndromes {
+		for j, l := range locator {
+			if i+j < ecc {
+				evaluator[i+j] ^= gfMul(s, l)
+			}
+		}
+	}
+
+	// Formal derivative Λ'(x) keeps the odd-degree terms
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+
+	// Chien search over every position, with Forney's formula for the value
+	found := 0
+	for position := 0; position < n; position++ {
+		power := n - 1 - position
+		inverse := gfPow(-power)
+		if evaluate(locator, inverse) != 0 {
+			continue
+		}
+
+		denominator := evaluate(derivative, inverse)
+		if denominator == 0 {
+			return fmt.Errorf("too many errors")
+		}
+		block[position] ^= gfMul(gfPow(power), gfDiv(evaluate(evaluator, inverse), denominator))
+		found++
+	}
+
+	if found != errors {
+		return fmt.Errorf("too many errors")
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package qrdecode
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// encodeBlock appends ecc error correction codewords to data, dividing by the
+// generator polynomial (x-α^0)(x-α^1)...(x-α^(ecc-1)) as QR encoders do
+func encodeBlock(data []byte, ecc int) []byte {
+	generator := []byte{1}
+	for i := 0; i < ecc; i++ {
+		next := make([]byte, len(generator)+1)
+		for j, c := range generator {
+			next[j] ^= c
+			next[j+1] ^= gfMul(c, gfPow(i))
+		}
+		generator = next
+	}
+
+	block := make([]byte, len(data)+ecc)
+	copy(block, data)
+	for i := range data {
+		if factor := block[i]; factor != 0 {
+			for j := 1; j < len(generator); j++ {
+				block[i+j] ^= gfMul(generator[j], factor)
+			}
+		}
+	}
+	copy(block, data)
+	return block
+}
+
+// corrupt changes count distinct codewords of block to other values
+func corrupt(random *rand.Rand, block []byte, count int) {
+	for _, position := range random.Perm(len(block))[:count] {
+		block[position] ^= byte(1 + random.Intn(255))
+	}
+}
+
+// blockSizes are data and error correction lengths that QR codes use
+var blockSizes = []struct{ data, ecc int }{
+	{19, 7}, {16, 10}, {13, 13}, {9, 17}, {34, 10}, {22, 22},
+	{15, 26}, {108, 26}, {45, 28}, {15, 30}, {122, 30},
+}
+
+func TestCorrectIntactBlock(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, size := range blockSizes {
+		data := make([]byte, size.data)
+		random.Read(data)
+		block := encodeBlock(data, size.ecc)
+
+		if err := correct(block, size.ecc); err != nil {
+			t.Errorf("%d+%d intact block: %v", size.data, size.ecc, err)
+		}
+		if !bytes.Equal(block[:size.data], data) {
+			t.Errorf("%d+%d intact block was changed", size.data, size.ecc)
+		}
+	}
+}
+
+func TestCorrectUpToLimit(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	for _, size := range blockSizes {
+		for errors := 1; errors <= size.ecc/2; errors++ {
+			for trial := 0; trial < 20; trial++ {
+				data := make([]byte, size.data)
+				random.Read(data)
+				block := encodeBlock(data, size.ecc)
+				corrupt(random, block, errors)
+
+				if err := correct(block, size.ecc); err != nil {
+					t.Fatalf("%d+%d block with %d errors: %v", size.data, size.ecc, errors, err)
+				}
+				if !bytes.Equal(block, encodeBlock(data, size.ecc)) {
+					t.Fatalf("%d+%d block with %d errors was not restored", size.data, size.ecc, errors)
+				}
+			}
+		}
+	}
+}
+
+func TestCorrectPastLimit(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	for _, size := range blockSizes {
+		// One past the limit, and a block that is mostly noise
+		for _, errors := range []int{size.ecc/2 + 1, size.ecc/2 + 2, (size.data + size.ecc) / 2} {
+			for trial := 0; trial < 50; trial++ {
+				data := make([]byte, size.data)
+				random.Read(data)
+				block := encodeBlock(data, size.ecc)
+				corrupt(random, block, errors)
+
+				// Landing within ecc/2 of another codeword is possible but
+				// far too unlikely to happen in these trials
+				if err := correct(block, size.ecc); err == nil {
+					t.Fatalf("%d+%d block with %d errors was accepted", size.data, size.ecc, errors)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// boxCursor marks the last box of a page. It is handed to clients as an opaque
+// base64 token and records the ordering it was issued for, so it can't be
+// replayed against a different sort.
+type boxCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    string `json:"i"`
+}
+
+func encodeBoxCursor(cursor boxCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeBoxCursor(token string) (*boxCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var cursor boxCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &cursor, nil
+}
+
+// prepareBoxList fills in default ordering and decodes the cursor, checking
+// that it belongs to the requested ordering
+func prepareBoxList(options models.BoxListOptions) (models.BoxListOptions, *boxCursor, error) {
+	options.Sort = strings.ToLower(strings.TrimSpace(options.Sort))
+	options.Order = strings.ToLower(strings.TrimSpace(options.Order))
+	options.Room = strings.TrimSpace(options.Room)
+
+	tags, err := prepareTagFilter(options.Tags)
+	if err != nil {
+		return options, nil, err
+	}
+	options.Tags = tags
+
+	switch options.Sort {
+	case "":
+		options.Sort = models.BoxSortCreated
+	case models.BoxSortName, models.BoxSortRoom, models.BoxSortCreated, models.BoxSortUpdated:
+	default:
+		return options, nil, fmt.Errorf("invalid sort field")
+	}
+
+	switch options.Order {
+	case "":
+		// Text sorts read naturally A-Z; time sorts show the newest first
+		if options.Sort == models.BoxSortName || options.Sort == models.BoxSortRoom {
+			options.Order = models.SortAscending
+		} else {
+			options.Order = models.SortDescending
+		}
+	case models.SortAscending, models.SortDescending:
+	default:
+		return options, nil, fmt.Errorf("invalid sort order")
+	}
+
+	if options.Limit < 0 {
+		return options, nil, fmt.Errorf("invalid limit")
+	}
+
+	if options.Cursor == "" {
+		return options, nil, nil
+	}
+
+	cursor, err := decodeBoxCursor(options.Cursor)
+	if err != nil {
+		return options, nil, err
+	}
+
+	if cursor.Sort != options.Sort || cursor.Order != options.Order {
+		return options, nil, fmt.Errorf("invalid cursor")
+	}
+
+	if isTimeSort(options.Sort) {
+		if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return options, nil, fmt.Errorf("invalid cursor")
+		}
+	}
+
+	return options, cursor, nil
+}
+
+func isTimeSort(sort string) bool {
+	return sort == models.BoxSortCreated || sort == models.BoxSortUpdated
+}
+
+// boxSortValue returns the value a box is ordered by, in the same form the SQL
+// stores return it: lower-cased text or an RFC 3339 timestamp
+func boxSortValue(box *models.Box, sort string) string {
+	switch sort {
+	case models.BoxSortName:
+		return strings.ToLower(box.Name)
+	case models.BoxSortRoom:
+		return strings.ToLower(box.Room)
+	case models.BoxSortUpdated:
+		return box.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return box.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
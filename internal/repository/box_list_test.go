@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// testStores returns an empty memory store and an empty SQLite database, so
+// that each test checks both behave the same
+func testStores(t *testing.T) map[string]*Stores {
+	t.Helper()
+
+	db, err := database.NewConnection(database.DriverSQLite, "sqlite:"+filepath.Join(t.TempDir(), "boxes.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrate sqlite: %v", err)
+	}
+
+	return map[string]*Stores{
+		"memory": NewMemoryStores(),
+		"sqlite": NewStores(db),
+	}
+}
+
+// seedBoxes creates a workspace for userID holding count boxes. Names and
+// creation times repeat so that orderings have ties to break.
+func seedBoxes(t *testing.T, stores *Stores, userID string, count int) []*models.Box {
+	t.Helper()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	workspace := &models.Workspace{ID: uuid.New().String(), Name: "Home", CreatedBy: userID, CreatedAt: now, UpdatedAt: now}
+	if err := stores.Workspaces.CreateWorkspace(workspace); err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+
+	rooms := []string{"Garage", "attic", "Kitchen"}
+	boxes := make([]*models.Box, 0, count)
+	for i := 0; i < count; i++ {
+		created := now.Add(-time.Duration(i/2) * time.Minute)
+		box := &models.Box{
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			WorkspaceID: workspace.ID,
+			Name:        fmt.Sprintf("Box %d", i%5),
+			Room:        rooms[i%len(rooms)],
+			CreatedAt:   created,
+			UpdatedAt:   created,
+		}
+		if err := stores.Boxes.Create(box); err != nil {
+			t.Fatalf("create box: %v", err)
+		}
+		boxes = append(boxes, box)
+	}
+
+	return boxes
+}
+
+// expectedOrder sorts boxes the way ListByUserID must: by the sort field, then by ID
+func expectedOrder(boxes []*models.Box, field, order string) []string {
+	sorted := append([]*models.Box{}, boxes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		var result int
+		switch field {
+		case models.BoxSortName:
+			result = strings.Compare(strings.ToLower(sorted[i].Name), strings.ToLower(sorted[j].Name))
+		case models.BoxSortRoom:
+			result = strings.Compare(strings.ToLower(sorted[i].Room), strings.ToLower(sorted[j].Room))
+		default:
+			result = sorted[i].CreatedAt.Compare(sorted[j].CreatedAt)
+		}
+		if result == 0 {
+			result = strings.Compare(sorted[i].ID, sorted[j].ID)
+		}
+		if order == models.SortDescending {
+			result = -result
+		}
+		return result < 0
+	})
+
+	ids := make([]string, len(sorted))
+	for i, box := range sorted {
+		ids[i] = box.ID
+	}
+	return ids
+}
+
+func TestListByUserIDPagesThroughEveryBoxOnce(t *testing.T) {
+	tests := []struct {
+		sort  string
+		order string
+	}{
+		{models.BoxSortName, models.SortAscending},
+		{models.BoxSortName, models.SortDescending},
+		{models.BoxSortRoom, models.SortAscending},
+		{models.BoxSortCreated, models.SortAscending},
+		{models.BoxSortCreated, models.SortDescending},
+	}
+
+	for name, stores := range testStores(t) {
+		boxes := seedBoxes(t, stores, "user-1", 17)
+
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/%s-%s", name, tt.sort, tt.order), func(t *testing.T) {
+				options := models.BoxListOptions{Limit: 4, Sort: tt.sort, Order: tt.order}
+
+				var got []string
+				for pages := 0; ; pages++ {
+					if pages > len(boxes) {
+						t.Fatal("pagination did not finish")
+					}
+
+					page, err := stores.Boxes.ListByUserID("user-1", options)
+					if err != nil {
+						t.Fatalf("ListByUserID: %v", err)
+					}
+					if page.Total != len(boxes) {
+						t.Errorf("Total = %d, want %d", page.Total, len(boxes))
+					}
+					if page.Count != len(page.Boxes) {
+						t.Errorf("Count = %d, want %d", page.Count, len(page.Boxes))
+					}
+
+					for _, box := range page.Boxes {
+						got = append(got, box.ID)
+					}
+
+					if page.NextCursor == "" {
+						break
+					}
+					if len(page.Boxes) != options.Limit {
+						t.Errorf("page with a next cursor has %d boxes, want %d", len(page.Boxes), options.Limit)
+					}
+					options.Cursor = page.NextCursor
+				}
+
+				want := expectedOrder(boxes, tt.sort, tt.order)
+				if strings.Join(got, ",") != strings.Join(want, ",") {
+					t.Errorf("paged order differs\n got %v\nwant %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestListByUserIDCursorSurvivesInserts(t *testing.T) {
+	for name, stores := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			boxes := seedBoxes(t, stores, "user-1", 6)
+
+			options := models.BoxListOptions{Limit: 3, Sort: models.BoxSortName}
+			first, err := stores.Boxes.ListByUserID("user-1", options)
+			if err != nil {
+				t.Fatalf("ListByUserID: %v", err)
+			}
+
+			// A box sorting before the cursor must not shift the next page
+			early := *boxes[0]
+			early.ID, early.Name = uuid.New().String(), "Aaa"
+			if err := stores.Boxes.Create(&early); err != nil {
+				t.Fatalf("create box: %v", err)
+			}
+
+			options.Cursor = first.NextCursor
+			second, err := stores.Boxes.ListByUserID("user-1", options)
+			if err != nil {
+				t.Fatalf("ListByUserID: %v", err)
+			}
+
+			seen := make(map[string]bool)
+			for _, box := range first.Boxes {
+				seen[box.ID] = true
+			}
+			for _, box := range second.Boxes {
+				if seen[box.ID] {
+					t.Errorf("box %s appears on both pages", box.Name)
+				}
+				if box.ID == early.ID {
+					t.Errorf("box inserted before the cursor appears on the next page")
+				}
+			}
+			if len(first.Boxes)+len(second.Boxes) != len(boxes) {
+				t.Errorf("got %d boxes over two pages, want %d", len(first.Boxes)+len(second.Boxes), len(boxes))
+			}
+		})
+	}
+}
+
+func TestListByUserIDRejectsBadOptions(t *testing.T) {
+	stores := NewMemoryStores()
+	seedBoxes(t, stores, "user-1", 5)
+
+	page, err := stores.Boxes.ListByUserID("user-1", models.BoxListOptions{Limit: 2, Sort: models.BoxSortName})
+	if err != nil {
+		t.Fatalf("ListByUserID: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		options models.BoxListOptions
+		want    string
+	}{
+		{"cursor from another sort", models.BoxListOptions{Cursor: page.NextCursor, Sort: models.BoxSortCreated}, "invalid cursor"},
+		{"cursor from another order", models.BoxListOptions{Cursor: page.NextCursor, Sort: models.BoxSortName, Order: models.SortDescending}, "invalid cursor"},
+		{"garbled cursor", models.BoxListOptions{Cursor: "not-a-cursor"}, "invalid cursor"},
+		{"unknown sort", models.BoxListOptions{Sort: "colour"}, "invalid sort field"},
+		{"unknown order", models.BoxListOptions{Order: "sideways"}, "invalid sort order"},
+		{"negative limit", models.BoxListOptions{Limit: -1}, "invalid limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := stores.Boxes.ListByUserID("user-1", tt.options)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestListByUserIDOnlyShowsMemberWorkspaces(t *testing.T) {
+	for name, stores := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			seedBoxes(t, stores, "user-1", 3)
+			seedBoxes(t, stores, "user-2", 2)
+
+			page, err := stores.Boxes.ListByUserID("user-2", models.BoxListOptions{})
+			if err != nil {
+				t.Fatalf("ListByUserID: %v", err)
+			}
+			if page.Total != 2 || len(page.Boxes) != 2 {
+				t.Errorf("user-2 sees %d of %d boxes, want 2", len(page.Boxes), page.Total)
+			}
+		})
+	}
+}
@@ -3,9 +3,9 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/lib/pq"
 	"github.com/qr-boxes/backend/internal/database"
 	"github.com/qr-boxes/backend/internal/models"
 )
@@ -18,58 +18,172 @@ func NewBoxRepository(db *database.DB) *BoxRepository {
 	return &BoxRepository{db: db}
 }
 
+// Create inserts the box with its items and tags in one transaction
 func (r *BoxRepository) Create(box *models.Box) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertBox(tx, box); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit box: %w", err)
+	}
+
+	return nil
+}
+
+// CreateBoxes inserts the boxes with their items and tags in one transaction,
+// so that either all of them are created or none is. The new locations in
+// rooms, which the boxes may be put in, are inserted first in the same transaction.
+func (r *BoxRepository) CreateBoxes(boxes []*models.Box, rooms []*models.Location) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, room := range rooms {
+		if err := insertLocation(tx.Exec, room); err != nil {
+			return err
+		}
+	}
+
+	for _, box := range boxes {
+		if err := insertBox(tx, box); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit boxes: %w", err)
+	}
+
+	return nil
+}
+
+// insertBox adds a box row with its items and tags
+func insertBox(tx *database.Tx, box *models.Box) error {
 	query := `
-		INSERT INTO boxes (id, user_id, name, description, room, items, qr_code, qr_code_url, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO boxes (id, short_code, user_id, workspace_id, name, description, room, location_id, parent_box_id, visibility, pin_hash, qr_code, qr_code_url, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`
 
-	_, err := r.db.Exec(
+	_, err := tx.Exec(
 		query,
 		box.ID,
+		nullableString(box.ShortCode),
 		box.UserID,
+		box.WorkspaceID,
 		box.Name,
 		box.Description,
 		box.Room,
-		pq.Array(box.Items),
+		nullableString(box.LocationID),
+		nullableString(box.ParentBoxID),
+		box.Visibility,
+		box.PINHash,
 		box.QRCode,
 		box.QRCodeURL,
-		box.CreatedAt,
-		box.UpdatedAt,
+		box.CreatedAt.UTC(),
+		box.UpdatedAt.UTC(),
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to create box: %w", err)
 	}
 
+	if err := insertItems(tx, box.ID, box.Items); err != nil {
+		return err
+	}
+
+	tagIDs := make([]string, len(box.Tags))
+	for i, tag := range box.Tags {
+		tagIDs[i] = tag.ID
+	}
+	return insertBoxTags(tx, box.ID, tagIDs)
+}
+
+// insertBoxTags puts the given tags on a box
+func insertBoxTags(tx *database.Tx, boxID string, tagIDs []string) error {
+	for _, tagID := range tagIDs {
+		if _, err := tx.Exec(`INSERT INTO box_tags (box_id, tag_id) VALUES ($1, $2)`, boxID, tagID); err != nil {
+			return fmt.Errorf("failed to tag box: %w", err)
+		}
+	}
 	return nil
 }
 
-func (r *BoxRepository) GetByID(id string) (*models.Box, error) {
-	query := `
-		SELECT id, user_id, name, description, room, items, qr_code, qr_code_url, created_at, updated_at
-		FROM boxes
-		WHERE id = $1
-	`
+// boxColumns lists the columns scanBox expects, in order
+const boxColumns = `id, short_code, user_id, workspace_id, name, description, room, location_id, parent_box_id, visibility, pin_hash, qr_code, qr_code_url, created_at, updated_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanBox reads the boxColumns of one row, followed by any extra columns
+func scanBox(row rowScanner, extra ...interface{}) (*models.Box, error) {
 	box := &models.Box{}
-	var items pq.StringArray
+	var shortCode sql.NullString
 	var description sql.NullString
 	var room sql.NullString
+	var locationID sql.NullString
+	var parentBoxID sql.NullString
 
-	err := r.db.QueryRow(query, id).Scan(
+	dest := []interface{}{
 		&box.ID,
+		&shortCode,
 		&box.UserID,
+		&box.WorkspaceID,
 		&box.Name,
 		&description,
 		&room,
-		&items,
+		&locationID,
+		&parentBoxID,
+		&box.Visibility,
+		&box.PINHash,
 		&box.QRCode,
 		&box.QRCodeURL,
 		&box.CreatedAt,
 		&box.UpdatedAt,
-	)
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	// Handle NULL short code, description, room, location and parent box
+	box.ShortCode = shortCode.String
+	box.Description = description.String
+	box.Room = room.String
+	box.LocationID = locationID.String
+	box.ParentBoxID = parentBoxID.String
+
+	return box, nil
+}
+
+// attachDetails loads the items, photos, tags and location breadcrumbs of the given boxes
+func attachDetails(db *database.DB, boxes []*models.Box) error {
+	if err := attachItems(db, boxes); err != nil {
+		return err
+	}
+	if err := attachPhotos(db, boxes); err != nil {
+		return err
+	}
+	if err := attachTags(db, boxes); err != nil {
+		return err
+	}
+	return attachLocations(db, boxes)
+}
+
+func (r *BoxRepository) GetByID(id string) (*models.Box, error) {
+	query := `SELECT ` + boxColumns + ` FROM boxes WHERE id = $1`
 
+	box, err := scanBox(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("box not found")
@@ -77,29 +191,124 @@ func (r *BoxRepository) GetByID(id string) (*models.Box, error) {
 		return nil, fmt.Errorf("failed to get box: %w", err)
 	}
 
-	// Handle NULL description
-	if description.Valid {
-		box.Description = description.String
-	} else {
-		box.Description = ""
+	if err := attachDetails(r.db, []*models.Box{box}); err != nil {
+		return nil, err
 	}
 
-	// Handle NULL room
-	if room.Valid {
-		box.Room = room.String
-	} else {
-		box.Room = ""
+	return box, nil
+}
+
+// GetByShortCode finds a box by its short code, as in "K7F-3QX"
+func (r *BoxRepository) GetByShortCode(code string) (*models.Box, error) {
+	query := `SELECT ` + boxColumns + ` FROM boxes WHERE short_code = $1`
+
+	box, err := scanBox(r.db.QueryRow(query, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("box not found")
+		}
+		return nil, fmt.Errorf("failed to get box: %w", err)
+	}
+
+	if err := attachDetails(r.db, []*models.Box{box}); err != nil {
+		return nil, err
 	}
 
-	box.Items = []string(items)
 	return box, nil
 }
 
+// GetBoxIDsWithoutShortCode returns the boxes created before short codes existed
+func (r *BoxRepository) GetBoxIDsWithoutShortCode() ([]string, error) {
+	rows, err := r.db.Query(`SELECT id FROM boxes WHERE short_code IS NULL ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get boxes without short code: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan box id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return ids, nil
+}
+
+// SetShortCode gives a box without one its short code
+func (r *BoxRepository) SetShortCode(boxID, code string) error {
+	result, err := r.db.Exec(`UPDATE boxes SET short_code = $2 WHERE id = $1 AND short_code IS NULL`, boxID, code)
+	if err != nil {
+		return fmt.Errorf("failed to set short code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("box not found")
+	}
+
+	return nil
+}
+
+// GetShortCodes returns the short code of every box that has one, by box ID
+func (r *BoxRepository) GetShortCodes() (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT id, short_code FROM boxes WHERE short_code IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := make(map[string]string)
+	for rows.Next() {
+		var id, code string
+		if err := rows.Scan(&id, &code); err != nil {
+			return nil, fmt.Errorf("failed to scan short code: %w", err)
+		}
+		codes[id] = code
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return codes, nil
+}
+
+// SetQRCode replaces the stored QR code image and the URL it encodes
+func (r *BoxRepository) SetQRCode(boxID, qrCode, qrCodeURL string) error {
+	result, err := r.db.Exec(`UPDATE boxes SET qr_code = $2, qr_code_url = $3 WHERE id = $1`, boxID, qrCode, qrCodeURL)
+	if err != nil {
+		return fmt.Errorf("failed to set QR code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("box not found")
+	}
+
+	return nil
+}
+
+// GetByUserID returns every box in the workspaces the user belongs to, newest first
 func (r *BoxRepository) GetByUserID(userID string) ([]*models.Box, error) {
 	query := `
-		SELECT id, user_id, name, description, room, items, qr_code, qr_code_url, created_at, updated_at
+		SELECT ` + boxColumns + `
 		FROM boxes
-		WHERE user_id = $1
+		WHERE ` + memberWorkspaces("workspace_id", "$1") + `
 		ORDER BY created_at DESC
 	`
 
@@ -112,71 +321,268 @@ func (r *BoxRepository) GetByUserID(userID string) ([]*models.Box, error) {
 	var boxes []*models.Box
 
 	for rows.Next() {
-		box := &models.Box{}
-		var items pq.StringArray
-		var description sql.NullString
-		var room sql.NullString
-
-		err := rows.Scan(
-			&box.ID,
-			&box.UserID,
-			&box.Name,
-			&description,
-			&room,
-			&items,
-			&box.QRCode,
-			&box.QRCodeURL,
-			&box.CreatedAt,
-			&box.UpdatedAt,
-		)
-
+		box, err := scanBox(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan box: %w", err)
 		}
+		boxes = append(boxes, box)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if err := attachDetails(r.db, boxes); err != nil {
+		return nil, err
+	}
+
+	return boxes, nil
+}
+
+// boxSortExpressions maps each sort field to the SQL expression it orders by.
+// Text sorts are case-insensitive; a missing room sorts as an empty string.
+var boxSortExpressions = map[string]string{
+	models.BoxSortName:    "LOWER(name)",
+	models.BoxSortRoom:    "LOWER(COALESCE(room, ''))",
+	models.BoxSortCreated: "created_at",
+	models.BoxSortUpdated: "updated_at",
+}
+
+// ListByUserID returns one page of the boxes in the user's workspaces using
+// keyset pagination on the sort expression with the box ID as a tie-breaker
+func (r *BoxRepository) ListByUserID(userID string, options models.BoxListOptions) (*models.BoxPage, error) {
+	options, cursor, err := prepareBoxList(options)
+	if err != nil {
+		return nil, err
+	}
+
+	sortExpr := boxSortExpressions[options.Sort]
+
+	conditions := []string{memberWorkspaces("workspace_id", "$1")}
+	args := []interface{}{userID}
+
+	if options.WorkspaceID != "" {
+		args = append(args, options.WorkspaceID)
+		conditions = append(conditions, fmt.Sprintf("workspace_id = $%d", len(args)))
+	}
+
+	if options.Room != "" {
+		args = append(args, options.Room)
+		conditions = append(conditions, fmt.Sprintf("LOWER(TRIM(COALESCE(room, ''))) = LOWER($%d)", len(args)))
+	}
+
+	// A location filter also matches boxes in any location below it
+	if options.LocationID != "" {
+		args = append(args, options.LocationID)
+		conditions = append(conditions, fmt.Sprintf(`location_id IN (
+			WITH RECURSIVE subtree(id) AS (
+				SELECT id FROM locations WHERE id = $%d AND `+memberWorkspaces("workspace_id", "$1")+`
+				UNION ALL
+				SELECT locations.id FROM locations JOIN subtree ON locations.parent_id = subtree.id
+			)
+			SELECT id FROM subtree
+		)`, len(args)))
+	}
+
+	if condition, tagArgs := tagFilterCondition(options.Tags, args); condition != "" {
+		args = tagArgs
+		conditions = append(conditions, condition)
+	}
+
+	if options.UpdatedSince != nil {
+		args = append(args, options.UpdatedSince.UTC())
+		conditions = append(conditions, fmt.Sprintf("updated_at >= $%d", len(args)))
+	}
+
+	// The total ignores the cursor so it stays the same on every page
+	var total int
+	countQuery := `SELECT COUNT(*) FROM boxes WHERE ` + strings.Join(conditions, " AND ")
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count user boxes: %w", err)
+	}
+
+	direction := "ASC"
+	comparison := ">"
+	if options.Order == models.SortDescending {
+		direction = "DESC"
+		comparison = "<"
+	}
+
+	if cursor != nil {
+		var value interface{} = cursor.Value
+		if isTimeSort(options.Sort) {
+			value, _ = time.Parse(time.RFC3339Nano, cursor.Value)
+		}
+		args = append(args, value, cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", sortExpr, comparison, len(args)-1, len(args)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM boxes
+		WHERE %s
+		ORDER BY %s %s, id %s
+	`, boxColumns, sortExpr, strings.Join(conditions, " AND "), sortExpr, direction, direction)
+
+	// Fetch one extra row to learn whether another page follows
+	if options.Limit > 0 {
+		args = append(args, options.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user boxes: %w", err)
+	}
+	defer rows.Close()
 
-		// Handle NULL description
-		if description.Valid {
-			box.Description = description.String
-		} else {
-			box.Description = ""
+	boxes := make([]*models.Box, 0)
+	sortValues := make([]string, 0)
+
+	for rows.Next() {
+		var sortValue string
+		box, err := scanBox(rows, &sortValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan box: %w", err)
 		}
+		boxes = append(boxes, box)
+		sortValues = append(sortValues, sortValue)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
 
-		// Handle NULL room
-		if room.Valid {
-			box.Room = room.String
-		} else {
-			box.Room = ""
+	page := &models.BoxPage{Total: total}
+	if options.Limit > 0 && len(boxes) > options.Limit {
+		boxes = boxes[:options.Limit]
+		sortValues = sortValues[:options.Limit]
+		last := boxes[len(boxes)-1]
+		page.NextCursor = encodeBoxCursor(boxCursor{
+			Sort:  options.Sort,
+			Order: options.Order,
+			Value: sortValues[len(boxes)-1],
+			ID:    last.ID,
+		})
+	}
+
+	if err := attachDetails(r.db, boxes); err != nil {
+		return nil, err
+	}
+
+	page.Boxes = boxes
+	page.Count = len(boxes)
+	return page, nil
+}
+
+// Search finds the boxes in the user's workspaces matching query and carrying the filter's
+// tags, best matches first. PostgreSQL uses the tsvector and trigram indexes;
+// SQLite ranks the user's boxes in Go.
+func (r *BoxRepository) Search(userID, query string, limit int, tags models.TagFilter) ([]*models.BoxSearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty search query")
+	}
+
+	tags, err := prepareTagFilter(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.db.IsSQLite() {
+		boxes, err := r.GetByUserID(userID)
+		if err != nil {
+			return nil, err
 		}
+		return searchBoxes(boxes, terms, limit, tags), nil
+	}
+
+	args := []interface{}{userID, prefixTSQuery(terms), strings.Join(terms, " "), limit}
+	tagCondition, args := tagFilterCondition(tags, args)
+	if tagCondition != "" {
+		tagCondition = "AND " + tagCondition
+	}
 
-		box.Items = []string(items)
+	sqlQuery := `
+		SELECT ` + boxColumns + `,
+			ts_rank(search_vector, to_tsquery('simple', $2))
+				+ 0.5 * word_similarity($3, box_search_text(name, description, room, items_text)) AS rank
+		FROM boxes
+		WHERE ` + memberWorkspaces("workspace_id", "$1") + `
+			AND (search_vector @@ to_tsquery('simple', $2)
+				OR $3 <% box_search_text(name, description, room, items_text))
+			` + tagCondition + `
+		ORDER BY rank DESC, created_at DESC
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search boxes: %w", err)
+	}
+	defer rows.Close()
+
+	var boxes []*models.Box
+	var ranks []float64
+	for rows.Next() {
+		var rank float64
+		box, err := scanBox(rows, &rank)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan box: %w", err)
+		}
 		boxes = append(boxes, box)
+		ranks = append(ranks, rank)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return boxes, nil
+	if err := attachDetails(r.db, boxes); err != nil {
+		return nil, err
+	}
+
+	// PostgreSQL decides what matches and how it ranks; matchBox only
+	// explains each hit
+	results := make([]*models.BoxSearchResult, 0, len(boxes))
+	for i, box := range boxes {
+		result, _ := matchBox(box, terms)
+		result.Rank = ranks[i]
+		results = append(results, result)
+	}
+
+	return results, nil
 }
 
-func (r *BoxRepository) Update(box *models.Box) error {
+// Update saves the box's own fields. A non-nil tagIDs or items replaces the
+// box's tags or contents in the same transaction.
+func (r *BoxRepository) Update(box *models.Box, tagIDs []string, items []models.Item) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE boxes
-		SET name = $2, description = $3, room = $4, items = $5, updated_at = $6
-		WHERE id = $1 AND user_id = $7
+		SET name = $2, description = $3, room = $4, location_id = $5, parent_box_id = $6, visibility = $7, pin_hash = $8, updated_at = $9
+		WHERE id = $1 AND workspace_id = $10
 	`
 
 	box.UpdatedAt = time.Now()
 
-	result, err := r.db.Exec(
+	result, err := tx.Exec(
 		query,
 		box.ID,
 		box.Name,
 		box.Description,
 		box.Room,
-		pq.Array(box.Items),
-		box.UpdatedAt,
-		box.UserID,
+		nullableString(box.LocationID),
+		nullableString(box.ParentBoxID),
+		box.Visibility,
+		box.PINHash,
+		box.UpdatedAt.UTC(),
+		box.WorkspaceID,
 	)
 
 	if err != nil {
@@ -192,13 +598,43 @@ func (r *BoxRepository) Update(box *models.Box) error {
 		return fmt.Errorf("box not found or unauthorized")
 	}
 
+	if tagIDs != nil {
+		if _, err := tx.Exec(`DELETE FROM box_tags WHERE box_id = $1`, box.ID); err != nil {
+			return fmt.Errorf("failed to clear box tags: %w", err)
+		}
+		if err := insertBoxTags(tx, box.ID, tagIDs); err != nil {
+			return err
+		}
+	}
+
+	if items != nil {
+		if _, err := tx.Exec(`DELETE FROM items WHERE box_id = $1`, box.ID); err != nil {
+			return fmt.Errorf("failed to clear box items: %w", err)
+		}
+		if err := insertItems(tx, box.ID, items); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit box: %w", err)
+	}
+
 	return nil
 }
 
+// Delete removes a box from one of the user's workspaces. Boxes that were
+// inside it move to the top level.
 func (r *BoxRepository) Delete(id, userID string) error {
-	query := `DELETE FROM boxes WHERE id = $1 AND user_id = $2`
+	query := `DELETE FROM boxes WHERE id = $1 AND ` + memberWorkspaces("workspace_id", "$2")
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	result, err := r.db.Exec(query, id, userID)
+	result, err := tx.Exec(query, id, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete box: %w", err)
 	}
@@ -212,11 +648,21 @@ func (r *BoxRepository) Delete(id, userID string) error {
 		return fmt.Errorf("box not found or unauthorized")
 	}
 
+	_, err = tx.Exec(`UPDATE boxes SET parent_box_id = NULL, updated_at = $2 WHERE parent_box_id = $1`, id, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to detach inner boxes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit box deletion: %w", err)
+	}
+
 	return nil
 }
 
+// GetUserBoxCount counts the boxes in the workspaces the user belongs to
 func (r *BoxRepository) GetUserBoxCount(userID string) (int, error) {
-	query := `SELECT COUNT(*) FROM boxes WHERE user_id = $1`
+	query := `SELECT COUNT(*) FROM boxes WHERE ` + memberWorkspaces("workspace_id", "$1")
 
 	var count int
 	err := r.db.QueryRow(query, userID).Scan(&count)
@@ -226,3 +672,104 @@ func (r *BoxRepository) GetUserBoxCount(userID string) (int, error) {
 
 	return count, nil
 }
+
+// maxBoxNesting bounds how deep containment chains and content trees are
+// followed, as a guard against runaway recursion
+const maxBoxNesting = 32
+
+// GetContainmentChains returns, for each of the given boxes, the boxes it sits
+// inside, outermost first. Boxes at the top level are left out of the map.
+func (r *BoxRepository) GetContainmentChains(boxIDs []string) (map[string][]models.BoxCrumb, error) {
+	chains := make(map[string][]models.BoxCrumb)
+	if len(boxIDs) == 0 {
+		return chains, nil
+	}
+
+	placeholders := make([]string, len(boxIDs))
+	args := make([]interface{}, len(boxIDs))
+	for i, id := range boxIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	args = append(args, maxBoxNesting)
+
+	query := fmt.Sprintf(`
+		WITH RECURSIVE chain(start_id, id, name, parent_box_id, depth) AS (
+			SELECT inner_box.id, outer_box.id, outer_box.name, outer_box.parent_box_id, 1
+			FROM boxes inner_box
+			JOIN boxes outer_box ON outer_box.id = inner_box.parent_box_id
+			WHERE inner_box.id IN (%s)
+			UNION ALL
+			SELECT chain.start_id, outer_box.id, outer_box.name, outer_box.parent_box_id, chain.depth + 1
+			FROM chain
+			JOIN boxes outer_box ON outer_box.id = chain.parent_box_id
+			WHERE chain.depth < $%d
+		)
+		SELECT start_id, id, name FROM chain ORDER BY start_id, depth DESC
+	`, strings.Join(placeholders, ", "), len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get containment chains: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var startID string
+		var crumb models.BoxCrumb
+		if err := rows.Scan(&startID, &crumb.ID, &crumb.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan containment chain: %w", err)
+		}
+		chains[startID] = append(chains[startID], crumb)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return chains, nil
+}
+
+// GetDescendants returns every box nested inside boxID at any depth, with
+// their items
+func (r *BoxRepository) GetDescendants(boxID string) ([]*models.Box, error) {
+	query := `
+		WITH RECURSIVE descendants(id, depth) AS (
+			SELECT id, 1 FROM boxes WHERE parent_box_id = $1
+			UNION ALL
+			SELECT boxes.id, descendants.depth + 1
+			FROM boxes
+			JOIN descendants ON boxes.parent_box_id = descendants.id
+			WHERE descendants.depth < $2
+		)
+		SELECT ` + boxColumns + `
+		FROM boxes
+		WHERE id IN (SELECT id FROM descendants)
+		ORDER BY LOWER(name), id
+	`
+
+	rows, err := r.db.Query(query, boxID, maxBoxNesting)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inner boxes: %w", err)
+	}
+	defer rows.Close()
+
+	boxes := make([]*models.Box, 0)
+	for rows.Next() {
+		box, err := scanBox(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan box: %w", err)
+		}
+		boxes = append(boxes, box)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if err := attachDetails(r.db, boxes); err != nil {
+		return nil, err
+	}
+
+	return boxes, nil
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// Field weights for ranking search hits. They follow the A/B/C/D tsvector
+// weights in the PostgreSQL search migration: name, items, room, description.
+var searchFieldWeights = map[string]float64{
+	"name":        1.0,
+	"items":       0.4,
+	"room":        0.2,
+	"description": 0.1,
+}
+
+// searchFieldOrder is the order matched fields are reported in
+var searchFieldOrder = []string{"name", "items", "room", "description"}
+
+// fuzzyMatchThreshold is the minimum trigram word similarity for a typo match,
+// the same as pg_trgm's default word_similarity_threshold
+const fuzzyMatchThreshold = 0.6
+
+// fuzzyMatchWeight discounts typo matches against exact word or prefix matches
+const fuzzyMatchWeight = 0.5
+
+// searchTerms splits a query into lower-cased words of letters and digits
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// prefixTSQuery builds a to_tsquery expression requiring every term as a prefix
+func prefixTSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// matchTerm scores how well term matches text: 1 when a word of text starts
+// with term, a discounted trigram similarity for near misses, otherwise 0
+func matchTerm(term, text string) float64 {
+	best := 0.0
+	for _, word := range searchTerms(text) {
+		if strings.HasPrefix(word, term) {
+			return 1
+		}
+
+		// Very short terms produce too few trigrams to tell typos from noise
+		if len([]rune(term)) < 3 {
+			continue
+		}
+
+		if similarity := wordSimilarity(term, word); similarity >= fuzzyMatchThreshold && similarity*fuzzyMatchWeight > best {
+			best = similarity * fuzzyMatchWeight
+		}
+	}
+	return best
+}
+
+// wordSimilarity returns the share of term's trigrams that also occur in word,
+// padding words the way pg_trgm does
+func wordSimilarity(term, word string) float64 {
+	termTrigrams := trigrams(term)
+	if len(termTrigrams) == 0 {
+		return 0
+	}
+
+	wordTrigrams := trigrams(word)
+	shared := 0
+	for trigram := range termTrigrams {
+		if wordTrigrams[trigram] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(termTrigrams))
+}
+
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	set := make(map[string]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
+
+// matchBox scores box against the search terms and records which fields and
+// items matched. The rank sums each term's best weighted match; ok is false
+// unless every term matched some field, exactly or as a typo.
+func matchBox(box *models.Box, terms []string) (result *models.BoxSearchResult, ok bool) {
+	fields := map[string]string{
+		"name":        box.Name,
+		"room":        box.Room,
+		"description": box.Description,
+	}
+
+	matchedFields := make(map[string]bool)
+	matchedItems := make(map[int]bool)
+	rank := 0.0
+	ok = true
+
+	for _, term := range terms {
+		best := 0.0
+
+		for field, text := range fields {
+			if score := matchTerm(term, text); score > 0 {
+				matchedFields[field] = true
+				best = maxFloat(best, score*searchFieldWeights[field])
+			}
+		}
+
+		for i, item := range box.Items {
+			if score := matchTerm(term, item.Name); score > 0 {
+				matchedFields["items"] = true
+				matchedItems[i] = true
+				best = maxFloat(best, score*searchFieldWeights["items"])
+			}
+		}
+
+		if best == 0 {
+			ok = false
+		}
+		rank += best
+	}
+
+	result = &models.BoxSearchResult{
+		Box:           box,
+		Rank:          rank / float64(len(terms)),
+		MatchedFields: make([]string, 0),
+	}
+
+	for _, field := range searchFieldOrder {
+		if matchedFields[field] {
+			result.MatchedFields = append(result.MatchedFields, field)
+		}
+	}
+
+	for i, item := range box.Items {
+		if matchedItems[i] {
+			result.MatchedItems = append(result.MatchedItems, item)
+		}
+	}
+
+	return result, ok
+}
+
+// searchBoxes ranks boxes carrying the filter's tags in Go; used by stores
+// without native full-text search
+func searchBoxes(boxes []*models.Box, terms []string, limit int, tags models.TagFilter) []*models.BoxSearchResult {
+	results := make([]*models.BoxSearchResult, 0)
+	for _, box := range boxes {
+		if !boxHasTags(box, tags) {
+			continue
+		}
+		if result, ok := matchBox(box, terms); ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Box.CreatedAt.After(results[j].Box.CreatedAt)
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+func newTestTag(t *testing.T, stores *Stores, workspaceID, name string) *models.Tag {
+	t.Helper()
+
+	now := time.Now()
+	tag := &models.Tag{ID: uuid.New().String(), WorkspaceID: workspaceID, Name: name, Color: "#9ca3af", CreatedAt: now, UpdatedAt: now}
+	if err := stores.Tags.CreateTag(tag); err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+	return tag
+}
+
+func TestCreateStoresTags(t *testing.T) {
+	for name, stores := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			box := seedBoxes(t, stores, "user-1", 1)[0]
+			tag := newTestTag(t, stores, box.WorkspaceID, "Fragile")
+
+			tagged := *box
+			tagged.ID, tagged.ShortCode = uuid.New().String(), "TAG-GED"
+			tagged.Tags = []models.BoxTag{{ID: tag.ID}}
+			if err := stores.Boxes.Create(&tagged); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			stored, err := stores.Boxes.GetByID(tagged.ID)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if len(stored.Tags) != 1 || stored.Tags[0].Name != "Fragile" {
+				t.Errorf("stored tags = %v, want Fragile", stored.Tags)
+			}
+		})
+	}
+}
+
+func TestUpdateIsAllOrNothing(t *testing.T) {
+	for name, stores := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			box := seedBoxes(t, stores, "user-1", 1)[0]
+			tag := newTestTag(t, stores, box.WorkspaceID, "Fragile")
+			items := []models.Item{{ID: uuid.New().String(), BoxID: box.ID, Name: "scarf", Quantity: 1, CreatedAt: time.Now()}}
+
+			// A tag that doesn't exist must leave the name and items alone too
+			renamed := *box
+			renamed.Name = "Renamed"
+			if err := stores.Boxes.Update(&renamed, []string{tag.ID, uuid.New().String()}, items); err == nil {
+				t.Fatal("Update with a missing tag succeeded")
+			}
+
+			stored, err := stores.Boxes.GetByID(box.ID)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if stored.Name != box.Name || len(stored.Tags) != 0 || len(stored.Items) != 0 {
+				t.Errorf("failed update left name %q, tags %v, items %v", stored.Name, stored.Tags, stored.Items)
+			}
+
+			if err := stores.Boxes.Update(&renamed, []string{tag.ID}, items); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+
+			// nil tags and items keep what the box has
+			renamed.Name = "Renamed again"
+			if err := stores.Boxes.Update(&renamed, nil, nil); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+
+			stored, err = stores.Boxes.GetByID(box.ID)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if stored.Name != "Renamed again" || len(stored.Tags) != 1 || len(stored.Items) != 1 || stored.Items[0].Name != "scarf" {
+				t.Errorf("updated box has name %q, tags %v, items %v", stored.Name, stored.Tags, stored.Items)
+			}
+		})
+	}
+}
+
+func TestCreateBoxesIsAllOrNothing(t *testing.T) {
+	for name, stores := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			box := seedBoxes(t, stores, "user-1", 1)[0]
+
+			now := time.Now()
+			room := &models.Location{ID: uuid.New().String(), UserID: "user-1", WorkspaceID: box.WorkspaceID, Name: "Loft", CreatedAt: now, UpdatedAt: now}
+			fresh := *box
+			fresh.ID, fresh.ShortCode, fresh.LocationID, fresh.Room = uuid.New().String(), "NEW-BOX", room.ID, "Loft"
+
+			// The seeded box is already stored, so the batch must fail
+			// without leaving the room behind
+			if err := stores.Boxes.CreateBoxes([]*models.Box{&fresh, box}, []*models.Location{room}); err == nil {
+				t.Fatal("CreateBoxes with a duplicate box succeeded")
+			}
+			if _, err := stores.Locations.GetLocationByID(room.ID); err == nil {
+				t.Error("failed batch stored its room")
+			}
+			if _, err := stores.Boxes.GetByID(fresh.ID); err == nil {
+				t.Error("failed batch stored a box")
+			}
+
+			if err := stores.Boxes.CreateBoxes([]*models.Box{&fresh}, []*models.Location{room}); err != nil {
+				t.Fatalf("CreateBoxes: %v", err)
+			}
+			stored, err := stores.Boxes.GetByID(fresh.ID)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if stored.LocationID != room.ID {
+				t.Errorf("stored box is in location %q, want %s", stored.LocationID, room.ID)
+			}
+			if location, err := stores.Locations.GetLocationByID(room.ID); err != nil || location.Name != "Loft" {
+				t.Errorf("GetLocationByID = %v, %v; want Loft", location, err)
+			}
+		})
+	}
+}
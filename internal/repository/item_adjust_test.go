@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+func TestAdjustItemQuantityStaysInRange(t *testing.T) {
+	for name, stores := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			box := seedBoxes(t, stores, "user-1", 1)[0]
+			item := &models.Item{ID: uuid.New().String(), BoxID: box.ID, Name: "screws", Quantity: 5, CreatedAt: time.Now()}
+			if err := stores.Items.CreateItem(item); err != nil {
+				t.Fatalf("CreateItem: %v", err)
+			}
+
+			tests := []struct {
+				delta int
+				err   string
+			}{
+				{-6, "quantity cannot be negative"},
+				{models.MaxItemQuantity, "quantity too large"},
+				{models.MaxItemQuantity - 5, ""},
+				{1, "quantity too large"},
+			}
+			for _, tt := range tests {
+				_, err := stores.Items.AdjustItemQuantity(item.ID, tt.delta)
+				if got := errorText(err); got != tt.err {
+					t.Errorf("AdjustItemQuantity(%d) error = %q, want %q", tt.delta, got, tt.err)
+				}
+			}
+
+			stored, err := stores.Items.GetItemByID(item.ID)
+			if err != nil {
+				t.Fatalf("GetItemByID: %v", err)
+			}
+			if stored.Quantity != models.MaxItemQuantity {
+				t.Errorf("quantity = %d, want %d", stored.Quantity, models.MaxItemQuantity)
+			}
+
+			if _, err := stores.Items.AdjustItemQuantity(uuid.New().String(), 1); errorText(err) != "item not found" {
+				t.Errorf("missing item error = %v, want item not found", err)
+			}
+		})
+	}
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
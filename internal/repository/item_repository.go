@@ -0,0 +1,371 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+type ItemRepository struct {
+	db *database.DB
+}
+
+func NewItemRepository(db *database.DB) *ItemRepository {
+	return &ItemRepository{db: db}
+}
+
+// itemColumns lists the columns scanItem expects, in order
+const itemColumns = `id, box_id, name, quantity, unit, notes, created_at`
+
+func scanItem(row rowScanner) (*models.Item, error) {
+	item := &models.Item{}
+	var unit, notes sql.NullString
+
+	err := row.Scan(
+		&item.ID,
+		&item.BoxID,
+		&item.Name,
+		&item.Quantity,
+		&unit,
+		&notes,
+		&item.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle NULL unit and notes
+	item.Unit = unit.String
+	item.Notes = notes.String
+
+	return item, nil
+}
+
+// insertItemQuery appends an item after the last one already in its box
+const insertItemQuery = `
+	INSERT INTO items (id, box_id, name, quantity, unit, notes, position, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, (SELECT COALESCE(MAX(position), 0) + 1 FROM items WHERE box_id = $2), $7)
+`
+
+// insertItems adds items to a box in order inside an open transaction
+func insertItems(tx *database.Tx, boxID string, items []models.Item) error {
+	for _, item := range items {
+		_, err := tx.Exec(insertItemQuery, item.ID, boxID, item.Name, item.Quantity, item.Unit, item.Notes, item.CreatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to create item: %w", err)
+		}
+	}
+	return nil
+}
+
+// touchBox bumps a box's updated_at after its contents change
+func touchBox(tx *database.Tx, boxID string) error {
+	_, err := tx.Exec(`UPDATE boxes SET updated_at = $2 WHERE id = $1`, boxID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update box timestamp: %w", err)
+	}
+	return nil
+}
+
+// attachItems loads the items of all the given boxes with a single query
+func attachItems(db *database.DB, boxes []*models.Box) error {
+	if len(boxes) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.Box, len(boxes))
+	placeholders := make([]string, len(boxes))
+	args := make([]interface{}, len(boxes))
+	for i, box := range boxes {
+		box.Items = make([]models.Item, 0)
+		byID[box.ID] = box
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = box.ID
+	}
+
+	query := `
+		SELECT ` + itemColumns + `
+		FROM items
+		WHERE box_id IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY position, created_at
+	`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get box items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan item: %w", err)
+		}
+		if box, ok := byID[item.BoxID]; ok {
+			box.Items = append(box.Items, *item)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
+
+// CreateItem appends an item to the end of its box
+func (r *ItemRepository) CreateItem(item *models.Item) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertItems(tx, item.BoxID, []models.Item{*item}); err != nil {
+		return err
+	}
+
+	if err := touchBox(tx, item.BoxID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit item: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ItemRepository) GetItemByID(id string) (*models.Item, error) {
+	query := `SELECT ` + itemColumns + ` FROM items WHERE id = $1`
+
+	item, err := scanItem(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("item not found")
+		}
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	return item, nil
+}
+
+// UpdateItem saves an item's name, quantity, unit and notes. When its box changes
+// the item, and its photos, move to the end of the new box.
+func (r *ItemRepository) UpdateItem(item *models.Item) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previousBoxID string
+	err = tx.QueryRow(`SELECT box_id FROM items WHERE id = $1`, item.ID).Scan(&previousBoxID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("item not found")
+		}
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+
+	if previousBoxID == item.BoxID {
+		_, err = tx.Exec(
+			`UPDATE items SET name = $2, quantity = $3, unit = $4, notes = $5 WHERE id = $1`,
+			item.ID, item.Name, item.Quantity, item.Unit, item.Notes,
+		)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE items
+			SET name = $2, quantity = $3, unit = $4, notes = $5, box_id = $6,
+				position = (SELECT COALESCE(MAX(position), 0) + 1 FROM items WHERE box_id = $6)
+			WHERE id = $1`,
+			item.ID, item.Name, item.Quantity, item.Unit, item.Notes, item.BoxID,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+
+	if err := touchBox(tx, item.BoxID); err != nil {
+		return err
+	}
+
+	if previousBoxID != item.BoxID {
+		if err := movePhotos(tx, item.ID, item.BoxID); err != nil {
+			return err
+		}
+
+		if err := touchBox(tx, previousBoxID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit item: %w", err)
+	}
+
+	return nil
+}
+
+// MoveItems moves items from one box to the end of another in a single
+// transaction. Nothing moves unless every item is currently in fromBoxID.
+func (r *ItemRepository) MoveItems(fromBoxID, toBoxID string, itemIDs []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range itemIDs {
+		result, err := tx.Exec(`
+			UPDATE items
+			SET box_id = $3,
+				position = (SELECT COALESCE(MAX(position), 0) + 1 FROM items WHERE box_id = $3)
+			WHERE id = $1 AND box_id = $2`,
+			id, fromBoxID, toBoxID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to move item: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("item not found")
+		}
+
+		if err := movePhotos(tx, id, toBoxID); err != nil {
+			return err
+		}
+	}
+
+	if err := touchBox(tx, fromBoxID); err != nil {
+		return err
+	}
+
+	if err := touchBox(tx, toBoxID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit item move: %w", err)
+	}
+
+	return nil
+}
+
+// AdjustItemQuantity adds delta to an item's quantity in a single statement, so
+// concurrent increments and decrements are never lost. The quantity cannot
+// drop below zero or rise above MaxItemQuantity.
+func (r *ItemRepository) AdjustItemQuantity(id string, delta int) (*models.Item, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE items
+		SET quantity = quantity + $2
+		WHERE id = $1 AND quantity + $2 BETWEEN 0 AND $3
+		RETURNING ` + itemColumns
+
+	item, err := scanItem(tx.QueryRow(query, id, delta, models.MaxItemQuantity))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to adjust item quantity: %w", err)
+		}
+
+		// Tell a missing item apart from one that would go out of range
+		var quantity int
+		if err := tx.QueryRow(`SELECT quantity FROM items WHERE id = $1`, id).Scan(&quantity); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("item not found")
+			}
+			return nil, fmt.Errorf("failed to get item: %w", err)
+		}
+		if quantity+delta > models.MaxItemQuantity {
+			return nil, fmt.Errorf("quantity too large")
+		}
+		return nil, fmt.Errorf("quantity cannot be negative")
+	}
+
+	if err := touchBox(tx, item.BoxID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit item: %w", err)
+	}
+
+	return item, nil
+}
+
+// GetItemTotals sums item quantities across the boxes in the user's
+// workspaces. Items are grouped by case-insensitive name and unit, largest
+// totals first.
+func (r *ItemRepository) GetItemTotals(userID string) ([]models.ItemTotal, error) {
+	query := `
+		SELECT MIN(i.name), COALESCE(i.unit, ''), SUM(i.quantity), COUNT(DISTINCT i.box_id)
+		FROM items i
+		JOIN boxes b ON b.id = i.box_id
+		WHERE ` + memberWorkspaces("b.workspace_id", "$1") + `
+		GROUP BY LOWER(i.name), COALESCE(i.unit, '')
+		ORDER BY SUM(i.quantity) DESC, LOWER(i.name)
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make([]models.ItemTotal, 0)
+	for rows.Next() {
+		var total models.ItemTotal
+		if err := rows.Scan(&total.Name, &total.Unit, &total.Quantity, &total.Boxes); err != nil {
+			return nil, fmt.Errorf("failed to scan item total: %w", err)
+		}
+		totals = append(totals, total)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return totals, nil
+}
+
+func (r *ItemRepository) DeleteItem(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var boxID string
+	err = tx.QueryRow(`DELETE FROM items WHERE id = $1 RETURNING box_id`, id).Scan(&boxID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("item not found")
+		}
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+
+	if err := touchBox(tx, boxID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit item deletion: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,358 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+type LocationRepository struct {
+	db *database.DB
+}
+
+func NewLocationRepository(db *database.DB) *LocationRepository {
+	return &LocationRepository{db: db}
+}
+
+// locationColumns lists the columns scanLocation expects, in order
+const locationColumns = `id, user_id, workspace_id, parent_id, name, created_at, updated_at`
+
+func scanLocation(row rowScanner) (*models.Location, error) {
+	location := &models.Location{}
+	var parentID sql.NullString
+
+	err := row.Scan(
+		&location.ID,
+		&location.UserID,
+		&location.WorkspaceID,
+		&parentID,
+		&location.Name,
+		&location.CreatedAt,
+		&location.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle NULL parent for top-level locations
+	location.ParentID = parentID.String
+
+	return location, nil
+}
+
+// locationPaths builds the breadcrumb of every location from a user's full set
+// of locations. A broken parent chain ends the breadcrumb where it breaks.
+func locationPaths(locations []*models.Location) map[string]models.LocationPath {
+	byID := make(map[string]*models.Location, len(locations))
+	for _, location := range locations {
+		byID[location.ID] = location
+	}
+
+	paths := make(map[string]models.LocationPath, len(locations))
+	for _, location := range locations {
+		var path models.LocationPath
+		seen := make(map[string]bool)
+		for current := location; current != nil && !seen[current.ID]; current = byID[current.ParentID] {
+			seen[current.ID] = true
+			path = append(models.LocationPath{{ID: current.ID, Name: current.Name}}, path...)
+		}
+		paths[location.ID] = path
+	}
+
+	return paths
+}
+
+// withLocationPaths fills in Path on each location and sorts them by it
+func withLocationPaths(locations []*models.Location) []*models.Location {
+	paths := locationPaths(locations)
+	for _, location := range locations {
+		location.Path = paths[location.ID]
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		return strings.ToLower(locations[i].Path.String()) < strings.ToLower(locations[j].Path.String())
+	})
+
+	return locations
+}
+
+// locationSubtree returns the ID of rootID and of every location below it
+func locationSubtree(locations []*models.Location, rootID string) map[string]bool {
+	children := make(map[string][]string)
+	for _, location := range locations {
+		children[location.ParentID] = append(children[location.ParentID], location.ID)
+	}
+
+	subtree := map[string]bool{rootID: true}
+	queue := []string{rootID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, child := range children[id] {
+			if !subtree[child] {
+				subtree[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	return subtree
+}
+
+// queryLocations runs a locations query and scans every row
+func queryLocations(query func(string, ...interface{}) (*sql.Rows, error), sqlQuery string, args ...interface{}) ([]*models.Location, error) {
+	rows, err := query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := make([]*models.Location, 0)
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan location: %w", err)
+		}
+		locations = append(locations, location)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return locations, nil
+}
+
+// attachLocations fills in the location breadcrumb of each box
+func attachLocations(db *database.DB, boxes []*models.Box) error {
+	workspaceIDs := make(map[string]bool)
+	for _, box := range boxes {
+		box.LocationPath = models.LocationPath{}
+		if box.LocationID != "" {
+			workspaceIDs[box.WorkspaceID] = true
+		}
+	}
+
+	if len(workspaceIDs) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(workspaceIDs))
+	args := make([]interface{}, 0, len(workspaceIDs))
+	for workspaceID := range workspaceIDs {
+		args = append(args, workspaceID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	locations, err := queryLocations(db.Query,
+		`SELECT `+locationColumns+` FROM locations WHERE workspace_id IN (`+strings.Join(placeholders, ", ")+`)`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+
+	paths := locationPaths(locations)
+	for _, box := range boxes {
+		if path, ok := paths[box.LocationID]; ok {
+			box.LocationPath = path
+		}
+	}
+
+	return nil
+}
+
+func (r *LocationRepository) CreateLocation(location *models.Location) error {
+	return insertLocation(r.db.Exec, location)
+}
+
+// insertLocation adds a location row with exec, which runs on the database or
+// inside a transaction
+func insertLocation(exec func(string, ...interface{}) (sql.Result, error), location *models.Location) error {
+	query := `
+		INSERT INTO locations (id, user_id, workspace_id, parent_id, name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := exec(
+		query,
+		location.ID,
+		location.UserID,
+		location.WorkspaceID,
+		nullableString(location.ParentID),
+		location.Name,
+		location.CreatedAt.UTC(),
+		location.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create location: %w", err)
+	}
+
+	return nil
+}
+
+// GetLocationByID returns a location with its breadcrumb
+func (r *LocationRepository) GetLocationByID(id string) (*models.Location, error) {
+	location, err := scanLocation(r.db.QueryRow(`SELECT `+locationColumns+` FROM locations WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("location not found")
+		}
+		return nil, fmt.Errorf("failed to get location: %w", err)
+	}
+
+	locations, err := r.getLocationsByWorkspaceID(location.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range locations {
+		if candidate.ID == location.ID {
+			return candidate, nil
+		}
+	}
+
+	return nil, fmt.Errorf("location not found")
+}
+
+// GetLocationsByUserID returns the locations of every workspace the user
+// belongs to with their breadcrumbs, ordered by breadcrumb
+func (r *LocationRepository) GetLocationsByUserID(userID string) ([]*models.Location, error) {
+	locations, err := queryLocations(r.db.Query,
+		`SELECT `+locationColumns+` FROM locations WHERE `+memberWorkspaces("workspace_id", "$1"),
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return withLocationPaths(locations), nil
+}
+
+// getLocationsByWorkspaceID returns a workspace's locations with their breadcrumbs
+func (r *LocationRepository) getLocationsByWorkspaceID(workspaceID string) ([]*models.Location, error) {
+	locations, err := queryLocations(r.db.Query, `SELECT `+locationColumns+` FROM locations WHERE workspace_id = $1`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return withLocationPaths(locations), nil
+}
+
+// UpdateLocation renames or moves a location. The room breadcrumb cached on
+// the boxes below it is refreshed in the same transaction.
+func (r *LocationRepository) UpdateLocation(location *models.Location) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	location.UpdatedAt = time.Now()
+
+	result, err := tx.Exec(
+		`UPDATE locations SET name = $2, parent_id = $3, updated_at = $4 WHERE id = $1 AND workspace_id = $5`,
+		location.ID,
+		location.Name,
+		nullableString(location.ParentID),
+		location.UpdatedAt.UTC(),
+		location.WorkspaceID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update location: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("location not found")
+	}
+
+	if err := refreshBoxRooms(tx, location.WorkspaceID, location.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit location: %w", err)
+	}
+
+	return nil
+}
+
+// refreshBoxRooms rewrites the room breadcrumb of every box at or below rootID
+func refreshBoxRooms(tx *database.Tx, workspaceID, rootID string) error {
+	locations, err := queryLocations(tx.Query, `SELECT `+locationColumns+` FROM locations WHERE workspace_id = $1`, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	paths := locationPaths(locations)
+	for id := range locationSubtree(locations, rootID) {
+		_, err := tx.Exec(`UPDATE boxes SET room = $2 WHERE location_id = $1`, id, paths[id].String())
+		if err != nil {
+			return fmt.Errorf("failed to update box rooms: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteLocation removes a location that has no child locations. Boxes in it
+// are left without a location.
+func (r *LocationRepository) DeleteLocation(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var children int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM locations WHERE parent_id = $1`, id).Scan(&children); err != nil {
+		return fmt.Errorf("failed to count child locations: %w", err)
+	}
+
+	if children > 0 {
+		return fmt.Errorf("location has child locations")
+	}
+
+	_, err = tx.Exec(`UPDATE boxes SET location_id = NULL, room = NULL, updated_at = $2 WHERE location_id = $1`, id, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to detach boxes: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM locations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete location: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("location not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit location deletion: %w", err)
+	}
+
+	return nil
+}
+
+// nullableString stores empty strings as NULL
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,481 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// MemoryBoxRepository keeps boxes in memory. It is safe for concurrent use and
+// returns copies, so callers can't mutate stored boxes without calling Update.
+type MemoryBoxRepository struct {
+	*memoryData
+}
+
+// Create adds the box with its items and tags
+func (r *MemoryBoxRepository) Create(box *models.Box) error {
+	return r.CreateBoxes([]*models.Box{box}, nil)
+}
+
+// CreateBoxes adds the boxes with their tags and the new locations in rooms,
+// checking them all first so that either all of them are created or none is
+func (r *MemoryBoxRepository) CreateBoxes(boxes []*models.Box, rooms []*models.Location) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	newRooms := make(map[string]bool, len(rooms))
+	for _, room := range rooms {
+		if _, exists := r.locations[room.ID]; exists || newRooms[room.ID] {
+			return fmt.Errorf("failed to create location: duplicate id %s", room.ID)
+		}
+		newRooms[room.ID] = true
+	}
+
+	shortCodes := make(map[string]bool, len(r.boxes)+len(boxes))
+	for _, other := range r.boxes {
+		shortCodes[other.ShortCode] = true
+	}
+
+	added := make(map[string]*models.Box, len(boxes))
+	for _, box := range boxes {
+		if _, exists := r.boxes[box.ID]; exists || added[box.ID] != nil {
+			return fmt.Errorf("failed to create box: duplicate id %s", box.ID)
+		}
+		if box.ShortCode != "" && shortCodes[box.ShortCode] {
+			return fmt.Errorf("failed to create box: duplicate short code %s", box.ShortCode)
+		}
+		shortCodes[box.ShortCode] = true
+
+		tagIDs := make([]string, len(box.Tags))
+		for i, tag := range box.Tags {
+			tagIDs[i] = tag.ID
+		}
+
+		stored := cloneBox(box)
+		tags, err := r.boxTags(tagIDs)
+		if err != nil {
+			return err
+		}
+		stored.Tags = tags
+		added[box.ID] = stored
+	}
+
+	for _, room := range rooms {
+		stored := *room
+		stored.Path = nil
+		r.locations[room.ID] = &stored
+	}
+	for id, box := range added {
+		r.boxes[id] = box
+	}
+	return nil
+}
+
+func (r *MemoryBoxRepository) GetByID(id string) (*models.Box, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	box, ok := r.boxes[id]
+	if !ok {
+		return nil, fmt.Errorf("box not found")
+	}
+
+	return cloneBox(box), nil
+}
+
+// GetByShortCode finds a box by its short code, as in "K7F-3QX"
+func (r *MemoryBoxRepository) GetByShortCode(code string) (*models.Box, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, box := range r.boxes {
+		if box.ShortCode != "" && box.ShortCode == code {
+			return cloneBox(box), nil
+		}
+	}
+
+	return nil, fmt.Errorf("box not found")
+}
+
+// GetBoxIDsWithoutShortCode returns the boxes created before short codes existed
+func (r *MemoryBoxRepository) GetBoxIDsWithoutShortCode() ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0)
+	for _, box := range r.boxes {
+		if box.ShortCode == "" {
+			ids = append(ids, box.ID)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// SetShortCode gives a box without one its short code
+func (r *MemoryBoxRepository) SetShortCode(boxID, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box, ok := r.boxes[boxID]
+	if !ok || box.ShortCode != "" {
+		return fmt.Errorf("box not found")
+	}
+
+	for _, other := range r.boxes {
+		if other.ShortCode == code {
+			return fmt.Errorf("failed to set short code: duplicate code %s", code)
+		}
+	}
+
+	box.ShortCode = code
+	return nil
+}
+
+// GetShortCodes returns the short code of every box that has one, by box ID
+func (r *MemoryBoxRepository) GetShortCodes() (map[string]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	codes := make(map[string]string)
+	for _, box := range r.boxes {
+		if box.ShortCode != "" {
+			codes[box.ID] = box.ShortCode
+		}
+	}
+
+	return codes, nil
+}
+
+// SetQRCode replaces the stored QR code image and the URL it encodes
+func (r *MemoryBoxRepository) SetQRCode(boxID, qrCode, qrCodeURL string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box, ok := r.boxes[boxID]
+	if !ok {
+		return fmt.Errorf("box not found")
+	}
+
+	box.QRCode = qrCode
+	box.QRCodeURL = qrCodeURL
+	return nil
+}
+
+// GetByUserID returns every box in the workspaces the user belongs to, newest first
+func (r *MemoryBoxRepository) GetByUserID(userID string) ([]*models.Box, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	member := r.userWorkspaces(userID)
+	var boxes []*models.Box
+	for _, box := range r.boxes {
+		if member[box.WorkspaceID] {
+			boxes = append(boxes, cloneBox(box))
+		}
+	}
+
+	// Match the PostgreSQL ordering: newest first
+	sort.Slice(boxes, func(i, j int) bool {
+		return boxes[i].CreatedAt.After(boxes[j].CreatedAt)
+	})
+
+	return boxes, nil
+}
+
+// ListByUserID returns one page of a user's boxes, following the same ordering
+// and cursor rules as the SQL repository
+func (r *MemoryBoxRepository) ListByUserID(userID string, options models.BoxListOptions) (*models.BoxPage, error) {
+	options, cursor, err := prepareBoxList(options)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	member := r.userWorkspaces(userID)
+	var subtree map[string]bool
+	if options.LocationID != "" {
+		subtree = locationSubtree(r.workspaceLocations(member), options.LocationID)
+		if location, ok := r.locations[options.LocationID]; !ok || !member[location.WorkspaceID] {
+			subtree = map[string]bool{}
+		}
+	}
+
+	var boxes []*models.Box
+	for _, box := range r.boxes {
+		if !member[box.WorkspaceID] {
+			continue
+		}
+		if options.WorkspaceID != "" && box.WorkspaceID != options.WorkspaceID {
+			continue
+		}
+		if options.Room != "" && !strings.EqualFold(strings.TrimSpace(box.Room), options.Room) {
+			continue
+		}
+		if subtree != nil && !subtree[box.LocationID] {
+			continue
+		}
+		if !boxHasTags(box, options.Tags) {
+			continue
+		}
+		if options.UpdatedSince != nil && box.UpdatedAt.Before(*options.UpdatedSince) {
+			continue
+		}
+		boxes = append(boxes, cloneBox(box))
+	}
+	r.mu.RUnlock()
+
+	descending := options.Order == models.SortDescending
+	compare := func(box *models.Box, value, id string) int {
+		result := compareSortValues(boxSortValue(box, options.Sort), value, options.Sort)
+		if result == 0 {
+			result = strings.Compare(box.ID, id)
+		}
+		if descending {
+			result = -result
+		}
+		return result
+	}
+
+	sort.Slice(boxes, func(i, j int) bool {
+		return compare(boxes[i], boxSortValue(boxes[j], options.Sort), boxes[j].ID) < 0
+	})
+
+	page := &models.BoxPage{Total: len(boxes)}
+
+	if cursor != nil {
+		start := sort.Search(len(boxes), func(i int) bool {
+			return compare(boxes[i], cursor.Value, cursor.ID) > 0
+		})
+		boxes = boxes[start:]
+	}
+
+	if options.Limit > 0 && len(boxes) > options.Limit {
+		boxes = boxes[:options.Limit]
+		last := boxes[len(boxes)-1]
+		page.NextCursor = encodeBoxCursor(boxCursor{
+			Sort:  options.Sort,
+			Order: options.Order,
+			Value: boxSortValue(last, options.Sort),
+			ID:    last.ID,
+		})
+	}
+
+	if boxes == nil {
+		boxes = make([]*models.Box, 0)
+	}
+
+	page.Boxes = boxes
+	page.Count = len(boxes)
+	return page, nil
+}
+
+// compareSortValues orders two boxSortValue results, comparing timestamps as
+// instants rather than strings
+func compareSortValues(a, b, sort string) int {
+	if isTimeSort(sort) {
+		timeA, _ := time.Parse(time.RFC3339Nano, a)
+		timeB, _ := time.Parse(time.RFC3339Nano, b)
+		return timeA.Compare(timeB)
+	}
+	return strings.Compare(a, b)
+}
+
+// Search ranks the user's boxes in memory with the same scoring as SQLite
+func (r *MemoryBoxRepository) Search(userID, query string, limit int, tags models.TagFilter) ([]*models.BoxSearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty search query")
+	}
+
+	tags, err := prepareTagFilter(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	boxes, err := r.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return searchBoxes(boxes, terms, limit, tags), nil
+}
+
+// Update saves the box's own fields. A non-nil tagIDs or items replaces the
+// box's tags or contents as well.
+func (r *MemoryBoxRepository) Update(box *models.Box, tagIDs []string, items []models.Item) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.boxes[box.ID]
+	if !ok || existing.WorkspaceID != box.WorkspaceID {
+		return fmt.Errorf("box not found or unauthorized")
+	}
+
+	// Look the tags up before anything changes, so a missing one changes nothing
+	var tags []models.BoxTag
+	if tagIDs != nil {
+		var err error
+		if tags, err = r.boxTags(tagIDs); err != nil {
+			return err
+		}
+	}
+
+	box.UpdatedAt = time.Now()
+
+	// Only the mutable columns change, as with the SQL UPDATE
+	updated := cloneBox(existing)
+	updated.Name = box.Name
+	updated.Description = box.Description
+	updated.Room = box.Room
+	updated.LocationID = box.LocationID
+	updated.LocationPath = append(models.LocationPath{}, box.LocationPath...)
+	updated.ParentBoxID = box.ParentBoxID
+	updated.Visibility = box.Visibility
+	updated.PINHash = box.PINHash
+	updated.UpdatedAt = box.UpdatedAt
+
+	if tagIDs != nil {
+		updated.Tags = tags
+	}
+
+	if items != nil {
+		kept := make(map[string]bool, len(items))
+		updated.Items = make([]models.Item, 0, len(items))
+		for _, item := range items {
+			item.BoxID = box.ID
+			updated.Items = append(updated.Items, item)
+			kept[item.ID] = true
+		}
+
+		// Photos of items that are gone stay on the box
+		for i := range updated.Photos {
+			if !kept[updated.Photos[i].ItemID] {
+				updated.Photos[i].ItemID = ""
+			}
+		}
+	}
+
+	r.boxes[box.ID] = updated
+
+	return nil
+}
+
+// Delete removes a box from one of the user's workspaces. Boxes that were
+// inside it move to the top level.
+func (r *MemoryBoxRepository) Delete(id, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box, ok := r.boxes[id]
+	if !ok || !r.userWorkspaces(userID)[box.WorkspaceID] {
+		return fmt.Errorf("box not found or unauthorized")
+	}
+
+	delete(r.boxes, id)
+
+	// Boxes that were inside it move to the top level
+	now := time.Now()
+	for _, inner := range r.boxes {
+		if inner.ParentBoxID == id {
+			inner.ParentBoxID = ""
+			inner.UpdatedAt = now
+		}
+	}
+
+	return nil
+}
+
+// GetUserBoxCount counts the boxes in the workspaces the user belongs to
+func (r *MemoryBoxRepository) GetUserBoxCount(userID string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	member := r.userWorkspaces(userID)
+	count := 0
+	for _, box := range r.boxes {
+		if member[box.WorkspaceID] {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// GetContainmentChains returns, for each of the given boxes, the boxes it sits
+// inside, outermost first. Boxes at the top level are left out of the map.
+func (r *MemoryBoxRepository) GetContainmentChains(boxIDs []string) (map[string][]models.BoxCrumb, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	chains := make(map[string][]models.BoxCrumb)
+	for _, id := range boxIDs {
+		box, ok := r.boxes[id]
+		if !ok {
+			continue
+		}
+
+		var chain []models.BoxCrumb
+		for depth := 0; depth < maxBoxNesting; depth++ {
+			outer, ok := r.boxes[box.ParentBoxID]
+			if !ok {
+				break
+			}
+			chain = append([]models.BoxCrumb{{ID: outer.ID, Name: outer.Name}}, chain...)
+			box = outer
+		}
+
+		if len(chain) > 0 {
+			chains[id] = chain
+		}
+	}
+
+	return chains, nil
+}
+
+// GetDescendants returns every box nested inside boxID at any depth, with
+// their items
+func (r *MemoryBoxRepository) GetDescendants(boxID string) ([]*models.Box, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	children := make(map[string][]*models.Box)
+	for _, box := range r.boxes {
+		if box.ParentBoxID != "" {
+			children[box.ParentBoxID] = append(children[box.ParentBoxID], box)
+		}
+	}
+
+	boxes := make([]*models.Box, 0)
+	seen := map[string]bool{boxID: true}
+	level := []string{boxID}
+	for depth := 0; depth < maxBoxNesting && len(level) > 0; depth++ {
+		var next []string
+		for _, id := range level {
+			for _, child := range children[id] {
+				if seen[child.ID] {
+					continue
+				}
+				seen[child.ID] = true
+				boxes = append(boxes, cloneBox(child))
+				next = append(next, child.ID)
+			}
+		}
+		level = next
+	}
+
+	// Match the SQL ordering
+	sort.Slice(boxes, func(i, j int) bool {
+		nameI, nameJ := strings.ToLower(boxes[i].Name), strings.ToLower(boxes[j].Name)
+		if nameI != nameJ {
+			return nameI < nameJ
+		}
+		return boxes[i].ID < boxes[j].ID
+	})
+
+	return boxes, nil
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// MemoryItemRepository keeps items inside the boxes of the memory store
+type MemoryItemRepository struct {
+	*memoryData
+}
+
+// CreateItem appends an item to the end of its box
+func (r *MemoryItemRepository) CreateItem(item *models.Item) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box, ok := r.boxes[item.BoxID]
+	if !ok {
+		return fmt.Errorf("failed to create item: box %s does not exist", item.BoxID)
+	}
+
+	box.Items = append(box.Items, *item)
+	box.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *MemoryItemRepository) GetItemByID(id string) (*models.Item, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	box, index := r.findItem(id)
+	if box == nil {
+		return nil, fmt.Errorf("item not found")
+	}
+
+	item := box.Items[index]
+	return &item, nil
+}
+
+// UpdateItem saves an item's name, quantity, unit and notes. When its box changes
+// the item, and its photos, move to the end of the new box.
+func (r *MemoryItemRepository) UpdateItem(item *models.Item) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box, index := r.findItem(item.ID)
+	if box == nil {
+		return fmt.Errorf("item not found")
+	}
+
+	updated := box.Items[index]
+	updated.Name = item.Name
+	updated.Quantity = item.Quantity
+	updated.Unit = item.Unit
+	updated.Notes = item.Notes
+	now := time.Now()
+
+	if item.BoxID == box.ID {
+		box.Items[index] = updated
+		box.UpdatedAt = now
+		return nil
+	}
+
+	target, ok := r.boxes[item.BoxID]
+	if !ok {
+		return fmt.Errorf("failed to update item: box %s does not exist", item.BoxID)
+	}
+
+	updated.BoxID = target.ID
+	box.Items = append(box.Items[:index:index], box.Items[index+1:]...)
+	target.Items = append(target.Items, updated)
+	moveItemPhotos(box, target, map[string]bool{updated.ID: true})
+	box.UpdatedAt = now
+	target.UpdatedAt = now
+
+	return nil
+}
+
+// MoveItems moves items from one box to the end of another. Nothing moves
+// unless every item is currently in fromBoxID.
+func (r *MemoryItemRepository) MoveItems(fromBoxID, toBoxID string, itemIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	from, ok := r.boxes[fromBoxID]
+	if !ok {
+		return fmt.Errorf("box not found")
+	}
+
+	to, ok := r.boxes[toBoxID]
+	if !ok {
+		return fmt.Errorf("box not found")
+	}
+
+	moving := make(map[string]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		moving[id] = true
+	}
+
+	// Check every item before changing anything
+	found := 0
+	for _, item := range from.Items {
+		if moving[item.ID] {
+			found++
+		}
+	}
+	if found != len(moving) {
+		return fmt.Errorf("item not found")
+	}
+
+	kept := make([]models.Item, 0, len(from.Items))
+	movedByID := make(map[string]models.Item, len(moving))
+	for _, item := range from.Items {
+		if moving[item.ID] {
+			item.BoxID = to.ID
+			movedByID[item.ID] = item
+			continue
+		}
+		kept = append(kept, item)
+	}
+
+	// Append in request order, as the SQL store does
+	for _, id := range itemIDs {
+		if item, ok := movedByID[id]; ok {
+			to.Items = append(to.Items, item)
+			delete(movedByID, id)
+		}
+	}
+
+	now := time.Now()
+	from.Items = kept
+	moveItemPhotos(from, to, moving)
+	from.UpdatedAt = now
+	to.UpdatedAt = now
+
+	return nil
+}
+
+// AdjustItemQuantity adds delta to an item's quantity. The quantity cannot
+// drop below zero or rise above MaxItemQuantity.
+func (r *MemoryItemRepository) AdjustItemQuantity(id string, delta int) (*models.Item, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box, index := r.findItem(id)
+	if box == nil {
+		return nil, fmt.Errorf("item not found")
+	}
+
+	if box.Items[index].Quantity+delta < 0 {
+		return nil, fmt.Errorf("quantity cannot be negative")
+	}
+	if box.Items[index].Quantity+delta > models.MaxItemQuantity {
+		return nil, fmt.Errorf("quantity too large")
+	}
+
+	box.Items[index].Quantity += delta
+	box.UpdatedAt = time.Now()
+
+	item := box.Items[index]
+	return &item, nil
+}
+
+// GetItemTotals sums item quantities across the boxes in the user's
+// workspaces. Items are grouped by case-insensitive name and unit, largest
+// totals first.
+func (r *MemoryItemRepository) GetItemTotals(userID string) ([]models.ItemTotal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type totalKey struct{ name, unit string }
+	byKey := make(map[totalKey]*models.ItemTotal)
+	boxesByKey := make(map[totalKey]map[string]bool)
+
+	member := r.userWorkspaces(userID)
+	for _, box := range r.boxes {
+		if !member[box.WorkspaceID] {
+			continue
+		}
+		for _, item := range box.Items {
+			key := totalKey{strings.ToLower(item.Name), item.Unit}
+			total, ok := byKey[key]
+			if !ok {
+				total = &models.ItemTotal{Name: item.Name, Unit: item.Unit}
+				byKey[key] = total
+				boxesByKey[key] = make(map[string]bool)
+			}
+			// Match the SQL store, which reports MIN(name) for each group
+			if item.Name < total.Name {
+				total.Name = item.Name
+			}
+			total.Quantity += item.Quantity
+			boxesByKey[key][box.ID] = true
+		}
+	}
+
+	totals := make([]models.ItemTotal, 0, len(byKey))
+	for key, total := range byKey {
+		total.Boxes = len(boxesByKey[key])
+		totals = append(totals, *total)
+	}
+
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Quantity != totals[j].Quantity {
+			return totals[i].Quantity > totals[j].Quantity
+		}
+		return strings.ToLower(totals[i].Name) < strings.ToLower(totals[j].Name)
+	})
+
+	return totals, nil
+}
+
+func (r *MemoryItemRepository) DeleteItem(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box, index := r.findItem(id)
+	if box == nil {
+		return fmt.Errorf("item not found")
+	}
+
+	// Photos of the item stay on the box, as with ON DELETE SET NULL
+	for i := range box.Photos {
+		if box.Photos[i].ItemID == id {
+			box.Photos[i].ItemID = ""
+		}
+	}
+
+	box.Items = append(box.Items[:index:index], box.Items[index+1:]...)
+	box.UpdatedAt = time.Now()
+	return nil
+}
+
+// moveItemPhotos moves the photos of the given items from one box to another;
+// callers must hold the lock
+func moveItemPhotos(from, to *models.Box, itemIDs map[string]bool) {
+	kept := make([]models.Photo, 0, len(from.Photos))
+	for _, photo := range from.Photos {
+		if photo.ItemID != "" && itemIDs[photo.ItemID] {
+			photo.BoxID = to.ID
+			to.Photos = append(to.Photos, photo)
+			continue
+		}
+		kept = append(kept, photo)
+	}
+	from.Photos = kept
+}
+
+// findItem locates an item by ID; callers must hold the lock
+func (r *MemoryItemRepository) findItem(id string) (*models.Box, int) {
+	for _, box := range r.boxes {
+		for i, item := range box.Items {
+			if item.ID == id {
+				return box, i
+			}
+		}
+	}
+	return nil, -1
+}
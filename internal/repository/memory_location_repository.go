@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// MemoryLocationRepository keeps the location trees of the memory store
+type MemoryLocationRepository struct {
+	*memoryData
+}
+
+func (r *MemoryLocationRepository) CreateLocation(location *models.Location) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.locations[location.ID]; exists {
+		return fmt.Errorf("failed to create location: duplicate id %s", location.ID)
+	}
+
+	stored := *location
+	stored.Path = nil
+	r.locations[location.ID] = &stored
+	return nil
+}
+
+// GetLocationByID returns a location with its breadcrumb
+func (r *MemoryLocationRepository) GetLocationByID(id string) (*models.Location, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	location, ok := r.locations[id]
+	if !ok {
+		return nil, fmt.Errorf("location not found")
+	}
+
+	for _, candidate := range withLocationPaths(r.workspaceLocations(map[string]bool{location.WorkspaceID: true})) {
+		if candidate.ID == id {
+			return candidate, nil
+		}
+	}
+
+	return nil, fmt.Errorf("location not found")
+}
+
+// GetLocationsByUserID returns the locations of every workspace the user
+// belongs to with their breadcrumbs, ordered by breadcrumb
+func (r *MemoryLocationRepository) GetLocationsByUserID(userID string) ([]*models.Location, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return withLocationPaths(r.workspaceLocations(r.userWorkspaces(userID))), nil
+}
+
+// UpdateLocation renames or moves a location and refreshes the breadcrumbs of
+// the boxes below it
+func (r *MemoryLocationRepository) UpdateLocation(location *models.Location) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.locations[location.ID]
+	if !ok || existing.WorkspaceID != location.WorkspaceID {
+		return fmt.Errorf("location not found")
+	}
+
+	location.UpdatedAt = time.Now()
+	existing.Name = location.Name
+	existing.ParentID = location.ParentID
+	existing.UpdatedAt = location.UpdatedAt
+
+	locations := r.workspaceLocations(map[string]bool{location.WorkspaceID: true})
+	paths := locationPaths(locations)
+	subtree := locationSubtree(locations, location.ID)
+	for _, box := range r.boxes {
+		if box.WorkspaceID == location.WorkspaceID && subtree[box.LocationID] {
+			box.LocationPath = paths[box.LocationID]
+			box.Room = box.LocationPath.String()
+		}
+	}
+
+	return nil
+}
+
+// DeleteLocation removes a location that has no child locations. Boxes in it
+// are left without a location.
+func (r *MemoryLocationRepository) DeleteLocation(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.locations[id]; !ok {
+		return fmt.Errorf("location not found")
+	}
+
+	for _, location := range r.locations {
+		if location.ParentID == id {
+			return fmt.Errorf("location has child locations")
+		}
+	}
+
+	now := time.Now()
+	for _, box := range r.boxes {
+		if box.LocationID == id {
+			box.LocationID = ""
+			box.LocationPath = models.LocationPath{}
+			box.Room = ""
+			box.UpdatedAt = now
+		}
+	}
+
+	delete(r.locations, id)
+	return nil
+}
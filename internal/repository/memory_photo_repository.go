@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// MemoryPhotoRepository keeps photo records inside the boxes of the memory store
+type MemoryPhotoRepository struct {
+	*memoryData
+}
+
+func (r *MemoryPhotoRepository) CreatePhoto(photo *models.Photo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box, ok := r.boxes[photo.BoxID]
+	if !ok {
+		return fmt.Errorf("failed to create photo: box %s does not exist", photo.BoxID)
+	}
+
+	// Stored photos carry their URLs, as photos read back from SQL do
+	stored := *photo
+	stored.SetURLs()
+	box.Photos = append(box.Photos, stored)
+	return nil
+}
+
+func (r *MemoryPhotoRepository) GetPhotoByID(id string) (*models.Photo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	box, index := r.findPhoto(id)
+	if box == nil {
+		return nil, fmt.Errorf("photo not found")
+	}
+
+	photo := box.Photos[index]
+	return &photo, nil
+}
+
+func (r *MemoryPhotoRepository) DeletePhoto(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box, index := r.findPhoto(id)
+	if box == nil {
+		return fmt.Errorf("photo not found")
+	}
+
+	box.Photos = append(box.Photos[:index:index], box.Photos[index+1:]...)
+	return nil
+}
+
+// findPhoto locates a photo by ID; callers must hold the lock
+func (r *MemoryPhotoRepository) findPhoto(id string) (*models.Box, int) {
+	for _, box := range r.boxes {
+		for i, photo := range box.Photos {
+			if photo.ID == id {
+				return box, i
+			}
+		}
+	}
+	return nil, -1
+}
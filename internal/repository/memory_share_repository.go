@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// MemoryShareRepository keeps share links in memory
+type MemoryShareRepository struct {
+	*memoryData
+}
+
+func (r *MemoryShareRepository) CreateShare(share *models.ShareLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.shares[share.ID]; exists {
+		return fmt.Errorf("failed to create share link: duplicate id %s", share.ID)
+	}
+
+	stored := *share
+	stored.Token, stored.URL, stored.TargetName = "", "", ""
+	r.shares[share.ID] = &stored
+	return nil
+}
+
+func (r *MemoryShareRepository) GetShareByID(id string) (*models.ShareLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	share, ok := r.shares[id]
+	if !ok {
+		return nil, fmt.Errorf("share not found")
+	}
+
+	copied := *share
+	return &copied, nil
+}
+
+// GetSharesByUserID returns the share links of every workspace the user
+// belongs to, newest first, including expired and revoked ones
+func (r *MemoryShareRepository) GetSharesByUserID(userID string) ([]*models.ShareLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	member := r.userWorkspaces(userID)
+	shares := make([]*models.ShareLink, 0)
+	for _, share := range r.shares {
+		if member[share.WorkspaceID] {
+			copied := *share
+			shares = append(shares, &copied)
+		}
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		if !shares[i].CreatedAt.Equal(shares[j].CreatedAt) {
+			return shares[i].CreatedAt.After(shares[j].CreatedAt)
+		}
+		return shares[i].ID < shares[j].ID
+	})
+
+	return shares, nil
+}
+
+// RevokeShare puts a share link on the revocation list. Links already revoked
+// are reported as not found.
+func (r *MemoryShareRepository) RevokeShare(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	share, ok := r.shares[id]
+	if !ok || share.RevokedAt != nil {
+		return fmt.Errorf("share not found")
+	}
+
+	now := time.Now()
+	share.RevokedAt = &now
+	return nil
+}
+
+// GetSharedBoxes returns the boxes a share link covers, by name. Only boxes
+// in the link's workspace count.
+func (r *MemoryShareRepository) GetSharedBoxes(share *models.ShareLink) ([]*models.Box, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var subtree map[string]bool
+	switch share.Scope {
+	case models.ShareScopeBox, models.ShareScopeTag:
+	case models.ShareScopeLocation:
+		subtree = map[string]bool{}
+		if location, ok := r.locations[share.TargetID]; ok && location.WorkspaceID == share.WorkspaceID {
+			subtree = locationSubtree(r.workspaceLocations(map[string]bool{share.WorkspaceID: true}), share.TargetID)
+		}
+	default:
+		return nil, fmt.Errorf("invalid share scope")
+	}
+
+	boxes := make([]*models.Box, 0)
+	for _, box := range r.boxes {
+		if box.WorkspaceID != share.WorkspaceID {
+			continue
+		}
+
+		switch share.Scope {
+		case models.ShareScopeBox:
+			if box.ID != share.TargetID {
+				continue
+			}
+		case models.ShareScopeLocation:
+			if box.LocationID == "" || !subtree[box.LocationID] {
+				continue
+			}
+		case models.ShareScopeTag:
+			if !boxHasTags(box, models.TagFilter{TagIDs: []string{share.TargetID}}) {
+				continue
+			}
+		}
+
+		boxes = append(boxes, cloneBox(box))
+	}
+
+	sort.Slice(boxes, func(i, j int) bool {
+		a, b := strings.ToLower(boxes[i].Name), strings.ToLower(boxes[j].Name)
+		if a != b {
+			return a < b
+		}
+		return boxes[i].ID < boxes[j].ID
+	})
+
+	return boxes, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// memoryData holds everything the in-memory stores keep. The stores share one
+// lock, so a write that touches several of them is atomic, as it is inside a
+// SQL transaction.
+type memoryData struct {
+	mu          sync.RWMutex
+	boxes       map[string]*models.Box
+	locations   map[string]*models.Location
+	tags        map[string]*models.Tag
+	workspaces  map[string]*models.Workspace
+	invitations map[string]*models.WorkspaceInvitation
+	shares      map[string]*models.ShareLink
+}
+
+// NewMemoryStores returns in-memory stores sharing one set of data, for local
+// runs without a database and for tests
+func NewMemoryStores() *Stores {
+	data := &memoryData{
+		boxes:       make(map[string]*models.Box),
+		locations:   make(map[string]*models.Location),
+		tags:        make(map[string]*models.Tag),
+		workspaces:  make(map[string]*models.Workspace),
+		invitations: make(map[string]*models.WorkspaceInvitation),
+		shares:      make(map[string]*models.ShareLink),
+	}
+
+	return &Stores{
+		Boxes:      &MemoryBoxRepository{data},
+		Items:      &MemoryItemRepository{data},
+		Locations:  &MemoryLocationRepository{data},
+		Photos:     &MemoryPhotoRepository{data},
+		Tags:       &MemoryTagRepository{data},
+		Workspaces: &MemoryWorkspaceRepository{data},
+		Shares:     &MemoryShareRepository{data},
+	}
+}
+
+// cloneBox returns a deep copy of box so the store never shares slices with callers
+func cloneBox(box *models.Box) *models.Box {
+	clone := *box
+	clone.Items = append(make([]models.Item, 0, len(box.Items)), box.Items...)
+	clone.Tags = append(make([]models.BoxTag, 0, len(box.Tags)), box.Tags...)
+	clone.Photos = append(make([]models.Photo, 0, len(box.Photos)), box.Photos...)
+	clone.LocationPath = append(models.LocationPath{}, box.LocationPath...)
+	return &clone
+}
+
+// boxTags returns the given tags as shown on a box; callers must hold the lock
+func (r *memoryData) boxTags(tagIDs []string) ([]models.BoxTag, error) {
+	tags := make([]models.BoxTag, 0, len(tagIDs))
+	for _, id := range tagIDs {
+		tag, ok := r.tags[id]
+		if !ok {
+			return nil, fmt.Errorf("failed to tag box: tag %s does not exist", id)
+		}
+		tags = append(tags, boxTag(tag))
+	}
+	sortBoxTags(tags)
+	return tags, nil
+}
+
+// workspaceLocations returns copies of the locations in the given workspaces;
+// callers must hold the lock
+func (r *memoryData) workspaceLocations(workspaceIDs map[string]bool) []*models.Location {
+	locations := make([]*models.Location, 0)
+	for _, location := range r.locations {
+		if workspaceIDs[location.WorkspaceID] {
+			clone := *location
+			locations = append(locations, &clone)
+		}
+	}
+	return locations
+}
+
+// userWorkspaces returns the IDs of the workspaces the user belongs to;
+// callers must hold the lock
+func (r *memoryData) userWorkspaces(userID string) map[string]bool {
+	member := make(map[string]bool)
+	for _, workspace := range r.workspaces {
+		for _, m := range workspace.Members {
+			if m.UserID == userID {
+				member[workspace.ID] = true
+				break
+			}
+		}
+	}
+	return member
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// MemoryTagRepository keeps tags in memory, with a copy of each one's name and
+// colour on the boxes carrying it
+type MemoryTagRepository struct {
+	*memoryData
+}
+
+func (r *MemoryTagRepository) CreateTag(tag *models.Tag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tags {
+		if existing.WorkspaceID == tag.WorkspaceID && strings.EqualFold(existing.Name, tag.Name) {
+			return fmt.Errorf("failed to create tag: duplicate name %s", tag.Name)
+		}
+	}
+
+	stored := *tag
+	stored.BoxCount = 0
+	r.tags[tag.ID] = &stored
+	return nil
+}
+
+func (r *MemoryTagRepository) GetTagByID(id string) (*models.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tag, ok := r.tags[id]
+	if !ok {
+		return nil, fmt.Errorf("tag not found")
+	}
+
+	return r.countedTag(tag), nil
+}
+
+// GetTagsByUserID returns the tags of every workspace the user belongs to
+// ordered by name, each with the number of boxes it is on
+func (r *MemoryTagRepository) GetTagsByUserID(userID string) ([]*models.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	member := r.userWorkspaces(userID)
+	tags := make([]*models.Tag, 0)
+	for _, tag := range r.tags {
+		if member[tag.WorkspaceID] {
+			tags = append(tags, r.countedTag(tag))
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		a, b := strings.ToLower(tags[i].Name), strings.ToLower(tags[j].Name)
+		if a != b {
+			return a < b
+		}
+		return tags[i].ID < tags[j].ID
+	})
+
+	return tags, nil
+}
+
+// UpdateTag saves a tag's name and colour
+func (r *MemoryTagRepository) UpdateTag(tag *models.Tag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.tags[tag.ID]
+	if !ok || existing.WorkspaceID != tag.WorkspaceID {
+		return fmt.Errorf("tag not found")
+	}
+
+	tag.UpdatedAt = time.Now()
+	existing.Name = tag.Name
+	existing.Color = tag.Color
+	existing.UpdatedAt = tag.UpdatedAt
+
+	// Boxes keep a copy of each tag's name and colour
+	for _, box := range r.boxes {
+		for i := range box.Tags {
+			if box.Tags[i].ID == tag.ID {
+				box.Tags[i] = boxTag(existing)
+				sortBoxTags(box.Tags)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeleteTag removes a tag from every box and deletes it
+func (r *MemoryTagRepository) DeleteTag(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tags[id]; !ok {
+		return fmt.Errorf("tag not found")
+	}
+
+	delete(r.tags, id)
+	for _, box := range r.boxes {
+		box.Tags = withoutTag(box.Tags, id)
+	}
+
+	return nil
+}
+
+// MergeTags retags every box carrying one of the source tags with the target
+// and deletes the sources. Nothing changes unless every tag exists.
+func (r *MemoryTagRepository) MergeTags(targetID string, sourceIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	target, ok := r.tags[targetID]
+	if !ok {
+		return fmt.Errorf("tag not found")
+	}
+
+	for _, id := range sourceIDs {
+		if _, ok := r.tags[id]; !ok {
+			return fmt.Errorf("tag not found")
+		}
+	}
+
+	for _, id := range sourceIDs {
+		delete(r.tags, id)
+		for _, box := range r.boxes {
+			had := len(box.Tags)
+			box.Tags = withoutTag(box.Tags, id)
+			if len(box.Tags) < had {
+				box.Tags = append(withoutTag(box.Tags, targetID), boxTag(target))
+				sortBoxTags(box.Tags)
+			}
+		}
+	}
+	target.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// countedTag returns a copy of tag with its box count; callers must hold the lock
+func (r *MemoryTagRepository) countedTag(tag *models.Tag) *models.Tag {
+	counted := *tag
+	counted.BoxCount = 0
+	for _, box := range r.boxes {
+		for _, boxTag := range box.Tags {
+			if boxTag.ID == tag.ID {
+				counted.BoxCount++
+			}
+		}
+	}
+	return &counted
+}
+
+func boxTag(tag *models.Tag) models.BoxTag {
+	return models.BoxTag{ID: tag.ID, Name: tag.Name, Color: tag.Color}
+}
+
+func withoutTag(tags []models.BoxTag, id string) []models.BoxTag {
+	kept := make([]models.BoxTag, 0, len(tags))
+	for _, tag := range tags {
+		if tag.ID != id {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+// sortBoxTags orders a box's tags by name, as the SQL store returns them
+func sortBoxTags(tags []models.BoxTag) {
+	sort.Slice(tags, func(i, j int) bool {
+		a, b := strings.ToLower(tags[i].Name), strings.ToLower(tags[j].Name)
+		if a != b {
+			return a < b
+		}
+		return tags[i].ID < tags[j].ID
+	})
+}
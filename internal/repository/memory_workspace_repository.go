@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// MemoryWorkspaceRepository keeps workspaces, their members and invitations in memory
+type MemoryWorkspaceRepository struct {
+	*memoryData
+}
+
+// copyWorkspace returns a copy of workspace that shares no members slice
+func copyWorkspace(workspace *models.Workspace) *models.Workspace {
+	copied := *workspace
+	copied.Members = append([]models.WorkspaceMember{}, workspace.Members...)
+	return &copied
+}
+
+// CreateWorkspace saves a workspace with its creator as the first member and owner
+func (r *MemoryWorkspaceRepository) CreateWorkspace(workspace *models.Workspace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workspace.Members = []models.WorkspaceMember{{UserID: workspace.CreatedBy, Role: models.RoleOwner, JoinedAt: workspace.CreatedAt}}
+	r.workspaces[workspace.ID] = copyWorkspace(workspace)
+	return nil
+}
+
+func (r *MemoryWorkspaceRepository) GetWorkspaceByID(id string) (*models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workspace, ok := r.workspaces[id]
+	if !ok {
+		return nil, fmt.Errorf("workspace not found")
+	}
+
+	return copyWorkspace(workspace), nil
+}
+
+// GetWorkspacesByUserID returns the workspaces the user belongs to in the
+// order they joined them
+func (r *MemoryWorkspaceRepository) GetWorkspacesByUserID(userID string) ([]*models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	joined := make(map[string]time.Time)
+	workspaces := make([]*models.Workspace, 0)
+	for _, workspace := range r.workspaces {
+		for _, member := range workspace.Members {
+			if member.UserID == userID {
+				joined[workspace.ID] = member.JoinedAt
+				workspaces = append(workspaces, copyWorkspace(workspace))
+				break
+			}
+		}
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool {
+		a, b := joined[workspaces[i].ID], joined[workspaces[j].ID]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return workspaces[i].ID < workspaces[j].ID
+	})
+
+	return workspaces, nil
+}
+
+// UpdateWorkspace saves a workspace's name
+func (r *MemoryWorkspaceRepository) UpdateWorkspace(workspace *models.Workspace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.workspaces[workspace.ID]
+	if !ok {
+		return fmt.Errorf("workspace not found")
+	}
+
+	workspace.UpdatedAt = time.Now()
+	existing.Name = workspace.Name
+	existing.UpdatedAt = workspace.UpdatedAt
+	return nil
+}
+
+// GetWorkspaceRole returns the user's role in the workspace, or "" when they
+// are not a member
+func (r *MemoryWorkspaceRepository) GetWorkspaceRole(workspaceID, userID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if workspace, ok := r.workspaces[workspaceID]; ok {
+		for _, member := range workspace.Members {
+			if member.UserID == userID {
+				return member.Role, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func (r *MemoryWorkspaceRepository) SetWorkspaceMemberRole(workspaceID, userID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if workspace, ok := r.workspaces[workspaceID]; ok {
+		for i := range workspace.Members {
+			if workspace.Members[i].UserID == userID {
+				workspace.Members[i].Role = role
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("member not found")
+}
+
+func (r *MemoryWorkspaceRepository) RemoveWorkspaceMember(workspaceID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workspace, ok := r.workspaces[workspaceID]
+	if !ok {
+		return fmt.Errorf("member not found")
+	}
+
+	for i, member := range workspace.Members {
+		if member.UserID == userID {
+			workspace.Members = append(workspace.Members[:i:i], workspace.Members[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("member not found")
+}
+
+func (r *MemoryWorkspaceRepository) CreateInvitation(invitation *models.WorkspaceInvitation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.invitations {
+		if existing.Code == invitation.Code {
+			return fmt.Errorf("failed to create invitation: duplicate code")
+		}
+	}
+
+	stored := *invitation
+	r.invitations[invitation.ID] = &stored
+	return nil
+}
+
+func (r *MemoryWorkspaceRepository) GetInvitationByCode(code string) (*models.WorkspaceInvitation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, invitation := range r.invitations {
+		if invitation.Code == code {
+			return r.namedInvitation(invitation), nil
+		}
+	}
+
+	return nil, fmt.Errorf("invitation not found")
+}
+
+// GetPendingInvitationsByEmails returns the unexpired invitations not yet
+// accepted that are addressed to any of the given emails, newest first
+func (r *MemoryWorkspaceRepository) GetPendingInvitationsByEmails(emails []string) ([]*models.WorkspaceInvitation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	invitations := make([]*models.WorkspaceInvitation, 0)
+	for _, invitation := range r.invitations {
+		if invitation.AcceptedBy != "" || !invitation.ExpiresAt.After(now) || invitation.Email == "" {
+			continue
+		}
+		for _, email := range emails {
+			if strings.EqualFold(invitation.Email, email) {
+				invitations = append(invitations, r.namedInvitation(invitation))
+				break
+			}
+		}
+	}
+
+	sort.Slice(invitations, func(i, j int) bool {
+		if !invitations[i].CreatedAt.Equal(invitations[j].CreatedAt) {
+			return invitations[i].CreatedAt.After(invitations[j].CreatedAt)
+		}
+		return invitations[i].ID < invitations[j].ID
+	})
+
+	return invitations, nil
+}
+
+// AcceptInvitation marks an invitation as used and adds the user to its
+// workspace with the invitation's role, so a code can only be used once.
+// Existing members keep their role.
+func (r *MemoryWorkspaceRepository) AcceptInvitation(id, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitation, ok := r.invitations[id]
+	if !ok || invitation.AcceptedBy != "" {
+		return fmt.Errorf("invitation not found")
+	}
+
+	workspace, ok := r.workspaces[invitation.WorkspaceID]
+	if !ok {
+		return fmt.Errorf("invitation not found")
+	}
+
+	now := time.Now()
+	invitation.AcceptedBy = userID
+	invitation.AcceptedAt = &now
+
+	if !r.userWorkspaces(userID)[workspace.ID] {
+		workspace.Members = append(workspace.Members, models.WorkspaceMember{UserID: userID, Role: invitation.Role, JoinedAt: now})
+	}
+
+	return nil
+}
+
+// namedInvitation returns a copy of invitation with its workspace name;
+// callers must hold the lock
+func (r *MemoryWorkspaceRepository) namedInvitation(invitation *models.WorkspaceInvitation) *models.WorkspaceInvitation {
+	named := *invitation
+	if workspace, ok := r.workspaces[invitation.WorkspaceID]; ok {
+		named.WorkspaceName = workspace.Name
+	}
+	return &named
+}
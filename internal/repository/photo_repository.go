@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+type PhotoRepository struct {
+	db *database.DB
+}
+
+func NewPhotoRepository(db *database.DB) *PhotoRepository {
+	return &PhotoRepository{db: db}
+}
+
+// photoColumns lists the columns scanPhoto expects, in order
+const photoColumns = `id, box_id, item_id, storage_key, thumbnail_key, content_type, size_bytes, width, height, created_at`
+
+func scanPhoto(row rowScanner) (*models.Photo, error) {
+	photo := &models.Photo{}
+	var itemID sql.NullString
+
+	err := row.Scan(
+		&photo.ID,
+		&photo.BoxID,
+		&itemID,
+		&photo.StorageKey,
+		&photo.ThumbnailKey,
+		&photo.ContentType,
+		&photo.Size,
+		&photo.Width,
+		&photo.Height,
+		&photo.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle NULL item for photos of the box itself
+	photo.ItemID = itemID.String
+	photo.SetURLs()
+
+	return photo, nil
+}
+
+// movePhotos keeps an item's photos with it when it changes box
+func movePhotos(tx *database.Tx, itemID, boxID string) error {
+	_, err := tx.Exec(`UPDATE photos SET box_id = $2 WHERE item_id = $1`, itemID, boxID)
+	if err != nil {
+		return fmt.Errorf("failed to move item photos: %w", err)
+	}
+	return nil
+}
+
+// attachPhotos loads the photos of all the given boxes with a single query
+func attachPhotos(db *database.DB, boxes []*models.Box) error {
+	if len(boxes) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.Box, len(boxes))
+	placeholders := make([]string, len(boxes))
+	args := make([]interface{}, len(boxes))
+	for i, box := range boxes {
+		box.Photos = make([]models.Photo, 0)
+		byID[box.ID] = box
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = box.ID
+	}
+
+	query := `
+		SELECT ` + photoColumns + `
+		FROM photos
+		WHERE box_id IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY created_at, id
+	`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get box photos: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan photo: %w", err)
+		}
+		if box, ok := byID[photo.BoxID]; ok {
+			box.Photos = append(box.Photos, *photo)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PhotoRepository) CreatePhoto(photo *models.Photo) error {
+	query := `
+		INSERT INTO photos (id, box_id, item_id, storage_key, thumbnail_key, content_type, size_bytes, width, height, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.Exec(
+		query,
+		photo.ID,
+		photo.BoxID,
+		nullableString(photo.ItemID),
+		photo.StorageKey,
+		photo.ThumbnailKey,
+		photo.ContentType,
+		photo.Size,
+		photo.Width,
+		photo.Height,
+		photo.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create photo: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PhotoRepository) GetPhotoByID(id string) (*models.Photo, error) {
+	photo, err := scanPhoto(r.db.QueryRow(`SELECT `+photoColumns+` FROM photos WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("photo not found")
+		}
+		return nil, fmt.Errorf("failed to get photo: %w", err)
+	}
+
+	return photo, nil
+}
+
+func (r *PhotoRepository) DeletePhoto(id string) error {
+	result, err := r.db.Exec(`DELETE FROM photos WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete photo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("photo not found")
+	}
+
+	return nil
+}
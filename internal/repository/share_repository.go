@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+type ShareRepository struct {
+	db *database.DB
+}
+
+func NewShareRepository(db *database.DB) *ShareRepository {
+	return &ShareRepository{db: db}
+}
+
+const shareColumns = `id, workspace_id, scope, target_id, can_edit, created_by, created_at, expires_at, revoked_at`
+
+func scanShare(row rowScanner) (*models.ShareLink, error) {
+	share := &models.ShareLink{}
+	var revokedAt sql.NullTime
+
+	err := row.Scan(
+		&share.ID,
+		&share.WorkspaceID,
+		&share.Scope,
+		&share.TargetID,
+		&share.CanEdit,
+		&share.CreatedBy,
+		&share.CreatedAt,
+		&share.ExpiresAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		share.RevokedAt = &revokedAt.Time
+	}
+
+	return share, nil
+}
+
+func (r *ShareRepository) CreateShare(share *models.ShareLink) error {
+	_, err := r.db.Exec(
+		`INSERT INTO share_links (`+shareColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULL)`,
+		share.ID, share.WorkspaceID, share.Scope, share.TargetID, share.CanEdit, share.CreatedBy,
+		share.CreatedAt.UTC(), share.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ShareRepository) GetShareByID(id string) (*models.ShareLink, error) {
+	share, err := scanShare(r.db.QueryRow(`SELECT `+shareColumns+` FROM share_links WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("share not found")
+		}
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	return share, nil
+}
+
+// GetSharesByUserID returns the share links of every workspace the user
+// belongs to, newest first, including expired and revoked ones
+func (r *ShareRepository) GetSharesByUserID(userID string) ([]*models.ShareLink, error) {
+	query := `
+		SELECT ` + shareColumns + `
+		FROM share_links
+		WHERE ` + memberWorkspaces("workspace_id", "$1") + `
+		ORDER BY created_at DESC, id
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+	defer rows.Close()
+
+	shares := make([]*models.ShareLink, 0)
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		shares = append(shares, share)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate share links: %w", err)
+	}
+
+	return shares, nil
+}
+
+// RevokeShare puts a share link on the revocation list. Links already revoked
+// are reported as not found.
+func (r *ShareRepository) RevokeShare(id string) error {
+	result, err := r.db.Exec(
+		`UPDATE share_links SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`,
+		id, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("share not found")
+	}
+
+	return nil
+}
+
+// GetSharedBoxes returns the boxes a share link covers, by name. Only boxes
+// in the link's workspace count.
+func (r *ShareRepository) GetSharedBoxes(share *models.ShareLink) ([]*models.Box, error) {
+	var condition string
+	switch share.Scope {
+	case models.ShareScopeBox:
+		condition = `id = $2`
+	case models.ShareScopeLocation:
+		condition = `location_id IN (
+			WITH RECURSIVE subtree(id) AS (
+				SELECT id FROM locations WHERE id = $2 AND workspace_id = $1
+				UNION ALL
+				SELECT locations.id FROM locations JOIN subtree ON locations.parent_id = subtree.id
+			)
+			SELECT id FROM subtree
+		)`
+	case models.ShareScopeTag:
+		condition = `id IN (SELECT box_id FROM box_tags WHERE tag_id = $2)`
+	default:
+		return nil, fmt.Errorf("invalid share scope")
+	}
+
+	query := `
+		SELECT ` + boxColumns + `
+		FROM boxes
+		WHERE workspace_id = $1 AND ` + condition + `
+		ORDER BY LOWER(name), id
+	`
+
+	rows, err := r.db.Query(query, share.WorkspaceID, share.TargetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shared boxes: %w", err)
+	}
+	defer rows.Close()
+
+	boxes := make([]*models.Box, 0)
+	for rows.Next() {
+		box, err := scanBox(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan box: %w", err)
+		}
+		boxes = append(boxes, box)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate boxes: %w", err)
+	}
+
+	if err := attachDetails(r.db, boxes); err != nil {
+		return nil, err
+	}
+
+	return boxes, nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// BoxStore is the persistence contract for boxes, implemented by the SQL
+// repository (PostgreSQL or SQLite) and by the in-memory store used for local runs
+type BoxStore interface {
+	Create(box *models.Box) error
+	CreateBoxes(boxes []*models.Box, rooms []*models.Location) error
+	GetByID(id string) (*models.Box, error)
+	GetByShortCode(code string) (*models.Box, error)
+	GetBoxIDsWithoutShortCode() ([]string, error)
+	SetShortCode(boxID, code string) error
+	GetShortCodes() (map[string]string, error)
+	SetQRCode(boxID, qrCode, qrCodeURL string) error
+	GetByUserID(userID string) ([]*models.Box, error)
+	ListByUserID(userID string, options models.BoxListOptions) (*models.BoxPage, error)
+	Search(userID, query string, limit int, tags models.TagFilter) ([]*models.BoxSearchResult, error)
+	Update(box *models.Box, tagIDs []string, items []models.Item) error
+	Delete(id, userID string) error
+	GetUserBoxCount(userID string) (int, error)
+	GetContainmentChains(boxIDs []string) (map[string][]models.BoxCrumb, error)
+	GetDescendants(boxID string) ([]*models.Box, error)
+}
+
+// ItemStore keeps the items packed in boxes
+type ItemStore interface {
+	CreateItem(item *models.Item) error
+	GetItemByID(id string) (*models.Item, error)
+	UpdateItem(item *models.Item) error
+	MoveItems(fromBoxID, toBoxID string, itemIDs []string) error
+	AdjustItemQuantity(id string, delta int) (*models.Item, error)
+	DeleteItem(id string) error
+	GetItemTotals(userID string) ([]models.ItemTotal, error)
+}
+
+// LocationStore keeps the location tree of each workspace
+type LocationStore interface {
+	CreateLocation(location *models.Location) error
+	GetLocationByID(id string) (*models.Location, error)
+	GetLocationsByUserID(userID string) ([]*models.Location, error)
+	UpdateLocation(location *models.Location) error
+	DeleteLocation(id string) error
+}
+
+// PhotoStore keeps the records of photos attached to boxes and items; the
+// files themselves live in a storage.BlobStore
+type PhotoStore interface {
+	CreatePhoto(photo *models.Photo) error
+	GetPhotoByID(id string) (*models.Photo, error)
+	DeletePhoto(id string) error
+}
+
+// TagStore keeps tags and which boxes carry them
+type TagStore interface {
+	CreateTag(tag *models.Tag) error
+	GetTagByID(id string) (*models.Tag, error)
+	GetTagsByUserID(userID string) ([]*models.Tag, error)
+	UpdateTag(tag *models.Tag) error
+	DeleteTag(id string) error
+	MergeTags(targetID string, sourceIDs []string) error
+}
+
+// WorkspaceStore keeps workspaces, their members and invitations
+type WorkspaceStore interface {
+	CreateWorkspace(workspace *models.Workspace) error
+	GetWorkspaceByID(id string) (*models.Workspace, error)
+	GetWorkspacesByUserID(userID string) ([]*models.Workspace, error)
+	UpdateWorkspace(workspace *models.Workspace) error
+	GetWorkspaceRole(workspaceID, userID string) (string, error)
+	SetWorkspaceMemberRole(workspaceID, userID, role string) error
+	RemoveWorkspaceMember(workspaceID, userID string) error
+	CreateInvitation(invitation *models.WorkspaceInvitation) error
+	GetInvitationByCode(code string) (*models.WorkspaceInvitation, error)
+	GetPendingInvitationsByEmails(emails []string) ([]*models.WorkspaceInvitation, error)
+	AcceptInvitation(id, userID string) error
+}
+
+// ShareStore keeps share links
+type ShareStore interface {
+	CreateShare(share *models.ShareLink) error
+	GetShareByID(id string) (*models.ShareLink, error)
+	GetSharesByUserID(userID string) ([]*models.ShareLink, error)
+	RevokeShare(id string) error
+	GetSharedBoxes(share *models.ShareLink) ([]*models.Box, error)
+}
+
+// Stores holds one store per aggregate, all backed by the same database so
+// that each sees the others' writes
+type Stores struct {
+	Boxes      BoxStore
+	Items      ItemStore
+	Locations  LocationStore
+	Photos     PhotoStore
+	Tags       TagStore
+	Workspaces WorkspaceStore
+	Shares     ShareStore
+}
+
+// NewStores returns the SQL stores for db
+func NewStores(db *database.DB) *Stores {
+	return &Stores{
+		Boxes:      NewBoxRepository(db),
+		Items:      NewItemRepository(db),
+		Locations:  NewLocationRepository(db),
+		Photos:     NewPhotoRepository(db),
+		Tags:       NewTagRepository(db),
+		Workspaces: NewWorkspaceRepository(db),
+		Shares:     NewShareRepository(db),
+	}
+}
+
+var (
+	_ BoxStore       = (*BoxRepository)(nil)
+	_ ItemStore      = (*ItemRepository)(nil)
+	_ LocationStore  = (*LocationRepository)(nil)
+	_ PhotoStore     = (*PhotoRepository)(nil)
+	_ TagStore       = (*TagRepository)(nil)
+	_ WorkspaceStore = (*WorkspaceRepository)(nil)
+	_ ShareStore     = (*ShareRepository)(nil)
+
+	_ BoxStore       = (*MemoryBoxRepository)(nil)
+	_ ItemStore      = (*MemoryItemRepository)(nil)
+	_ LocationStore  = (*MemoryLocationRepository)(nil)
+	_ PhotoStore     = (*MemoryPhotoRepository)(nil)
+	_ TagStore       = (*MemoryTagRepository)(nil)
+	_ WorkspaceStore = (*MemoryWorkspaceRepository)(nil)
+	_ ShareStore     = (*MemoryShareRepository)(nil)
+)
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+type TagRepository struct {
+	db *database.DB
+}
+
+func NewTagRepository(db *database.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// tagSelect reads tags in the column order scanTag expects, counting the
+// boxes each one is on
+const tagSelect = `
+	SELECT id, user_id, workspace_id, name, color, created_at, updated_at,
+		(SELECT COUNT(*) FROM box_tags WHERE box_tags.tag_id = tags.id)
+	FROM tags
+`
+
+func scanTag(row rowScanner) (*models.Tag, error) {
+	tag := &models.Tag{}
+
+	err := row.Scan(
+		&tag.ID,
+		&tag.UserID,
+		&tag.WorkspaceID,
+		&tag.Name,
+		&tag.Color,
+		&tag.CreatedAt,
+		&tag.UpdatedAt,
+		&tag.BoxCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// prepareTagFilter drops blank and repeated tag IDs and defaults to matching all tags
+func prepareTagFilter(filter models.TagFilter) (models.TagFilter, error) {
+	filter.Match = strings.ToLower(strings.TrimSpace(filter.Match))
+	switch filter.Match {
+	case "":
+		filter.Match = models.TagMatchAll
+	case models.TagMatchAll, models.TagMatchAny:
+	default:
+		return filter, fmt.Errorf("invalid tag match")
+	}
+
+	seen := make(map[string]bool, len(filter.TagIDs))
+	tagIDs := make([]string, 0, len(filter.TagIDs))
+	for _, id := range filter.TagIDs {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			tagIDs = append(tagIDs, id)
+		}
+	}
+	filter.TagIDs = tagIDs
+
+	return filter, nil
+}
+
+// tagFilterCondition returns a WHERE condition on boxes.id for the filter,
+// appending its arguments to args. It returns "" when the filter is empty.
+func tagFilterCondition(filter models.TagFilter, args []interface{}) (string, []interface{}) {
+	if len(filter.TagIDs) == 0 {
+		return "", args
+	}
+
+	placeholders := make([]string, len(filter.TagIDs))
+	for i, id := range filter.TagIDs {
+		args = append(args, id)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+
+	condition := `id IN (SELECT box_id FROM box_tags WHERE tag_id IN (` + strings.Join(placeholders, ", ") + `)`
+	if filter.Match == models.TagMatchAll {
+		condition += fmt.Sprintf(` GROUP BY box_id HAVING COUNT(*) = %d`, len(filter.TagIDs))
+	}
+
+	return condition + `)`, args
+}
+
+// boxHasTags reports whether box passes filter; used where boxes are filtered in Go
+func boxHasTags(box *models.Box, filter models.TagFilter) bool {
+	if len(filter.TagIDs) == 0 {
+		return true
+	}
+
+	onBox := make(map[string]bool, len(box.Tags))
+	for _, tag := range box.Tags {
+		onBox[tag.ID] = true
+	}
+
+	matched := 0
+	for _, id := range filter.TagIDs {
+		if onBox[id] {
+			matched++
+		}
+	}
+
+	if filter.Match == models.TagMatchAny {
+		return matched > 0
+	}
+	return matched == len(filter.TagIDs)
+}
+
+// attachTags loads the tags of all the given boxes with a single query
+func attachTags(db *database.DB, boxes []*models.Box) error {
+	if len(boxes) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.Box, len(boxes))
+	placeholders := make([]string, len(boxes))
+	args := make([]interface{}, len(boxes))
+	for i, box := range boxes {
+		box.Tags = make([]models.BoxTag, 0)
+		byID[box.ID] = box
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = box.ID
+	}
+
+	query := `
+		SELECT box_tags.box_id, tags.id, tags.name, tags.color
+		FROM box_tags
+		JOIN tags ON tags.id = box_tags.tag_id
+		WHERE box_tags.box_id IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY LOWER(tags.name), tags.id
+	`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get box tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var boxID string
+		var tag models.BoxTag
+		if err := rows.Scan(&boxID, &tag.ID, &tag.Name, &tag.Color); err != nil {
+			return fmt.Errorf("failed to scan tag: %w", err)
+		}
+		if box, ok := byID[boxID]; ok {
+			box.Tags = append(box.Tags, tag)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TagRepository) CreateTag(tag *models.Tag) error {
+	query := `
+		INSERT INTO tags (id, user_id, workspace_id, name, color, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(query, tag.ID, tag.UserID, tag.WorkspaceID, tag.Name, tag.Color, tag.CreatedAt.UTC(), tag.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TagRepository) GetTagByID(id string) (*models.Tag, error) {
+	tag, err := scanTag(r.db.QueryRow(tagSelect+` WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("tag not found")
+		}
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// GetTagsByUserID returns the tags of every workspace the user belongs to
+// ordered by name, each with the number of boxes it is on
+func (r *TagRepository) GetTagsByUserID(userID string) ([]*models.Tag, error) {
+	rows, err := r.db.Query(tagSelect+` WHERE `+memberWorkspaces("workspace_id", "$1")+` ORDER BY LOWER(name), id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make([]*models.Tag, 0)
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// UpdateTag saves a tag's name and colour
+func (r *TagRepository) UpdateTag(tag *models.Tag) error {
+	tag.UpdatedAt = time.Now()
+
+	result, err := r.db.Exec(
+		`UPDATE tags SET name = $2, color = $3, updated_at = $4 WHERE id = $1 AND workspace_id = $5`,
+		tag.ID, tag.Name, tag.Color, tag.UpdatedAt.UTC(), tag.WorkspaceID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag not found")
+	}
+
+	return nil
+}
+
+// DeleteTag removes a tag from every box and deletes it
+func (r *TagRepository) DeleteTag(id string) error {
+	result, err := r.db.Exec(`DELETE FROM tags WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag not found")
+	}
+
+	return nil
+}
+
+// MergeTags retags every box carrying one of the source tags with the target
+// and deletes the sources, in a single transaction
+func (r *TagRepository) MergeTags(targetID string, sourceIDs []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, sourceID := range sourceIDs {
+		_, err := tx.Exec(`
+			INSERT INTO box_tags (box_id, tag_id)
+			SELECT box_id, $1 FROM box_tags
+			WHERE tag_id = $2
+				AND box_id NOT IN (SELECT box_id FROM box_tags WHERE tag_id = $1)`,
+			targetID, sourceID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to retag boxes: %w", err)
+		}
+
+		result, err := tx.Exec(`DELETE FROM tags WHERE id = $1`, sourceID)
+		if err != nil {
+			return fmt.Errorf("failed to delete merged tag: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("tag not found")
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE tags SET updated_at = $2 WHERE id = $1`, targetID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tag merge: %w", err)
+	}
+
+	return nil
+}
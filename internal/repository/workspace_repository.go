@@ -0,0 +1,419 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+type WorkspaceRepository struct {
+	db *database.DB
+}
+
+func NewWorkspaceRepository(db *database.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// memberWorkspaces returns a WHERE condition limiting column to the workspaces
+// the user bound to placeholder belongs to
+func memberWorkspaces(column, placeholder string) string {
+	return column + ` IN (SELECT workspace_id FROM workspace_members WHERE user_id = ` + placeholder + `)`
+}
+
+const invitationSelect = `
+	SELECT workspace_invitations.id, workspace_invitations.workspace_id, workspaces.name,
+		workspace_invitations.code, workspace_invitations.email, workspace_invitations.role,
+		workspace_invitations.invited_by,
+		workspace_invitations.created_at, workspace_invitations.expires_at,
+		workspace_invitations.accepted_by, workspace_invitations.accepted_at
+	FROM workspace_invitations
+	JOIN workspaces ON workspaces.id = workspace_invitations.workspace_id
+`
+
+func scanInvitation(row rowScanner) (*models.WorkspaceInvitation, error) {
+	invitation := &models.WorkspaceInvitation{}
+	var email, acceptedBy sql.NullString
+	var acceptedAt sql.NullTime
+
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.WorkspaceID,
+		&invitation.WorkspaceName,
+		&invitation.Code,
+		&email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.CreatedAt,
+		&invitation.ExpiresAt,
+		&acceptedBy,
+		&acceptedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation.Email = email.String
+	invitation.AcceptedBy = acceptedBy.String
+	if acceptedAt.Valid {
+		invitation.AcceptedAt = &acceptedAt.Time
+	}
+
+	return invitation, nil
+}
+
+// CreateWorkspace saves a workspace with its creator as the first member and owner
+func (r *WorkspaceRepository) CreateWorkspace(workspace *models.Workspace) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO workspaces (id, name, created_by, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)`,
+		workspace.ID, workspace.Name, workspace.CreatedBy, workspace.CreatedAt.UTC(), workspace.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4)`,
+		workspace.ID, workspace.CreatedBy, models.RoleOwner, workspace.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add workspace member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit workspace: %w", err)
+	}
+
+	workspace.Members = []models.WorkspaceMember{{UserID: workspace.CreatedBy, Role: models.RoleOwner, JoinedAt: workspace.CreatedAt}}
+	return nil
+}
+
+func (r *WorkspaceRepository) GetWorkspaceByID(id string) (*models.Workspace, error) {
+	workspace := &models.Workspace{}
+	err := r.db.QueryRow(
+		`SELECT id, name, created_by, created_at, updated_at FROM workspaces WHERE id = $1`, id,
+	).Scan(&workspace.ID, &workspace.Name, &workspace.CreatedBy, &workspace.CreatedAt, &workspace.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("workspace not found")
+		}
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	if err := r.attachMembers([]*models.Workspace{workspace}); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// GetWorkspacesByUserID returns the workspaces the user belongs to in the
+// order they joined them
+func (r *WorkspaceRepository) GetWorkspacesByUserID(userID string) ([]*models.Workspace, error) {
+	rows, err := r.db.Query(`
+		SELECT workspaces.id, workspaces.name, workspaces.created_by, workspaces.created_at, workspaces.updated_at
+		FROM workspaces
+		JOIN workspace_members ON workspace_members.workspace_id = workspaces.id
+		WHERE workspace_members.user_id = $1
+		ORDER BY workspace_members.joined_at, workspaces.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	workspaces := make([]*models.Workspace, 0)
+	for rows.Next() {
+		workspace := &models.Workspace{}
+		if err := rows.Scan(&workspace.ID, &workspace.Name, &workspace.CreatedBy, &workspace.CreatedAt, &workspace.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace: %w", err)
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if err := r.attachMembers(workspaces); err != nil {
+		return nil, err
+	}
+
+	return workspaces, nil
+}
+
+// attachMembers loads the members of all the given workspaces with a single query
+func (r *WorkspaceRepository) attachMembers(workspaces []*models.Workspace) error {
+	if len(workspaces) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.Workspace, len(workspaces))
+	placeholders := make([]string, len(workspaces))
+	args := make([]interface{}, len(workspaces))
+	for i, workspace := range workspaces {
+		workspace.Members = make([]models.WorkspaceMember, 0)
+		byID[workspace.ID] = workspace
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = workspace.ID
+	}
+
+	rows, err := r.db.Query(`
+		SELECT workspace_id, user_id, role, joined_at
+		FROM workspace_members
+		WHERE workspace_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY joined_at, user_id`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get workspace members: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workspaceID string
+		var member models.WorkspaceMember
+		if err := rows.Scan(&workspaceID, &member.UserID, &member.Role, &member.JoinedAt); err != nil {
+			return fmt.Errorf("failed to scan workspace member: %w", err)
+		}
+		if workspace, ok := byID[workspaceID]; ok {
+			workspace.Members = append(workspace.Members, member)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateWorkspace saves a workspace's name
+func (r *WorkspaceRepository) UpdateWorkspace(workspace *models.Workspace) error {
+	workspace.UpdatedAt = time.Now()
+
+	result, err := r.db.Exec(
+		`UPDATE workspaces SET name = $2, updated_at = $3 WHERE id = $1`,
+		workspace.ID, workspace.Name, workspace.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update workspace: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("workspace not found")
+	}
+
+	return nil
+}
+
+// GetWorkspaceRole returns the user's role in the workspace, or "" when they
+// are not a member
+func (r *WorkspaceRepository) GetWorkspaceRole(workspaceID, userID string) (string, error) {
+	var role string
+	err := r.db.QueryRow(
+		`SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`,
+		workspaceID, userID,
+	).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get workspace role: %w", err)
+	}
+
+	return role, nil
+}
+
+func (r *WorkspaceRepository) SetWorkspaceMemberRole(workspaceID, userID, role string) error {
+	result, err := r.db.Exec(
+		`UPDATE workspace_members SET role = $3 WHERE workspace_id = $1 AND user_id = $2`,
+		workspaceID, userID, role,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update member role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("member not found")
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) RemoveWorkspaceMember(workspaceID, userID string) error {
+	result, err := r.db.Exec(
+		`DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`,
+		workspaceID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("member not found")
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) CreateInvitation(invitation *models.WorkspaceInvitation) error {
+	query := `
+		INSERT INTO workspace_invitations (id, workspace_id, code, email, role, invited_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(query,
+		invitation.ID,
+		invitation.WorkspaceID,
+		invitation.Code,
+		nullableString(invitation.Email),
+		invitation.Role,
+		invitation.InvitedBy,
+		invitation.CreatedAt.UTC(),
+		invitation.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) GetInvitationByCode(code string) (*models.WorkspaceInvitation, error) {
+	invitation, err := scanInvitation(r.db.QueryRow(invitationSelect+` WHERE workspace_invitations.code = $1`, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invitation not found")
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	return invitation, nil
+}
+
+// GetPendingInvitationsByEmails returns the unexpired invitations not yet
+// accepted that are addressed to any of the given emails, newest first
+func (r *WorkspaceRepository) GetPendingInvitationsByEmails(emails []string) ([]*models.WorkspaceInvitation, error) {
+	invitations := make([]*models.WorkspaceInvitation, 0)
+	if len(emails) == 0 {
+		return invitations, nil
+	}
+
+	args := []interface{}{time.Now().UTC()}
+	placeholders := make([]string, len(emails))
+	for i, email := range emails {
+		args = append(args, strings.ToLower(email))
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+
+	rows, err := r.db.Query(invitationSelect+`
+		WHERE workspace_invitations.accepted_by IS NULL
+			AND workspace_invitations.expires_at > $1
+			AND LOWER(workspace_invitations.email) IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY workspace_invitations.created_at DESC, workspace_invitations.id`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// AcceptInvitation marks an invitation as used and adds the user to its
+// workspace with the invitation's role in one transaction, so a code can only
+// be used once. Existing members keep their role.
+func (r *WorkspaceRepository) AcceptInvitation(id, userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.Exec(
+		`UPDATE workspace_invitations SET accepted_by = $2, accepted_at = $3 WHERE id = $1 AND accepted_by IS NULL`,
+		id, userID, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("invitation not found")
+	}
+
+	var workspaceID, role string
+	var members int
+	err = tx.QueryRow(`
+		SELECT workspace_id, role,
+			(SELECT COUNT(*) FROM workspace_members
+			WHERE workspace_members.workspace_id = workspace_invitations.workspace_id AND user_id = $2)
+		FROM workspace_invitations WHERE id = $1`,
+		id, userID,
+	).Scan(&workspaceID, &role, &members)
+	if err != nil {
+		return fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	// Accepting an invitation to a workspace the user is already in just uses it up
+	if members == 0 {
+		_, err = tx.Exec(
+			`INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4)`,
+			workspaceID, userID, role, now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add workspace member: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit invitation: %w", err)
+	}
+
+	return nil
+}
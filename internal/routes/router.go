@@ -10,16 +10,30 @@ import (
 )
 
 type Router struct {
-	userHandler   *handlers.UserHandler
-	healthHandler *handlers.HealthHandler
-	qrHandler     *handlers.QRHandler
+	userHandler      *handlers.UserHandler
+	healthHandler    *handlers.HealthHandler
+	qrHandler        *handlers.QRHandler
+	labelHandler     *handlers.LabelHandler
+	itemHandler      *handlers.ItemHandler
+	locationHandler  *handlers.LocationHandler
+	photoHandler     *handlers.PhotoHandler
+	tagHandler       *handlers.TagHandler
+	workspaceHandler *handlers.WorkspaceHandler
+	shareHandler     *handlers.ShareHandler
 }
 
-func NewRouter(userHandler *handlers.UserHandler, healthHandler *handlers.HealthHandler, qrHandler *handlers.QRHandler) *Router {
+func NewRouter(userHandler *handlers.UserHandler, healthHandler *handlers.HealthHandler, qrHandler *handlers.QRHandler, labelHandler *handlers.LabelHandler, itemHandler *handlers.ItemHandler, locationHandler *handlers.LocationHandler, photoHandler *handlers.PhotoHandler, tagHandler *handlers.TagHandler, workspaceHandler *handlers.WorkspaceHandler, shareHandler *handlers.ShareHandler) *Router {
 	return &Router{
-		userHandler:   userHandler,
-		healthHandler: healthHandler,
-		qrHandler:     qrHandler,
+		userHandler:      userHandler,
+		healthHandler:    healthHandler,
+		qrHandler:        qrHandler,
+		labelHandler:     labelHandler,
+		itemHandler:      itemHandler,
+		locationHandler:  locationHandler,
+		photoHandler:     photoHandler,
+		tagHandler:       tagHandler,
+		workspaceHandler: workspaceHandler,
+		shareHandler:     shareHandler,
 	}
 }
 
@@ -28,21 +42,73 @@ func (rt *Router) SetupRoutes() http.Handler {
 
 	// Public endpoints
 	mux.HandleFunc("/api/health", rt.healthHandler.GetHealth)
+	mux.HandleFunc("/q/", rt.qrHandler.RedirectToBox)
 	mux.HandleFunc("/api/public/box", rt.qrHandler.GetPublicBoxDetails)
+	mux.HandleFunc("/api/public/box/unlock", rt.qrHandler.UnlockPublicBox)
+	mux.HandleFunc("/api/public/photo", middleware.OptionalAuthMiddleware(rt.photoHandler.GetPhoto))
+	mux.HandleFunc("/api/public/share", rt.shareHandler.ResolveShare)
+	mux.HandleFunc("/api/public/share/add-item", rt.shareHandler.AddSharedItem)
+	mux.HandleFunc("/api/public/share/remove-item", rt.shareHandler.RemoveSharedItem)
 
 	// Protected endpoints requiring authentication
 	mux.HandleFunc("/api/user/profile", middleware.AuthMiddleware(rt.userHandler.GetProfile))
 
 	// QR and Box endpoints
 	mux.HandleFunc("/api/boxes", middleware.AuthMiddleware(rt.qrHandler.CreateBox))
+	mux.HandleFunc("/api/boxes/batch", middleware.AuthMiddleware(rt.qrHandler.CreateBoxes))
 	mux.HandleFunc("/api/boxes/list", middleware.AuthMiddleware(rt.qrHandler.GetUserBoxes))
+	mux.HandleFunc("/api/boxes/search", middleware.AuthMiddleware(rt.qrHandler.SearchBoxes))
 	mux.HandleFunc("/api/boxes/details", middleware.AuthMiddleware(rt.qrHandler.GetBoxByID))
 	mux.HandleFunc("/api/boxes/qr", middleware.AuthMiddleware(rt.qrHandler.GetBoxQR))
+	mux.HandleFunc("/api/boxes/qr/image", middleware.AuthMiddleware(rt.qrHandler.GetBoxQRImage))
+	mux.HandleFunc("/api/boxes/scan", middleware.AuthMiddleware(rt.qrHandler.ScanPhoto))
+	mux.HandleFunc("/api/boxes/labels", middleware.AuthMiddleware(rt.labelHandler.GetLabelSheet))
 	mux.HandleFunc("/api/boxes/update", middleware.AuthMiddleware(rt.qrHandler.UpdateBox))
 	mux.HandleFunc("/api/boxes/add-item", middleware.AuthMiddleware(rt.qrHandler.AddItemToBox))
+	mux.HandleFunc("/api/boxes/move-item", middleware.AuthMiddleware(rt.qrHandler.MoveItems))
 	mux.HandleFunc("/api/boxes/remove-item", middleware.AuthMiddleware(rt.qrHandler.RemoveItemFromBox))
 	mux.HandleFunc("/api/boxes/delete", middleware.AuthMiddleware(rt.qrHandler.DeleteBox))
 	mux.HandleFunc("/api/boxes/stats", middleware.AuthMiddleware(rt.qrHandler.GetUserStats))
+	mux.HandleFunc("/api/boxes/photos", middleware.AuthMiddleware(rt.photoHandler.UploadPhoto))
+	mux.HandleFunc("/api/boxes/photos/delete", middleware.AuthMiddleware(rt.photoHandler.DeletePhoto))
+
+	// Item endpoints
+	mux.HandleFunc("/api/items", middleware.AuthMiddleware(rt.itemHandler.CreateItem))
+	mux.HandleFunc("/api/items/details", middleware.AuthMiddleware(rt.itemHandler.GetItem))
+	mux.HandleFunc("/api/items/update", middleware.AuthMiddleware(rt.itemHandler.UpdateItem))
+	mux.HandleFunc("/api/items/move", middleware.AuthMiddleware(rt.itemHandler.MoveItem))
+	mux.HandleFunc("/api/items/adjust", middleware.AuthMiddleware(rt.itemHandler.AdjustItemQuantity))
+	mux.HandleFunc("/api/items/delete", middleware.AuthMiddleware(rt.itemHandler.DeleteItem))
+
+	// Location endpoints
+	mux.HandleFunc("/api/locations", middleware.AuthMiddleware(rt.locationHandler.CreateLocation))
+	mux.HandleFunc("/api/locations/list", middleware.AuthMiddleware(rt.locationHandler.GetUserLocations))
+	mux.HandleFunc("/api/locations/details", middleware.AuthMiddleware(rt.locationHandler.GetLocation))
+	mux.HandleFunc("/api/locations/update", middleware.AuthMiddleware(rt.locationHandler.UpdateLocation))
+	mux.HandleFunc("/api/locations/delete", middleware.AuthMiddleware(rt.locationHandler.DeleteLocation))
+
+	// Tag endpoints
+	mux.HandleFunc("/api/tags", middleware.AuthMiddleware(rt.tagHandler.CreateTag))
+	mux.HandleFunc("/api/tags/list", middleware.AuthMiddleware(rt.tagHandler.GetUserTags))
+	mux.HandleFunc("/api/tags/update", middleware.AuthMiddleware(rt.tagHandler.UpdateTag))
+	mux.HandleFunc("/api/tags/merge", middleware.AuthMiddleware(rt.tagHandler.MergeTags))
+	mux.HandleFunc("/api/tags/delete", middleware.AuthMiddleware(rt.tagHandler.DeleteTag))
+
+	// Workspace endpoints
+	mux.HandleFunc("/api/workspaces", middleware.AuthMiddleware(rt.workspaceHandler.CreateWorkspace))
+	mux.HandleFunc("/api/workspaces/list", middleware.AuthMiddleware(rt.workspaceHandler.GetUserWorkspaces))
+	mux.HandleFunc("/api/workspaces/update", middleware.AuthMiddleware(rt.workspaceHandler.UpdateWorkspace))
+	mux.HandleFunc("/api/workspaces/invite", middleware.AuthMiddleware(rt.workspaceHandler.CreateInvitation))
+	mux.HandleFunc("/api/workspaces/invitations", middleware.AuthMiddleware(rt.workspaceHandler.GetPendingInvitations))
+	mux.HandleFunc("/api/workspaces/join", middleware.AuthMiddleware(rt.workspaceHandler.JoinWorkspace))
+	mux.HandleFunc("/api/workspaces/leave", middleware.AuthMiddleware(rt.workspaceHandler.LeaveWorkspace))
+	mux.HandleFunc("/api/workspaces/members/role", middleware.AuthMiddleware(rt.workspaceHandler.UpdateMemberRole))
+	mux.HandleFunc("/api/workspaces/members/remove", middleware.AuthMiddleware(rt.workspaceHandler.RemoveMember))
+
+	// Share link endpoints
+	mux.HandleFunc("/api/shares", middleware.AuthMiddleware(rt.shareHandler.CreateShare))
+	mux.HandleFunc("/api/shares/list", middleware.AuthMiddleware(rt.shareHandler.GetShares))
+	mux.HandleFunc("/api/shares/revoke", middleware.AuthMiddleware(rt.shareHandler.RevokeShare))
 
 	// Setup CORS
 	config := utils.GetConfig()
@@ -59,11 +125,12 @@ func (rt *Router) SetupRoutes() http.Handler {
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-None-Match", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"ETag", "Link"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum cache age for preflight options requests
 	})
 
-	return corsMiddleware.Handler(mux)
+	// Behind a proxy the connection comes from the proxy, not the client
+	return middleware.ClientIP(config.ClientIPHeader, corsMiddleware.Handler(mux))
 }
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+)
+
+// action is something a user can do with the boxes, items, photos,
+// locations and tags of a workspace
+type action string
+
+const (
+	// actionView reads workspace data
+	actionView action = "view"
+	// actionEdit changes contents: boxes, items, photos, locations and tags
+	actionEdit action = "edit"
+	// actionDelete deletes boxes
+	actionDelete action = "delete"
+	// actionManage renames the workspace and invites, removes or changes members
+	actionManage action = "manage"
+)
+
+// rolePermissions lists what each workspace role may do
+var rolePermissions = map[string]map[action]bool{
+	models.RoleViewer: {actionView: true},
+	models.RoleEditor: {actionView: true, actionEdit: true},
+	models.RoleOwner:  {actionView: true, actionEdit: true, actionDelete: true, actionManage: true},
+}
+
+// validRole reports whether role is one of the workspace roles
+func validRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// roleAllows reports whether a member with role may perform act. An empty
+// role, meaning not a member, allows nothing.
+func roleAllows(role string, act action) bool {
+	return rolePermissions[role][act]
+}
+
+// authorize is the single check every service goes through before touching
+// workspace data. Users outside the workspace and members whose role doesn't
+// allow act are both refused with "forbidden".
+func authorize(workspaceRepo repository.WorkspaceStore, userID, workspaceID string, act action) error {
+	role, err := workspaceRepo.GetWorkspaceRole(workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !roleAllows(role, act) {
+		return fmt.Errorf("forbidden")
+	}
+
+	return nil
+}
+
+// authorizeBox loads a box by its ID or short code and checks that the user
+// may perform act on it
+func authorizeBox(boxRepo repository.BoxStore, workspaceRepo repository.WorkspaceStore, userID, boxID string, act action) (*models.Box, error) {
+	box, err := lookupBox(boxRepo, boxID)
+	if err != nil {
+		return nil, fmt.Errorf("box not found")
+	}
+
+	if err := authorize(workspaceRepo, userID, box.WorkspaceID, act); err != nil {
+		return nil, err
+	}
+
+	return box, nil
+}
+
+// authorizeReference checks a location or tag the user is looking up or
+// pointing a box at. Outside the workspace it reports notFound, so IDs from
+// other households can't be probed; members lacking the role get "forbidden".
+func authorizeReference(workspaceRepo repository.WorkspaceStore, userID, workspaceID string, act action, notFound string) error {
+	role, err := workspaceRepo.GetWorkspaceRole(workspaceID, userID)
+	if err != nil || role == "" {
+		return fmt.Errorf("%s", notFound)
+	}
+
+	if !roleAllows(role, act) {
+		return fmt.Errorf("forbidden")
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+)
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role    string
+		allowed []action
+	}{
+		{models.RoleViewer, []action{actionView}},
+		{models.RoleEditor, []action{actionView, actionEdit}},
+		{models.RoleOwner, []action{actionView, actionEdit, actionDelete, actionManage}},
+		{"", nil},
+		{"admin", nil},
+	}
+
+	for _, tt := range tests {
+		allowed := make(map[action]bool)
+		for _, act := range tt.allowed {
+			allowed[act] = true
+		}
+
+		for _, act := range []action{actionView, actionEdit, actionDelete, actionManage} {
+			if got := roleAllows(tt.role, act); got != allowed[act] {
+				t.Errorf("roleAllows(%q, %s) = %v, want %v", tt.role, act, got, allowed[act])
+			}
+		}
+	}
+}
+
+// testWorkspace is a workspace with one member of each role and a box in it
+type testWorkspace struct {
+	stores     *repository.Stores
+	qr         *QRService
+	workspaces *WorkspaceService
+	id         string
+}
+
+func newTestWorkspace(t *testing.T) *testWorkspace {
+	t.Helper()
+
+	stores := repository.NewMemoryStores()
+	w := &testWorkspace{
+		stores:     stores,
+		qr:         NewQRService("http://frontend", "http://backend", stores, nil),
+		workspaces: NewWorkspaceService(stores),
+	}
+
+	workspace, err := w.workspaces.CreateWorkspace("owner", &models.CreateWorkspaceRequest{Name: "Home"})
+	if err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	w.id = workspace.ID
+
+	for _, member := range []string{models.RoleViewer, models.RoleEditor} {
+		invitation, err := w.workspaces.CreateInvitation("owner", &models.CreateInvitationRequest{WorkspaceID: w.id, Role: member})
+		if err != nil {
+			t.Fatalf("invite %s: %v", member, err)
+		}
+		if _, err := w.workspaces.JoinWorkspace(member, nil, invitation.Code); err != nil {
+			t.Fatalf("join as %s: %v", member, err)
+		}
+	}
+
+	return w
+}
+
+func (w *testWorkspace) newBox(t *testing.T) *models.Box {
+	t.Helper()
+
+	created, err := w.qr.CreateBox("owner", &models.CreateBoxRequest{Name: "Winter clothes", WorkspaceID: w.id, Items: "scarf"})
+	if err != nil {
+		t.Fatalf("create box: %v", err)
+	}
+	return created.Box
+}
+
+func TestAuthorizationMatrix(t *testing.T) {
+	// The users are named after their role; "outsider" is not a member
+	users := []string{models.RoleOwner, models.RoleEditor, models.RoleViewer, "outsider"}
+
+	tests := []struct {
+		name string
+		do   func(w *testWorkspace, box *models.Box, userID string) error
+		// want maps each user to the error expected, "" meaning allowed
+		want map[string]string
+	}{
+		{
+			name: "view box",
+			do: func(w *testWorkspace, box *models.Box, userID string) error {
+				_, err := w.qr.GetUserBox(userID, box.ID)
+				return err
+			},
+			want: map[string]string{"owner": "", "editor": "", "viewer": "", "outsider": "forbidden"},
+		},
+		{
+			name: "update box",
+			do: func(w *testWorkspace, box *models.Box, userID string) error {
+				_, err := w.qr.UpdateBox(userID, box.ID, &models.UpdateBoxRequest{Description: "Scarves"})
+				return err
+			},
+			want: map[string]string{"owner": "", "editor": "", "viewer": "forbidden", "outsider": "forbidden"},
+		},
+		{
+			name: "add item",
+			do: func(w *testWorkspace, box *models.Box, userID string) error {
+				_, err := w.qr.AddItemToBox(userID, box.ShortCode, "gloves")
+				return err
+			},
+			want: map[string]string{"owner": "", "editor": "", "viewer": "forbidden", "outsider": "forbidden"},
+		},
+		{
+			name: "delete box",
+			do: func(w *testWorkspace, box *models.Box, userID string) error {
+				return w.qr.DeleteBox(userID, box.ID)
+			},
+			want: map[string]string{"owner": "", "editor": "forbidden", "viewer": "forbidden", "outsider": "forbidden"},
+		},
+		{
+			name: "create box in workspace",
+			do: func(w *testWorkspace, box *models.Box, userID string) error {
+				_, err := w.qr.CreateBox(userID, &models.CreateBoxRequest{Name: "Books", WorkspaceID: w.id})
+				return err
+			},
+			want: map[string]string{"owner": "", "editor": "", "viewer": "forbidden", "outsider": "workspace not found"},
+		},
+		{
+			name: "pack into box",
+			do: func(w *testWorkspace, box *models.Box, userID string) error {
+				_, err := w.qr.CreateBox(userID, &models.CreateBoxRequest{Name: "Hats", ParentBoxID: box.ID})
+				return err
+			},
+			want: map[string]string{"owner": "", "editor": "", "viewer": "forbidden", "outsider": "parent box not found"},
+		},
+		{
+			name: "create tag",
+			do: func(w *testWorkspace, box *models.Box, userID string) error {
+				_, err := NewTagService(w.stores).CreateTag(userID, &models.CreateTagRequest{Name: "Fragile " + userID, WorkspaceID: w.id})
+				return err
+			},
+			want: map[string]string{"owner": "", "editor": "", "viewer": "forbidden", "outsider": "workspace not found"},
+		},
+		{
+			name: "invite member",
+			do: func(w *testWorkspace, box *models.Box, userID string) error {
+				_, err := w.workspaces.CreateInvitation(userID, &models.CreateInvitationRequest{WorkspaceID: w.id})
+				return err
+			},
+			want: map[string]string{"owner": "", "editor": "forbidden", "viewer": "forbidden", "outsider": "workspace not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, userID := range users {
+			t.Run(tt.name+"/"+userID, func(t *testing.T) {
+				w := newTestWorkspace(t)
+				err := tt.do(w, w.newBox(t), userID)
+
+				want := tt.want[userID]
+				switch {
+				case want == "" && err != nil:
+					t.Errorf("got error %q, want it allowed", err)
+				case want != "" && (err == nil || err.Error() != want):
+					t.Errorf("got error %v, want %q", err, want)
+				}
+			})
+		}
+	}
+}
+
+func TestRefusedUpdateChangesNothing(t *testing.T) {
+	w := newTestWorkspace(t)
+	box := w.newBox(t)
+
+	if _, err := w.qr.UpdateBox(models.RoleViewer, box.ID, &models.UpdateBoxRequest{Name: "Renamed", Items: "nothing"}); err == nil {
+		t.Fatal("viewer was allowed to update the box")
+	}
+
+	stored, err := w.qr.GetUserBox("owner", box.ID)
+	if err != nil {
+		t.Fatalf("GetUserBox: %v", err)
+	}
+	if stored.Name != box.Name || len(stored.Items) != 1 || stored.Items[0].Name != "scarf" {
+		t.Errorf("box changed to %q with items %v", stored.Name, stored.Items)
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// defaultBatchNamePrefix names the boxes of a batch given only a count
+const defaultBatchNamePrefix = "Box"
+
+// CreateBoxes creates a batch of boxes in one transaction, so either all of
+// them are created or none is. Every box is checked before anything is
+// written, and the rooms the boxes need are created in the same transaction.
+// Their QR codes are generated in parallel.
+func (s *QRService) CreateBoxes(userID string, request *models.CreateBoxesRequest) (*models.CreateBoxesResponse, error) {
+	definitions, err := batchDefinitions(request)
+	if err != nil {
+		return nil, err
+	}
+
+	boxes := make([]*models.Box, len(definitions))
+	taken := make(map[string]bool, len(definitions))
+	rooms := newPendingRooms(s.locationRepo, userID)
+	for i := range definitions {
+		box, err := s.newBox(userID, &definitions[i], taken, rooms)
+		if err != nil {
+			return nil, err
+		}
+		taken[box.ShortCode] = true
+		boxes[i] = box
+	}
+
+	if err := s.generateQRCodes(boxes); err != nil {
+		return nil, err
+	}
+
+	if err := s.boxRepo.CreateBoxes(boxes, rooms.created); err != nil {
+		return nil, fmt.Errorf("failed to save boxes to database: %w", err)
+	}
+
+	return &models.CreateBoxesResponse{
+		Boxes:   boxes,
+		Count:   len(boxes),
+		Message: fmt.Sprintf("Created %d boxes with QR codes", len(boxes)),
+	}, nil
+}
+
+// batchDefinitions returns the box requests a batch stands for, expanding a
+// count into numbered empty boxes
+func batchDefinitions(request *models.CreateBoxesRequest) ([]models.CreateBoxRequest, error) {
+	if (len(request.Boxes) > 0) == (request.Count > 0) {
+		return nil, fmt.Errorf("invalid box batch")
+	}
+
+	if len(request.Boxes) > 0 {
+		return request.Boxes, nil
+	}
+
+	prefix := strings.TrimSpace(request.NamePrefix)
+	if prefix == "" {
+		prefix = defaultBatchNamePrefix
+	}
+
+	definitions := make([]models.CreateBoxRequest, request.Count)
+	for i := range definitions {
+		definitions[i] = models.CreateBoxRequest{
+			WorkspaceID: request.WorkspaceID,
+			Name:        fmt.Sprintf("%s %d", prefix, i+1),
+			Room:        request.Room,
+			LocationID:  request.LocationID,
+			TagIDs:      request.TagIDs,
+		}
+	}
+	return definitions, nil
+}
+
+// generateQRCodes fills in the QR code of each box, spreading the work over
+// the available CPUs
+func (s *QRService) generateQRCodes(boxes []*models.Box) error {
+	errs := make([]error, len(boxes))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), len(boxes)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				boxes[i].QRCodeURL, boxes[i].QRCode, errs[i] = s.boxQRCode(boxes[i].ShortCode)
+			}
+		}()
+	}
+
+	for i := range boxes {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+)
+
+// countingLocations counts how often the user's locations are read
+type countingLocations struct {
+	repository.LocationStore
+	reads int
+}
+
+func (c *countingLocations) GetLocationsByUserID(userID string) ([]*models.Location, error) {
+	c.reads++
+	return c.LocationStore.GetLocationsByUserID(userID)
+}
+
+func TestCreateBoxesResolvesEachRoomOnce(t *testing.T) {
+	w := newTestWorkspace(t)
+	garage, err := NewLocationService(w.stores).CreateLocation("owner", &models.CreateLocationRequest{WorkspaceID: w.id, Name: "Garage"})
+	if err != nil {
+		t.Fatalf("CreateLocation: %v", err)
+	}
+
+	locations := &countingLocations{LocationStore: w.stores.Locations}
+	stores := *w.stores
+	stores.Locations = locations
+	qr := NewQRService("http://frontend", "http://backend", &stores, nil)
+
+	definitions := []models.CreateBoxRequest{
+		{Name: "Tools", Room: "Garage"},
+		{Name: "Bikes", Room: " garage "},
+		{Name: "Toys", Room: "Loft"},
+		{Name: "Books", Room: "LOFT"},
+		{Name: "Lamps"},
+	}
+	response, err := qr.CreateBoxes("owner", &models.CreateBoxesRequest{Boxes: definitions, WorkspaceID: w.id})
+	if err != nil {
+		t.Fatalf("CreateBoxes: %v", err)
+	}
+	if locations.reads != 1 {
+		t.Errorf("locations read %d times, want once", locations.reads)
+	}
+
+	boxes := response.Boxes
+	if boxes[0].LocationID != garage.ID || boxes[1].LocationID != garage.ID {
+		t.Errorf("garage boxes are in %s and %s, want %s", boxes[0].LocationID, boxes[1].LocationID, garage.ID)
+	}
+	if boxes[2].LocationID == "" || boxes[3].LocationID != boxes[2].LocationID || boxes[2].Room != "Loft" {
+		t.Errorf("loft boxes are in %s (%q) and %s, want one new Loft", boxes[2].LocationID, boxes[2].Room, boxes[3].LocationID)
+	}
+	if boxes[4].LocationID != "" {
+		t.Errorf("box without a room is in %s", boxes[4].LocationID)
+	}
+
+	all, err := w.stores.Locations.GetLocationsByUserID("owner")
+	if err != nil {
+		t.Fatalf("GetLocationsByUserID: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("workspace has %d locations, want Garage and Loft", len(all))
+	}
+}
+
+func TestFailedBatchCreatesNoRooms(t *testing.T) {
+	tests := []struct {
+		name string
+		last models.CreateBoxRequest
+	}{
+		{"unknown tag", models.CreateBoxRequest{Name: "Tagged", TagIDs: []string{"missing"}}},
+		{"unknown location", models.CreateBoxRequest{Name: "Placed", LocationID: "missing"}},
+		{"bad visibility", models.CreateBoxRequest{Name: "Hidden", Visibility: "secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorkspace(t)
+			definitions := []models.CreateBoxRequest{{Name: "Tools", Room: "Garage"}, {Name: "Toys", Room: "Loft"}, tt.last}
+
+			if _, err := w.qr.CreateBoxes("owner", &models.CreateBoxesRequest{Boxes: definitions, WorkspaceID: w.id}); err == nil {
+				t.Fatal("CreateBoxes succeeded")
+			}
+
+			locations, err := w.stores.Locations.GetLocationsByUserID("owner")
+			if err != nil {
+				t.Fatalf("GetLocationsByUserID: %v", err)
+			}
+			if len(locations) != 0 {
+				t.Errorf("failed batch left %d locations", len(locations))
+			}
+
+			boxes, err := w.stores.Boxes.GetByUserID("owner")
+			if err != nil {
+				t.Fatalf("GetByUserID: %v", err)
+			}
+			if len(boxes) != 0 {
+				t.Errorf("failed batch left %d boxes", len(boxes))
+			}
+		})
+	}
+}
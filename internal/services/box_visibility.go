@@ -0,0 +1,268 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// pinPattern is the form of a box PIN: 4 to 12 digits
+var pinPattern = regexp.MustCompile(`^[0-9]{4,12}$`)
+
+// PIN guesses are limited per box and client. Past pinFreeFailures wrong
+// guesses on a box, guesses on it must be spaced out, from pinBaseDelay
+// doubling up to pinMaxDelay after the latest wrong one, so that spreading
+// guesses over many addresses doesn't help much either. Spacing guesses out
+// rather than refusing them for the whole window means strangers can't lock
+// the owner out.
+const (
+	pinAttemptsPerClient = 5
+	pinAttemptWindow     = 15 * time.Minute
+	pinFreeFailures      = 5
+	pinBaseDelay         = 500 * time.Millisecond
+	pinMaxDelay          = 8 * time.Second
+)
+
+// validVisibility reports whether visibility is one of the box visibilities
+func validVisibility(visibility string) bool {
+	switch visibility {
+	case models.VisibilityPublic, models.VisibilityNameOnly, models.VisibilityPIN, models.VisibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// setBoxVisibility applies a requested visibility and PIN to a box. An empty
+// visibility or pin keeps the current one. A pin box needs a PIN; boxes with
+// any other visibility drop theirs.
+func setBoxVisibility(box *models.Box, visibility, pin string) error {
+	visibility = strings.TrimSpace(visibility)
+	if visibility != "" {
+		if !validVisibility(visibility) {
+			return fmt.Errorf("invalid visibility")
+		}
+		box.Visibility = visibility
+	}
+	if box.Visibility == "" {
+		box.Visibility = models.VisibilityPublic
+	}
+
+	pin = strings.TrimSpace(pin)
+	if pin != "" {
+		if box.Visibility != models.VisibilityPIN {
+			return fmt.Errorf("pin requires pin visibility")
+		}
+		if !pinPattern.MatchString(pin) {
+			return fmt.Errorf("invalid pin")
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("failed to hash pin: %w", err)
+		}
+		box.PINHash = string(hash)
+	}
+
+	if box.Visibility != models.VisibilityPIN {
+		box.PINHash = ""
+	} else if box.PINHash == "" {
+		return fmt.Errorf("pin required")
+	}
+
+	return nil
+}
+
+// GetPublicBox returns a box, by its ID or short code, for the public scan
+// page. unlocked reports whether all of its details may be shown; name-only
+// and pin boxes show just their name. Private boxes are reported as not found.
+func (s *QRService) GetPublicBox(boxID string) (box *models.Box, unlocked bool, err error) {
+	box, err = lookupBox(s.boxRepo, boxID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch box.Visibility {
+	case models.VisibilityPrivate:
+		return nil, false, fmt.Errorf("box not found")
+	case models.VisibilityNameOnly, models.VisibilityPIN:
+		return box, false, nil
+	}
+
+	return box, true, nil
+}
+
+// UnlockPublicBox returns a pin box for the public scan page when pin is its
+// PIN. client identifies the caller, such as their IP address, for limiting
+// guesses. A guess that comes too soon is refused with "too many attempts"
+// and retryAfter set to how long is left to wait.
+func (s *QRService) UnlockPublicBox(boxID, pin, client string) (box *models.Box, retryAfter time.Duration, err error) {
+	box, err = lookupBox(s.boxRepo, boxID)
+	if err != nil || box.Visibility == models.VisibilityPrivate {
+		return nil, 0, fmt.Errorf("box not found")
+	}
+
+	if box.Visibility != models.VisibilityPIN {
+		return nil, 0, fmt.Errorf("box is not pin protected")
+	}
+
+	// Each guess is counted, for the client and as a failure on the box,
+	// before the PIN is checked, so parallel requests can't slip past the
+	// limits while bcrypt runs
+	now := time.Now()
+	clientKey := box.ID + " " + client
+	if wait := s.clientPINAttempts.take(clientKey, now); wait > 0 {
+		return nil, wait, fmt.Errorf("too many attempts")
+	}
+
+	if wait := s.boxPINFailures.takeSpaced(box.ID, now, pinDelay); wait > 0 {
+		// Being told to wait doesn't use up one of the client's guesses
+		s.clientPINAttempts.drop(clientKey, now)
+		return nil, wait, fmt.Errorf("too many attempts")
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(box.PINHash), []byte(strings.TrimSpace(pin))) != nil {
+		return nil, 0, fmt.Errorf("incorrect pin")
+	}
+
+	// Only wrong guesses count towards the limits. The box keeps the other
+	// failures in its window.
+	s.boxPINFailures.drop(box.ID, now)
+	s.clientPINAttempts.reset(clientKey)
+
+	return box, 0, nil
+}
+
+// pinDelay is how long the next guess on a box must wait after the latest
+// one, given the number of recent wrong guesses
+func pinDelay(failures int) time.Duration {
+	if failures < pinFreeFailures {
+		return 0
+	}
+
+	delay := pinBaseDelay
+	for i := pinFreeFailures; i < failures && delay < pinMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, pinMaxDelay)
+}
+
+// GetPublicBoxContents is GetBoxContents for the public scan page. Private
+// inner boxes are left out and name-only or pin inner boxes show only their
+// name, each of them hiding whatever is packed inside.
+func (s *QRService) GetPublicBoxContents(box *models.Box) (*models.BoxContents, error) {
+	return s.boxContents(box, true)
+}
+
+// attemptLimiter counts attempts per key within a sliding window
+type attemptLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	attempts  map[string][]time.Time
+	lastSweep time.Time
+}
+
+func newAttemptLimiter(limit int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		limit:    limit,
+		window:   window,
+		attempts: make(map[string][]time.Time),
+	}
+}
+
+// take records an attempt at now and returns zero. When key has used up its
+// attempts for the window it records nothing and returns how long until its
+// oldest attempt leaves the window.
+func (l *attemptLimiter) take(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > l.window {
+		l.sweep(now)
+	}
+
+	times := recent(l.attempts[key], now.Add(-l.window))
+	l.attempts[key] = times
+	if len(times) >= l.limit {
+		return times[0].Add(l.window).Sub(now)
+	}
+
+	l.attempts[key] = append(l.attempts[key], now)
+	return 0
+}
+
+// takeSpaced records an attempt at now and returns zero, unless it comes
+// sooner than delay, given the number of attempts in the window, after the
+// latest one. Then it records nothing and returns how long is left to wait.
+func (l *attemptLimiter) takeSpaced(key string, now time.Time, delay func(int) time.Duration) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > l.window {
+		l.sweep(now)
+	}
+
+	times := recent(l.attempts[key], now.Add(-l.window))
+	l.attempts[key] = times
+	if len(times) > 0 {
+		if wait := times[len(times)-1].Add(delay(len(times))).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	l.attempts[key] = append(l.attempts[key], now)
+	return 0
+}
+
+// drop forgets the attempt key made at the given time, one that turned out
+// not to count
+func (l *attemptLimiter) drop(key string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	times := l.attempts[key]
+	for i := len(times) - 1; i >= 0; i-- {
+		if times[i].Equal(at) {
+			l.attempts[key] = append(times[:i], times[i+1:]...)
+			return
+		}
+	}
+}
+
+// reset forgets every attempt for key
+func (l *attemptLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
+
+// sweep drops keys whose attempts have all left the window, so the map
+// doesn't keep growing
+func (l *attemptLimiter) sweep(now time.Time) {
+	cutoff := now.Add(-l.window)
+	for key, times := range l.attempts {
+		if kept := recent(times, cutoff); len(kept) > 0 {
+			l.attempts[key] = kept
+		} else {
+			delete(l.attempts, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// recent returns the times after cutoff, reusing the slice
+func recent(times []time.Time, cutoff time.Time) []time.Time {
+	kept := times[:0]
+	for _, t := range times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
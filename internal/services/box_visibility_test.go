@@ -0,0 +1,138 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+func TestPINDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{pinFreeFailures - 1, 0},
+		{pinFreeFailures, pinBaseDelay},
+		{pinFreeFailures + 1, 2 * pinBaseDelay},
+		{pinFreeFailures + 3, 8 * pinBaseDelay},
+		{pinFreeFailures + 50, pinMaxDelay},
+		{1 << 30, pinMaxDelay},
+	}
+
+	for _, tt := range tests {
+		if got := pinDelay(tt.failures); got != tt.want {
+			t.Errorf("pinDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestAttemptLimiterTake(t *testing.T) {
+	limiter := newAttemptLimiter(2, time.Minute)
+	start := time.Now()
+
+	tests := []struct {
+		at   time.Duration
+		want time.Duration
+	}{
+		{0, 0},
+		{20 * time.Second, 0},
+		{30 * time.Second, 30 * time.Second},
+		{61 * time.Second, 0},
+		{70 * time.Second, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := limiter.take("client", start.Add(tt.at)); got != tt.want {
+			t.Errorf("take after %v waits %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestAttemptLimiterTakeSpaced(t *testing.T) {
+	limiter := newAttemptLimiter(0, time.Minute)
+	start := time.Now()
+	// Each attempt in the window adds ten seconds between attempts
+	delay := func(attempts int) time.Duration { return time.Duration(attempts) * 10 * time.Second }
+
+	tests := []struct {
+		at   time.Duration
+		want time.Duration
+	}{
+		{0, 0},
+		{5 * time.Second, 5 * time.Second},
+		{10 * time.Second, 0},
+		{25 * time.Second, 5 * time.Second},
+		{30 * time.Second, 0},
+		// Everything has left the window
+		{95 * time.Second, 0},
+	}
+
+	for _, tt := range tests {
+		if got := limiter.takeSpaced("box", start.Add(tt.at), delay); got != tt.want {
+			t.Errorf("takeSpaced after %v waits %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	// A dropped attempt no longer counts towards the spacing
+	limiter.drop("box", start.Add(95*time.Second))
+	if got := limiter.takeSpaced("box", start.Add(96*time.Second), delay); got != 0 {
+		t.Errorf("takeSpaced after a dropped attempt waits %v, want 0", got)
+	}
+}
+
+func TestWrongPINsSlowDownButNeverLockOut(t *testing.T) {
+	w := newTestWorkspace(t)
+	box := w.newBox(t)
+	if _, err := w.qr.UpdateBox("owner", box.ID, &models.UpdateBoxRequest{Visibility: models.VisibilityPIN, PIN: "2468"}); err != nil {
+		t.Fatalf("UpdateBox: %v", err)
+	}
+
+	// Strangers from many addresses use up the box's free guesses
+	failures := pinFreeFailures + 2
+	noDelay := func(int) time.Duration { return 0 }
+	guessed := time.Now()
+	for i := 0; i < failures; i++ {
+		w.qr.boxPINFailures.takeSpaced(box.ID, guessed.Add(time.Duration(i-failures)*time.Millisecond), noDelay)
+	}
+
+	// Right after them even the owner is told to wait, without the request
+	// being held
+	started := time.Now()
+	_, retryAfter, err := w.qr.UnlockPublicBox(box.ID, "2468", "owner-address")
+	if err == nil || err.Error() != "too many attempts" {
+		t.Fatalf("guess right after the strangers: error %v, want \"too many attempts\"", err)
+	}
+	if retryAfter <= 0 || retryAfter > pinDelay(failures) {
+		t.Errorf("retry after %v, want up to %v", retryAfter, pinDelay(failures))
+	}
+	if waited := time.Since(started); waited > pinBaseDelay {
+		t.Errorf("refused guess took %v", waited)
+	}
+
+	// Once the wait is over the owner gets in, and the strangers' failures
+	// still space out the guesses after
+	w.qr.boxPINFailures = newAttemptLimiter(0, pinAttemptWindow)
+	for i := 0; i < failures; i++ {
+		w.qr.boxPINFailures.takeSpaced(box.ID, guessed.Add(-pinDelay(failures)-time.Duration(failures-i)*time.Millisecond), noDelay)
+	}
+	if _, _, err := w.qr.UnlockPublicBox(box.ID, "2468", "owner-address"); err != nil {
+		t.Fatalf("owner's unlock after the wait failed: %v", err)
+	}
+	if wait := w.qr.boxPINFailures.takeSpaced(box.ID, time.Now(), pinDelay); wait != 0 {
+		t.Errorf("the owner's unlock counted as a failure: next guess waits %v", wait)
+	}
+
+	// The client limit still refuses one address that keeps guessing
+	w.qr.boxPINFailures = newAttemptLimiter(0, pinAttemptWindow)
+	for i := 0; i <= pinAttemptsPerClient; i++ {
+		_, retryAfter, err = w.qr.UnlockPublicBox(box.ID, "0000", "stranger")
+	}
+	if err == nil || err.Error() != "too many attempts" {
+		t.Errorf("guess past the client limit: error %v, want \"too many attempts\"", err)
+	}
+	if retryAfter <= 0 || retryAfter > pinAttemptWindow {
+		t.Errorf("retry after %v past the client limit, want up to %v", retryAfter, pinAttemptWindow)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+)
+
+type ItemService struct {
+	boxRepo       repository.BoxStore
+	itemRepo      repository.ItemStore
+	workspaceRepo repository.WorkspaceStore
+}
+
+func NewItemService(stores *repository.Stores) *ItemService {
+	return &ItemService{
+		boxRepo:       stores.Boxes,
+		itemRepo:      stores.Items,
+		workspaceRepo: stores.Workspaces,
+	}
+}
+
+// newItem builds an item with a fresh ID for the given box
+func newItem(boxID, name string, quantity int, unit, notes string) *models.Item {
+	return &models.Item{
+		ID:        uuid.New().String(),
+		BoxID:     boxID,
+		Name:      name,
+		Quantity:  quantity,
+		Unit:      unit,
+		Notes:     notes,
+		CreatedAt: time.Now(),
+	}
+}
+
+// CreateItem adds an item to the end of one of the user's boxes
+func (s *ItemService) CreateItem(userID string, request *models.CreateItemRequest) (*models.Item, error) {
+	box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, request.BoxID, actionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := request.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	item := newItem(box.ID, strings.TrimSpace(request.Name), quantity, strings.TrimSpace(request.Unit), strings.TrimSpace(request.Notes))
+	if err := s.itemRepo.CreateItem(item); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// GetItem returns one of the user's items
+func (s *ItemService) GetItem(userID string, itemID string) (*models.Item, error) {
+	return s.authorizeItem(userID, itemID, actionView)
+}
+
+// UpdateItem renames an item or changes its quantity, unit or notes
+func (s *ItemService) UpdateItem(userID string, itemID string, request *models.UpdateItemRequest) (*models.Item, error) {
+	item, err := s.authorizeItem(userID, itemID, actionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Name != nil {
+		item.Name = strings.TrimSpace(*request.Name)
+	}
+
+	if request.Quantity != nil {
+		item.Quantity = *request.Quantity
+	}
+
+	if request.Unit != nil {
+		item.Unit = strings.TrimSpace(*request.Unit)
+	}
+
+	if request.Notes != nil {
+		item.Notes = strings.TrimSpace(*request.Notes)
+	}
+
+	if err := s.itemRepo.UpdateItem(item); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// MoveItem moves an item into another of the user's boxes
+func (s *ItemService) MoveItem(userID string, request *models.MoveItemRequest) (*models.Item, error) {
+	item, err := s.authorizeItem(userID, request.ItemID, actionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, request.BoxID, actionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	item.BoxID = box.ID
+	if err := s.itemRepo.UpdateItem(item); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// AdjustItemQuantity increments or decrements the quantity of one of the user's items
+func (s *ItemService) AdjustItemQuantity(userID string, request *models.AdjustItemQuantityRequest) (*models.Item, error) {
+	if _, err := s.authorizeItem(userID, request.ItemID, actionEdit); err != nil {
+		return nil, err
+	}
+
+	return s.itemRepo.AdjustItemQuantity(request.ItemID, request.Delta)
+}
+
+// DeleteItem removes one of the user's items
+func (s *ItemService) DeleteItem(userID string, itemID string) error {
+	if _, err := s.authorizeItem(userID, itemID, actionEdit); err != nil {
+		return err
+	}
+
+	return s.itemRepo.DeleteItem(itemID)
+}
+
+// authorizeItem loads an item and checks that the user may perform act on its box
+func (s *ItemService) authorizeItem(userID string, itemID string, act action) (*models.Item, error) {
+	item, err := s.itemRepo.GetItemByID(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, item.BoxID, act); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// itemUnits are the units parseItemLine recognises after a leading quantity
+var itemUnits = map[string]bool{
+	"mm": true, "cm": true, "m": true, "km": true,
+	"g": true, "kg": true, "ml": true, "l": true,
+	"pcs": true, "pc": true, "pack": true, "packs": true,
+	"pair": true, "pairs": true, "roll": true, "rolls": true,
+	"set": true, "sets": true, "box": true, "boxes": true,
+}
+
+var (
+	// "3x screws", "3 x screws", "12 × AA batteries"
+	multiplierPattern = regexp.MustCompile(`^(\d+)\s*[xX×]\s+(\S.*)$`)
+	// "5 screws", "3 m of cable", "2 kg flour"
+	leadingCountPattern = regexp.MustCompile(`^(\d+)\s+(\S+)\s*(.*)$`)
+)
+
+// parseItemLine splits a free-text item line into quantity, unit and name.
+// Lines without a leading quantity, or with one above MaxItemQuantity, count
+// as a single item.
+func parseItemLine(line string) (name string, quantity int, unit string) {
+	line = strings.TrimSpace(line)
+
+	if match := multiplierPattern.FindStringSubmatch(line); match != nil {
+		if quantity, err := strconv.Atoi(match[1]); err == nil && quantity > 0 && quantity <= models.MaxItemQuantity {
+			return strings.TrimSpace(match[2]), quantity, ""
+		}
+	}
+
+	if match := leadingCountPattern.FindStringSubmatch(line); match != nil {
+		quantity, err := strconv.Atoi(match[1])
+		if err != nil || quantity <= 0 || quantity > models.MaxItemQuantity {
+			return line, 1, ""
+		}
+
+		// A recognised unit must be followed by a name, optionally after "of"
+		word := strings.TrimSuffix(match[2], ".")
+		rest := strings.TrimSpace(match[3])
+		if itemUnits[strings.ToLower(word)] && rest != "" {
+			if after, ok := strings.CutPrefix(rest, "of "); ok {
+				rest = strings.TrimSpace(after)
+			}
+			if rest != "" {
+				return rest, quantity, strings.ToLower(word)
+			}
+		}
+
+		return strings.TrimSpace(match[2] + " " + match[3]), quantity, ""
+	}
+
+	return line, 1, ""
+}
@@ -0,0 +1,38 @@
+package services
+
+import "testing"
+
+func TestParseItemLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		name     string
+		quantity int
+		unit     string
+	}{
+		{"screwdriver", "screwdriver", 1, ""},
+		{"  screwdriver  ", "screwdriver", 1, ""},
+		{"5 screws", "screws", 5, ""},
+		{"3x screws", "screws", 3, ""},
+		{"3 x screws", "screws", 3, ""},
+		{"12 × AA batteries", "AA batteries", 12, ""},
+		{"3 m of cable", "cable", 3, "m"},
+		{"2 kg flour", "flour", 2, "kg"},
+		{"2 KG flour", "flour", 2, "kg"},
+		{"4 pcs. sandpaper", "sandpaper", 4, "pcs"},
+		{"3 pairs", "pairs", 3, ""},
+		{"0 screws", "0 screws", 1, ""},
+		{"0x screws", "0x screws", 1, ""},
+		{"99999999999999999999 screws", "99999999999999999999 screws", 1, ""},
+		{"1000000 screws", "screws", 1000000, ""},
+		{"1000001 screws", "1000001 screws", 1, ""},
+		{"1984 poster", "poster", 1984, ""},
+	}
+
+	for _, tt := range tests {
+		name, quantity, unit := parseItemLine(tt.line)
+		if name != tt.name || quantity != tt.quantity || unit != tt.unit {
+			t.Errorf("parseItemLine(%q) = %q, %d, %q; want %q, %d, %q",
+				tt.line, name, quantity, unit, tt.name, tt.quantity, tt.unit)
+		}
+	}
+}
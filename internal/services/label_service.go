@@ -0,0 +1,281 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+// DefaultLabelTemplate is used when a label sheet request doesn't name one
+const DefaultLabelTemplate = "avery5160"
+
+// labelTemplates lists the supported label stock, keyed by template name
+var labelTemplates = map[string]models.LabelTemplate{
+	// Avery 5160: US Letter, 30 labels of 2.625" x 1"
+	"avery5160": {
+		Name:         "avery5160",
+		PageSize:     "Letter",
+		Columns:      3,
+		Rows:         10,
+		LabelWidth:   66.675,
+		LabelHeight:  25.4,
+		MarginTop:    12.7,
+		MarginLeft:   4.7625,
+		PitchX:       69.85,
+		PitchY:       25.4,
+		ItemsPerCard: 1,
+	},
+	// Avery L7163: A4, 14 labels of 99.1mm x 38.1mm
+	"l7163": {
+		Name:         "l7163",
+		PageSize:     "A4",
+		Columns:      2,
+		Rows:         7,
+		LabelWidth:   99.1,
+		LabelHeight:  38.1,
+		MarginTop:    15.15,
+		MarginLeft:   4.65,
+		PitchX:       101.6,
+		PitchY:       38.1,
+		ItemsPerCard: 3,
+	},
+	// A4 2x4: 8 labels of 99.1mm x 67.7mm (Avery L7165)
+	"a4-2x4": {
+		Name:         "a4-2x4",
+		PageSize:     "A4",
+		Columns:      2,
+		Rows:         4,
+		LabelWidth:   99.1,
+		LabelHeight:  67.7,
+		MarginTop:    13.1,
+		MarginLeft:   4.65,
+		PitchX:       101.6,
+		PitchY:       67.7,
+		ItemsPerCard: 6,
+	},
+}
+
+// MaxLabelBoxes caps how many boxes can be printed in a single request
+const MaxLabelBoxes = 500
+
+// labelPadding is the blank space kept inside each label edge, in millimetres
+const labelPadding = 2.0
+
+type LabelService struct {
+	qrService *QRService
+}
+
+func NewLabelService(qrService *QRService) *LabelService {
+	return &LabelService{
+		qrService: qrService,
+	}
+}
+
+// GetLabelTemplate looks up a label template by name, falling back to the default
+func GetLabelTemplate(name string) (models.LabelTemplate, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultLabelTemplate
+	}
+
+	template, ok := labelTemplates[name]
+	if !ok {
+		return models.LabelTemplate{}, fmt.Errorf("unknown label template")
+	}
+
+	return template, nil
+}
+
+// GenerateLabelSheet renders a PDF with one QR label per selected box
+func (s *LabelService) GenerateLabelSheet(userID string, request *models.LabelSheetRequest) ([]byte, error) {
+	template, err := GetLabelTemplate(request.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	boxes, err := s.selectBoxes(userID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(boxes) == 0 {
+		return nil, fmt.Errorf("no boxes to print")
+	}
+
+	if len(boxes) > MaxLabelBoxes {
+		return nil, fmt.Errorf("too many boxes")
+	}
+
+	return s.RenderLabels(template, boxes)
+}
+
+// RenderLabels lays out the given boxes on as many sheets of the template as needed
+func (s *LabelService) RenderLabels(template models.LabelTemplate, boxes []*models.Box) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", template.PageSize, "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+
+	// Core PDF fonts use cp1252, so translate UTF-8 box data before drawing it
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for i, box := range boxes {
+		slot := i % template.LabelsPerPage()
+		if slot == 0 {
+			pdf.AddPage()
+		}
+
+		x := template.MarginLeft + float64(slot%template.Columns)*template.PitchX
+		y := template.MarginTop + float64(slot/template.Columns)*template.PitchY
+
+		if err := s.drawLabel(pdf, translate, template, box, x, y); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render label sheet: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *LabelService) drawLabel(pdf *gofpdf.Fpdf, translate func(string) string, template models.LabelTemplate, box *models.Box, x, y float64) error {
+	qrPNG, err := s.qrService.GenerateQRCodePNG(box, 512)
+	if err != nil {
+		return fmt.Errorf("failed to generate QR code for box %s: %w", box.ID, err)
+	}
+
+	// The QR fills the label height on the left; text goes in the remaining space
+	qrSize := template.LabelHeight - 2*labelPadding
+	if maxSize := template.LabelWidth * 0.45; qrSize > maxSize {
+		qrSize = maxSize
+	}
+
+	imageName := "qr-" + box.ID
+	options := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader(imageName, options, bytes.NewReader(qrPNG))
+	pdf.ImageOptions(imageName, x+labelPadding, y+labelPadding, qrSize, qrSize, false, options, 0, "")
+
+	textX := x + 2*labelPadding + qrSize
+	textWidth := template.LabelWidth - qrSize - 3*labelPadding
+	bottom := y + template.LabelHeight - labelPadding
+
+	// Scale the type with the label so small stock stays legible
+	nameSize := 11.0
+	detailSize := 8.0
+	if template.LabelHeight < 30 {
+		nameSize = 9
+		detailSize = 6.5
+	}
+
+	lineY := y + labelPadding
+	writeLine := func(text string, style string, size float64) bool {
+		lineHeight := size * 0.45
+		if lineY+lineHeight > bottom {
+			return false
+		}
+		pdf.SetFont("Helvetica", style, size)
+		pdf.SetXY(textX, lineY)
+		pdf.CellFormat(textWidth, lineHeight, fitText(pdf, translate, text, textWidth), "", 0, "L", false, 0, "")
+		lineY += lineHeight
+		return true
+	}
+
+	writeLine(box.Name, "B", nameSize)
+
+	if box.Room != "" {
+		writeLine(box.Room, "I", detailSize)
+	}
+
+	for i, item := range box.Items {
+		if i == template.ItemsPerCard {
+			break
+		}
+		line := "- " + item.Name
+		if item.Unit != "" {
+			line = fmt.Sprintf("- %d %s %s", item.Quantity, item.Unit, item.Name)
+		} else if item.Quantity > 1 {
+			line = fmt.Sprintf("- %d x %s", item.Quantity, item.Name)
+		}
+		if !writeLine(line, "", detailSize) {
+			break
+		}
+	}
+
+	if extra := len(box.Items) - template.ItemsPerCard; extra > 0 {
+		writeLine(fmt.Sprintf("+%d more", extra), "I", detailSize)
+	}
+
+	return nil
+}
+
+// fitText shortens text with an ellipsis until it fits in width using the current font
+func fitText(pdf *gofpdf.Fpdf, translate func(string) string, text string, width float64) string {
+	if encoded := translate(text); pdf.GetStringWidth(encoded) <= width {
+		return encoded
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := translate(strings.TrimSpace(string(runes)) + "...")
+		if pdf.GetStringWidth(candidate) <= width {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// selectBoxes resolves the boxes named in a label request, checking membership.
+// A location or room selects at most one box over MaxLabelBoxes, enough to tell
+// the request is too large without loading every box.
+func (s *LabelService) selectBoxes(userID string, request *models.LabelSheetRequest) ([]*models.Box, error) {
+	if len(request.BoxIDs) > 0 {
+		boxes := make([]*models.Box, 0, len(request.BoxIDs))
+		for _, boxID := range request.BoxIDs {
+			box, err := s.qrService.GetUserBox(userID, boxID)
+			if err != nil {
+				return nil, err
+			}
+
+			boxes = append(boxes, box)
+		}
+		return boxes, nil
+	}
+
+	// A location prints every box in it and in the locations below it
+	if request.LocationID != "" {
+		page, err := s.qrService.ListUserBoxes(userID, models.BoxListOptions{
+			LocationID: request.LocationID,
+			Sort:       models.BoxSortRoom,
+			Limit:      MaxLabelBoxes + 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return page.Boxes, nil
+	}
+
+	userBoxes, err := s.qrService.GetUserBoxes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	room := strings.TrimSpace(request.Room)
+	boxes := make([]*models.Box, 0)
+	for _, box := range userBoxes {
+		if strings.EqualFold(strings.TrimSpace(box.Room), room) {
+			boxes = append(boxes, box)
+		}
+		if len(boxes) > MaxLabelBoxes {
+			break
+		}
+	}
+
+	return boxes, nil
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+)
+
+type LocationService struct {
+	locationRepo  repository.LocationStore
+	workspaceRepo repository.WorkspaceStore
+}
+
+func NewLocationService(stores *repository.Stores) *LocationService {
+	return &LocationService{
+		locationRepo:  stores.Locations,
+		workspaceRepo: stores.Workspaces,
+	}
+}
+
+// CreateLocation adds a location, at the top level or inside one of the user's
+// locations. A location goes in its parent's workspace unless one is given.
+func (s *LocationService) CreateLocation(userID string, request *models.CreateLocationRequest) (*models.Location, error) {
+	name := strings.TrimSpace(request.Name)
+	parentID := strings.TrimSpace(request.ParentID)
+	workspaceID := strings.TrimSpace(request.WorkspaceID)
+
+	if parentID != "" {
+		parent, err := ownedLocation(s.locationRepo, s.workspaceRepo, userID, parentID, actionView)
+		if err != nil || (workspaceID != "" && parent.WorkspaceID != workspaceID) {
+			return nil, fmt.Errorf("parent location not found")
+		}
+		workspaceID = parent.WorkspaceID
+	}
+
+	workspaceID, err := resolveWorkspace(s.workspaceRepo, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return createLocation(s.locationRepo, userID, workspaceID, parentID, name)
+}
+
+// GetLocation returns one of the user's locations
+func (s *LocationService) GetLocation(userID string, locationID string) (*models.Location, error) {
+	return ownedLocation(s.locationRepo, s.workspaceRepo, userID, locationID, actionView)
+}
+
+// GetUserLocations returns the locations of all the user's workspaces ordered by breadcrumb
+func (s *LocationService) GetUserLocations(userID string) ([]*models.Location, error) {
+	return s.locationRepo.GetLocationsByUserID(userID)
+}
+
+// UpdateLocation renames a location or moves it under another parent
+func (s *LocationService) UpdateLocation(userID string, locationID string, request *models.UpdateLocationRequest) (*models.Location, error) {
+	location, err := ownedLocation(s.locationRepo, s.workspaceRepo, userID, locationID, actionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	// A location can only move within its own workspace
+	locations, err := s.locationRepo.GetLocationsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	locations = workspaceLocations(locations, location.WorkspaceID)
+
+	if request.Name != nil {
+		location.Name = strings.TrimSpace(*request.Name)
+	}
+
+	if request.ParentID != nil {
+		location.ParentID = strings.TrimSpace(*request.ParentID)
+		if err := checkLocationParent(locations, location.ID, location.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if siblingNameTaken(locations, location.ID, location.ParentID, location.Name) {
+		return nil, fmt.Errorf("location already exists")
+	}
+
+	if err := s.locationRepo.UpdateLocation(location); err != nil {
+		return nil, err
+	}
+
+	return s.locationRepo.GetLocationByID(location.ID)
+}
+
+// DeleteLocation removes one of the user's locations. Locations that still
+// contain other locations can't be deleted.
+func (s *LocationService) DeleteLocation(userID string, locationID string) error {
+	if _, err := ownedLocation(s.locationRepo, s.workspaceRepo, userID, locationID, actionEdit); err != nil {
+		return err
+	}
+
+	return s.locationRepo.DeleteLocation(locationID)
+}
+
+// ownedLocation loads a location and checks that the user may perform act in its workspace
+func ownedLocation(locationRepo repository.LocationStore, workspaceRepo repository.WorkspaceStore, userID string, locationID string, act action) (*models.Location, error) {
+	location, err := locationRepo.GetLocationByID(locationID)
+	if err != nil {
+		return nil, fmt.Errorf("location not found")
+	}
+
+	if err := authorizeReference(workspaceRepo, userID, location.WorkspaceID, act, "location not found"); err != nil {
+		return nil, err
+	}
+
+	return location, nil
+}
+
+// createLocation stores a new location in a workspace after checking its name
+// is free among its siblings
+func createLocation(locationRepo repository.LocationStore, userID, workspaceID, parentID, name string) (*models.Location, error) {
+	locations, err := locationRepo.GetLocationsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if siblingNameTaken(workspaceLocations(locations, workspaceID), "", parentID, name) {
+		return nil, fmt.Errorf("location already exists")
+	}
+
+	location := newLocation(userID, workspaceID, parentID, name)
+	if err := locationRepo.CreateLocation(location); err != nil {
+		return nil, err
+	}
+
+	return locationRepo.GetLocationByID(location.ID)
+}
+
+// newLocation builds a location that is yet to be stored
+func newLocation(userID, workspaceID, parentID, name string) *models.Location {
+	now := time.Now()
+	return &models.Location{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		ParentID:    parentID,
+		Name:        name,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// roomLocation finds the workspace's top-level location named like room,
+// ignoring case and surrounding spaces, and creates it if there is none. It
+// lets clients that still send a free-text room end up in the location tree.
+func roomLocation(locationRepo repository.LocationStore, userID, workspaceID, room string) (*models.Location, error) {
+	room = strings.TrimSpace(room)
+
+	locations, err := locationRepo.GetLocationsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if location := findRoom(locations, workspaceID, room); location != nil {
+		return location, nil
+	}
+
+	return createLocation(locationRepo, userID, workspaceID, "", room)
+}
+
+// findRoom returns the workspace's top-level location named like room, or nil
+func findRoom(locations []*models.Location, workspaceID, room string) *models.Location {
+	for _, location := range workspaceLocations(locations, workspaceID) {
+		if location.ParentID == "" && strings.EqualFold(location.Name, room) {
+			return location
+		}
+	}
+	return nil
+}
+
+// pendingRooms resolves the rooms of new boxes like roomLocation, but reads
+// the user's locations only once and doesn't store the rooms it has to create.
+// They are kept in created, to be stored in the same transaction as the boxes.
+type pendingRooms struct {
+	locationRepo repository.LocationStore
+	userID       string
+	locations    []*models.Location
+	loaded       bool
+	created      []*models.Location
+}
+
+func newPendingRooms(locationRepo repository.LocationStore, userID string) *pendingRooms {
+	return &pendingRooms{locationRepo: locationRepo, userID: userID}
+}
+
+// location returns the top-level location for room, planning it if it is new
+func (p *pendingRooms) location(workspaceID, room string) (*models.Location, error) {
+	room = strings.TrimSpace(room)
+
+	if !p.loaded {
+		locations, err := p.locationRepo.GetLocationsByUserID(p.userID)
+		if err != nil {
+			return nil, err
+		}
+		p.locations, p.loaded = locations, true
+	}
+
+	if location := findRoom(p.locations, workspaceID, room); location != nil {
+		return location, nil
+	}
+
+	location := newLocation(p.userID, workspaceID, "", room)
+	location.Path = models.LocationPath{{ID: location.ID, Name: location.Name}}
+	p.locations = append(p.locations, location)
+	p.created = append(p.created, location)
+	return location, nil
+}
+
+// workspaceLocations keeps the locations that belong to one workspace
+func workspaceLocations(locations []*models.Location, workspaceID string) []*models.Location {
+	kept := make([]*models.Location, 0, len(locations))
+	for _, location := range locations {
+		if location.WorkspaceID == workspaceID {
+			kept = append(kept, location)
+		}
+	}
+	return kept
+}
+
+// checkLocationParent rejects a parent that is missing or that would put the
+// location inside itself
+func checkLocationParent(locations []*models.Location, locationID, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+
+	byID := make(map[string]*models.Location, len(locations))
+	for _, location := range locations {
+		byID[location.ID] = location
+	}
+
+	if _, ok := byID[parentID]; !ok {
+		return fmt.Errorf("parent location not found")
+	}
+
+	seen := make(map[string]bool)
+	for current := byID[parentID]; current != nil && !seen[current.ID]; current = byID[current.ParentID] {
+		seen[current.ID] = true
+		if current.ID == locationID {
+			return fmt.Errorf("location cannot be moved inside itself")
+		}
+	}
+
+	return nil
+}
+
+// siblingNameTaken reports whether another location under parentID already uses name
+func siblingNameTaken(locations []*models.Location, locationID, parentID, name string) bool {
+	for _, location := range locations {
+		if location.ID != locationID && location.ParentID == parentID && strings.EqualFold(location.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/storage"
+)
+
+// photoFixture is a test workspace with a photo on one of its boxes
+type photoFixture struct {
+	*testWorkspace
+	shares *ShareService
+	photos *PhotoService
+	box    *models.Box
+	photo  *models.Photo
+}
+
+func newPhotoFixture(t *testing.T) *photoFixture {
+	t.Helper()
+
+	w := newTestWorkspace(t)
+	blobs, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("blob store: %v", err)
+	}
+	shares, err := NewShareService("http://frontend", w.stores, nil)
+	if err != nil {
+		t.Fatalf("share service: %v", err)
+	}
+
+	var data bytes.Buffer
+	if err := png.Encode(&data, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	f := &photoFixture{testWorkspace: w, shares: shares, photos: NewPhotoService(w.stores, blobs, shares), box: w.newBox(t)}
+	if f.photo, err = f.photos.UploadPhoto("owner", f.box.ID, "", data.Bytes()); err != nil {
+		t.Fatalf("upload photo: %v", err)
+	}
+	return f
+}
+
+// grant returns the grant GrantPhotos puts on the photo URLs of the box
+func (f *photoFixture) grant(t *testing.T, boxID string) string {
+	t.Helper()
+
+	box, err := f.qr.GetUserBox("owner", boxID)
+	if err != nil {
+		t.Fatalf("GetUserBox: %v", err)
+	}
+	box.Photos = []models.Photo{*f.photo}
+	f.photos.GrantPhotos(box)
+
+	link, err := url.Parse(box.Photos[0].URL)
+	if err != nil {
+		t.Fatalf("parse photo URL: %v", err)
+	}
+	return link.Query().Get("grant")
+}
+
+func TestPhotoAccess(t *testing.T) {
+	visibilities := []string{models.VisibilityPublic, models.VisibilityNameOnly, models.VisibilityPIN, models.VisibilityPrivate}
+
+	tests := []struct {
+		name   string
+		access func(t *testing.T, f *photoFixture) models.PhotoAccess
+		// allowed lists the visibilities under which the photo is served
+		allowed []string
+	}{
+		{
+			name:    "anonymous",
+			access:  func(t *testing.T, f *photoFixture) models.PhotoAccess { return models.PhotoAccess{} },
+			allowed: []string{models.VisibilityPublic},
+		},
+		{
+			name: "viewer",
+			access: func(t *testing.T, f *photoFixture) models.PhotoAccess {
+				return models.PhotoAccess{UserID: models.RoleViewer}
+			},
+			allowed: visibilities,
+		},
+		{
+			name:    "outsider",
+			access:  func(t *testing.T, f *photoFixture) models.PhotoAccess { return models.PhotoAccess{UserID: "outsider"} },
+			allowed: []string{models.VisibilityPublic},
+		},
+		{
+			name: "share link to the box",
+			access: func(t *testing.T, f *photoFixture) models.PhotoAccess {
+				share, err := f.shares.CreateShare("owner", &models.CreateShareRequest{Scope: models.ShareScopeBox, TargetID: f.box.ID, ExpiresAt: time.Now().Add(time.Hour)})
+				if err != nil {
+					t.Fatalf("CreateShare: %v", err)
+				}
+				return models.PhotoAccess{ShareToken: share.Token}
+			},
+			allowed: visibilities,
+		},
+		{
+			name: "revoked share link",
+			access: func(t *testing.T, f *photoFixture) models.PhotoAccess {
+				share, err := f.shares.CreateShare("owner", &models.CreateShareRequest{Scope: models.ShareScopeBox, TargetID: f.box.ID, ExpiresAt: time.Now().Add(time.Hour)})
+				if err != nil {
+					t.Fatalf("CreateShare: %v", err)
+				}
+				if err := f.shares.RevokeShare("owner", share.ID); err != nil {
+					t.Fatalf("RevokeShare: %v", err)
+				}
+				return models.PhotoAccess{ShareToken: share.Token}
+			},
+			allowed: []string{models.VisibilityPublic},
+		},
+		{
+			name: "share link to another box",
+			access: func(t *testing.T, f *photoFixture) models.PhotoAccess {
+				other := f.newBox(t)
+				share, err := f.shares.CreateShare("owner", &models.CreateShareRequest{Scope: models.ShareScopeBox, TargetID: other.ID, ExpiresAt: time.Now().Add(time.Hour)})
+				if err != nil {
+					t.Fatalf("CreateShare: %v", err)
+				}
+				return models.PhotoAccess{ShareToken: share.Token}
+			},
+			allowed: []string{models.VisibilityPublic},
+		},
+		{
+			name: "grant from unlocking the box",
+			access: func(t *testing.T, f *photoFixture) models.PhotoAccess {
+				return models.PhotoAccess{Grant: f.grant(t, f.box.ID)}
+			},
+			allowed: []string{models.VisibilityPublic, models.VisibilityPIN},
+		},
+		{
+			name: "grant from unlocking another box",
+			access: func(t *testing.T, f *photoFixture) models.PhotoAccess {
+				return models.PhotoAccess{Grant: f.grant(t, f.newBox(t).ID)}
+			},
+			allowed: []string{models.VisibilityPublic},
+		},
+		{
+			name: "forged grant",
+			access: func(t *testing.T, f *photoFixture) models.PhotoAccess {
+				payload := photoGrantPayload(f.box.ID, time.Now().Add(time.Hour).Unix())
+				return models.PhotoAccess{Grant: payload + "." + payload}
+			},
+			allowed: []string{models.VisibilityPublic},
+		},
+	}
+
+	for _, tt := range tests {
+		allowed := make(map[string]bool)
+		for _, visibility := range tt.allowed {
+			allowed[visibility] = true
+		}
+
+		for _, visibility := range visibilities {
+			t.Run(tt.name+"/"+visibility, func(t *testing.T) {
+				f := newPhotoFixture(t)
+				access := tt.access(t, f)
+
+				request := &models.UpdateBoxRequest{Visibility: visibility}
+				if visibility == models.VisibilityPIN {
+					request.PIN = "1234"
+				}
+				if _, err := f.qr.UpdateBox("owner", f.box.ID, request); err != nil {
+					t.Fatalf("UpdateBox: %v", err)
+				}
+
+				for _, thumbnail := range []bool{false, true} {
+					file, _, err := f.photos.OpenPhoto(f.photo.ID, thumbnail, access)
+					switch {
+					case allowed[visibility] && err != nil:
+						t.Errorf("OpenPhoto(thumbnail %v) error %v, want the photo", thumbnail, err)
+					case !allowed[visibility] && (err == nil || err.Error() != "photo not found"):
+						t.Errorf("OpenPhoto(thumbnail %v) error %v, want \"photo not found\"", thumbnail, err)
+					}
+					if file != nil {
+						io.Copy(io.Discard, file)
+						file.Close()
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestResolveShareAddsTokenToPhotoURLs(t *testing.T) {
+	f := newPhotoFixture(t)
+	if _, err := f.qr.UpdateBox("owner", f.box.ID, &models.UpdateBoxRequest{Visibility: models.VisibilityPrivate}); err != nil {
+		t.Fatalf("UpdateBox: %v", err)
+	}
+
+	share, err := f.shares.CreateShare("owner", &models.CreateShareRequest{Scope: models.ShareScopeBox, TargetID: f.box.ID, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateShare: %v", err)
+	}
+
+	_, boxes, err := f.shares.ResolveShare(share.Token)
+	if err != nil {
+		t.Fatalf("ResolveShare: %v", err)
+	}
+	if len(boxes) != 1 || len(boxes[0].Photos) != 1 {
+		t.Fatalf("got %d shared boxes, want 1 with a photo", len(boxes))
+	}
+
+	for _, link := range []string{boxes[0].Photos[0].URL, boxes[0].Photos[0].ThumbnailURL} {
+		parsed, err := url.Parse(link)
+		if err != nil {
+			t.Fatalf("parse %s: %v", link, err)
+		}
+
+		query := parsed.Query()
+		access := models.PhotoAccess{ShareToken: query.Get("share")}
+		file, _, err := f.photos.OpenPhoto(query.Get("id"), query.Get("size") == "thumbnail", access)
+		if err != nil {
+			t.Errorf("photo at %s: %v", link, err)
+			continue
+		}
+		file.Close()
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+	"github.com/qr-boxes/backend/internal/storage"
+
+	// Registers WebP with image.Decode; JPEG, PNG and GIF come with imaging
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// maxPhotoPixels stops small files that decode to huge images
+	maxPhotoPixels = 50_000_000
+	// thumbnailSize bounds the longer side of a thumbnail, in pixels
+	thumbnailSize = 320
+	// photoGrantDuration is how long the photo links of an unlocked PIN box work
+	photoGrantDuration = time.Hour
+)
+
+// photoExtensions maps the accepted image types to the extension of the stored file
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type PhotoService struct {
+	boxRepo       repository.BoxStore
+	itemRepo      repository.ItemStore
+	photoRepo     repository.PhotoStore
+	workspaceRepo repository.WorkspaceStore
+	blobs         storage.BlobStore
+	shares        *ShareService
+}
+
+// NewPhotoService stores photo files in blobs. Share links, and the grants
+// given out when a PIN box is unlocked, are checked with shares.
+func NewPhotoService(stores *repository.Stores, blobs storage.BlobStore, shares *ShareService) *PhotoService {
+	return &PhotoService{
+		boxRepo:       stores.Boxes,
+		itemRepo:      stores.Items,
+		photoRepo:     stores.Photos,
+		workspaceRepo: stores.Workspaces,
+		blobs:         blobs,
+		shares:        shares,
+	}
+}
+
+// UploadPhoto stores an image and a JPEG thumbnail of it, and attaches them
+// to one of the user's boxes or, when itemID is set, to an item in that box
+func (s *PhotoService) UploadPhoto(userID, boxID, itemID string, data []byte) (*models.Photo, error) {
+	box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, boxID, actionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	if itemID != "" {
+		item, err := s.itemRepo.GetItemByID(itemID)
+		if err != nil || item.BoxID != box.ID {
+			return nil, fmt.Errorf("item not found")
+		}
+	}
+
+	// Trust the file's content rather than the name or header the client sent
+	contentType := http.DetectContentType(data)
+	extension, ok := photoExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported image type")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image")
+	}
+
+	if config.Width*config.Height > maxPhotoPixels {
+		return nil, fmt.Errorf("image is too large")
+	}
+
+	// Phones record rotation in EXIF; apply it so the thumbnail is upright
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image")
+	}
+
+	var thumbnail bytes.Buffer
+	err = imaging.Encode(&thumbnail, imaging.Fit(img, thumbnailSize, thumbnailSize, imaging.Lanczos), imaging.JPEG, imaging.JPEGQuality(80))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail: %w", err)
+	}
+
+	photoID := uuid.New().String()
+	photo := &models.Photo{
+		ID:           photoID,
+		BoxID:        box.ID,
+		ItemID:       itemID,
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        img.Bounds().Dx(),
+		Height:       img.Bounds().Dy(),
+		StorageKey:   fmt.Sprintf("photos/%s/%s%s", box.ID, photoID, extension),
+		ThumbnailKey: fmt.Sprintf("photos/%s/%s_thumb.jpg", box.ID, photoID),
+		CreatedAt:    time.Now(),
+	}
+
+	if err := s.blobs.Put(photo.StorageKey, bytes.NewReader(data), photo.Size, contentType); err != nil {
+		return nil, err
+	}
+
+	if err := s.blobs.Put(photo.ThumbnailKey, &thumbnail, int64(thumbnail.Len()), "image/jpeg"); err != nil {
+		deletePhotoFiles(s.blobs, []models.Photo{*photo})
+		return nil, err
+	}
+
+	if err := s.photoRepo.CreatePhoto(photo); err != nil {
+		deletePhotoFiles(s.blobs, []models.Photo{*photo})
+		return nil, err
+	}
+
+	photo.SetURLs()
+	return photo, nil
+}
+
+// DeletePhoto removes a photo from one of the user's boxes
+func (s *PhotoService) DeletePhoto(userID, photoID string) error {
+	photo, err := s.photoRepo.GetPhotoByID(photoID)
+	if err != nil {
+		return err
+	}
+
+	box, err := s.boxRepo.GetByID(photo.BoxID)
+	if err != nil {
+		return fmt.Errorf("photo not found")
+	}
+
+	if err := authorizeReference(s.workspaceRepo, userID, box.WorkspaceID, actionEdit, "photo not found"); err != nil {
+		return err
+	}
+
+	if err := s.photoRepo.DeletePhoto(photo.ID); err != nil {
+		return err
+	}
+
+	deletePhotoFiles(s.blobs, []models.Photo{*photo})
+	return nil
+}
+
+// OpenPhoto returns the photo, or its thumbnail, for download together with its
+// content type. The caller must close the reader. Photos of boxes that aren't
+// public are only served to the access that box allows, and are otherwise
+// reported as not found.
+func (s *PhotoService) OpenPhoto(photoID string, thumbnail bool, access models.PhotoAccess) (io.ReadCloser, string, error) {
+	photo, err := s.photoRepo.GetPhotoByID(photoID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	box, err := s.boxRepo.GetByID(photo.BoxID)
+	if err != nil || !s.canSeePhotos(box, access) {
+		return nil, "", fmt.Errorf("photo not found")
+	}
+
+	key, contentType := photo.StorageKey, photo.ContentType
+	if thumbnail {
+		key, contentType = photo.ThumbnailKey, "image/jpeg"
+	}
+
+	file, err := s.blobs.Get(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return file, contentType, nil
+}
+
+// canSeePhotos reports whether access may see the photos of box: anyone for a
+// public box, otherwise workspace members, share links covering the box and,
+// for a PIN box, a grant from unlocking it
+func (s *PhotoService) canSeePhotos(box *models.Box, access models.PhotoAccess) bool {
+	if box.Visibility == models.VisibilityPublic {
+		return true
+	}
+
+	if access.UserID != "" && authorizeReference(s.workspaceRepo, access.UserID, box.WorkspaceID, actionView, "box not found") == nil {
+		return true
+	}
+
+	if access.ShareToken != "" && s.shares.coversBox(access.ShareToken, box.ID) {
+		return true
+	}
+
+	return access.Grant != "" && box.Visibility == models.VisibilityPIN && s.validGrant(access.Grant, box.ID)
+}
+
+// GrantPhotos adds a grant to the photo URLs of a PIN box that was just
+// unlocked, so the public page can load them for photoGrantDuration
+func (s *PhotoService) GrantPhotos(box *models.Box) {
+	payload := photoGrantPayload(box.ID, time.Now().Add(photoGrantDuration).Unix())
+	grant := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(s.shares.sign(payload))
+
+	for i := range box.Photos {
+		box.Photos[i].AddURLParam("grant", grant)
+	}
+}
+
+// validGrant checks that grant was given out by GrantPhotos for boxID and
+// hasn't expired
+func (s *PhotoService) validGrant(grant, boxID string) bool {
+	encodedPayload, encodedSignature, ok := strings.Cut(grant, ".")
+	if !ok {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return false
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, s.shares.sign(string(payload))) {
+		return false
+	}
+
+	expiresText, ok := strings.CutPrefix(string(payload), photoGrantPrefix+boxID+".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(expiresText, 10, 64)
+	return err == nil && time.Unix(expires, 0).After(time.Now())
+}
+
+// photoGrantPrefix starts what a photo grant signs, so that it can never be
+// read as a share token, which is signed with the same key
+const photoGrantPrefix = "photo."
+
+func photoGrantPayload(boxID string, expires int64) string {
+	return photoGrantPrefix + boxID + "." + strconv.FormatInt(expires, 10)
+}
+
+// deletePhotoFiles removes the stored files of photos whose rows are already
+// gone. It is best effort: a file left behind is unreachable and harmless,
+// and failing the request would misreport a deletion that did happen.
+func deletePhotoFiles(blobs storage.BlobStore, photos []models.Photo) {
+	for _, photo := range photos {
+		_ = blobs.Delete(photo.StorageKey)
+		_ = blobs.Delete(photo.ThumbnailKey)
+	}
+}
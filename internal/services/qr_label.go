@@ -0,0 +1,299 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/qrdecode"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Proportions of a QR label
+const (
+	// captionShare is the height of the caption strip as a share of the code's width
+	captionShare = 0.18
+
+	// captionFontShare is the caption's font size as a share of the strip's height
+	captionFontShare = 0.55
+
+	// logoShare is the width of the logo as a share of the code inside its
+	// quiet zone. Level H restores up to 30% of the code, and the logo and
+	// its padding hide well under a tenth of it.
+	logoShare = 0.22
+
+	// maxLogoBytes caps the size of an uploaded logo
+	maxLogoBytes = 1 << 20
+)
+
+// captionFont is the typeface of label captions, parsed on first use
+var captionFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(gobold.TTF)
+})
+
+// qrLabel is what is drawn around and over the code of a label
+type qrLabel struct {
+	caption string
+	logo    image.Image
+}
+
+// newQRLabel validates the caption choice and logo of a label for a box
+func newQRLabel(box *models.Box, options models.QRRenderOptions) (*qrLabel, error) {
+	label := &qrLabel{}
+
+	switch strings.TrimSpace(options.Caption) {
+	case "":
+	case models.QRCaptionName:
+		label.caption = box.Name
+	case models.QRCaptionCode:
+		label.caption = box.ShortCode
+	default:
+		return nil, fmt.Errorf("invalid qr caption")
+	}
+
+	if len(options.Logo) > 0 {
+		if len(options.Logo) > maxLogoBytes {
+			return nil, fmt.Errorf("qr logo too large")
+		}
+
+		config, _, err := image.DecodeConfig(bytes.NewReader(options.Logo))
+		if err != nil || config.Width*config.Height > maxPhotoPixels {
+			return nil, fmt.Errorf("invalid qr logo")
+		}
+
+		if label.logo, err = imaging.Decode(bytes.NewReader(options.Logo)); err != nil {
+			return nil, fmt.Errorf("invalid qr logo")
+		}
+	}
+
+	return label, nil
+}
+
+// renderQRLabel draws a code with a caption under it and a logo over its
+// middle, as a PNG or SVG. The label is decoded again to make sure the logo
+// hasn't made it unreadable; an SVG is checked through the PNG it matches.
+func renderQRLabel(content string, style *qrStyle, label *qrLabel) (*models.QRImage, error) {
+	if style.format != "png" && style.format != "svg" {
+		return nil, fmt.Errorf("invalid qr label format")
+	}
+
+	qr, err := qrcode.New(content, style.level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	qr.DisableBorder = true
+	bitmap := addQRBorder(qr.Bitmap(), style.border)
+
+	if style.size < len(bitmap) {
+		return nil, fmt.Errorf("qr size too small")
+	}
+
+	caption, fontSize, err := fitCaption(label.caption, style)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := drawQRLabel(bitmap, style, label, caption, fontSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if text, err := qrdecode.Decode(img); err != nil || text != content {
+		return nil, fmt.Errorf("qr label does not decode")
+	}
+
+	rendered := qrFormats[style.format]
+	if style.format == "svg" {
+		rendered.Data, err = renderQRLabelSVG(bitmap, style, label, caption, fontSize)
+	} else {
+		rendered.Data, err = encodePNG(img, style.dpi)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rendered, nil
+}
+
+// captionHeight is the height in pixels of the caption strip, if there is one
+func captionHeight(style *qrStyle, caption string) int {
+	if caption == "" {
+		return 0
+	}
+	return int(math.Round(float64(style.size) * captionShare))
+}
+
+// fitCaption returns the caption and its font size in pixels, shrinking the
+// font to fit the label's width and then shortening the text if it still
+// doesn't fit
+func fitCaption(caption string, style *qrStyle) (string, float64, error) {
+	caption = strings.TrimSpace(caption)
+	if caption == "" {
+		return "", 0, nil
+	}
+
+	fontSize := float64(captionHeight(style, caption)) * captionFontShare
+	maxWidth := float64(style.size) * 0.9
+	minFontSize := fontSize / 2
+
+	width, err := captionWidth(caption, fontSize)
+	if err != nil {
+		return "", 0, err
+	}
+	if width <= maxWidth {
+		return caption, fontSize, nil
+	}
+
+	fontSize = max(minFontSize, fontSize*maxWidth/width)
+	runes := []rune(caption)
+	for text := caption; len(runes) > 0; text = strings.TrimSpace(string(runes)) + "…" {
+		if width, err = captionWidth(text, fontSize); err != nil {
+			return "", 0, err
+		}
+		if width <= maxWidth {
+			return text, fontSize, nil
+		}
+		runes = runes[:len(runes)-1]
+	}
+
+	return "", 0, nil
+}
+
+// captionFace returns the caption font at size pixels
+func captionFace(size float64) (font.Face, error) {
+	f, err := captionFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load caption font: %w", err)
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load caption font: %w", err)
+	}
+	return face, nil
+}
+
+// captionWidth measures text in the caption font at size pixels
+func captionWidth(text string, size float64) (float64, error) {
+	face, err := captionFace(size)
+	if err != nil {
+		return 0, err
+	}
+	defer face.Close()
+
+	return float64(font.MeasureString(face, text)) / 64, nil
+}
+
+// drawQRLabel draws the label as an image: the code, the logo on a pad of
+// background color in its middle, and the caption centred under it
+func drawQRLabel(bitmap [][]bool, style *qrStyle, label *qrLabel, caption string, fontSize float64) (*image.RGBA, error) {
+	height := style.size + captionHeight(style, caption)
+	canvas := image.NewRGBA(image.Rect(0, 0, style.size, height))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(style.background), image.Point{}, draw.Src)
+	draw.Draw(canvas, image.Rect(0, 0, style.size, style.size), drawQRCode(bitmap, style), image.Point{}, draw.Src)
+
+	if label.logo != nil {
+		scale := style.size / len(bitmap)
+		side := int(float64((len(bitmap)-2*style.border)*scale) * logoShare)
+		logo := imaging.Fit(label.logo, side, side, imaging.Lanczos)
+
+		// The pad keeps a module of clear space around the logo
+		centre := style.size / 2
+		pad := image.Rect(centre-side/2-scale, centre-side/2-scale, centre+(side+1)/2+scale, centre+(side+1)/2+scale)
+		draw.Draw(canvas, pad, image.NewUniform(style.background), image.Point{}, draw.Src)
+
+		bounds := logo.Bounds()
+		at := image.Pt(centre-bounds.Dx()/2, centre-bounds.Dy()/2)
+		draw.Draw(canvas, bounds.Add(at), logo, bounds.Min, draw.Over)
+	}
+
+	if caption != "" {
+		face, err := captionFace(fontSize)
+		if err != nil {
+			return nil, err
+		}
+		defer face.Close()
+
+		drawer := &font.Drawer{Dst: canvas, Src: image.NewUniform(style.foreground), Face: face}
+		metrics := face.Metrics()
+		textHeight := metrics.Ascent + metrics.Descent
+		baseline := fixed.I(style.size) + (fixed.I(height-style.size)-textHeight)/2 + metrics.Ascent
+		drawer.Dot = fixed.Point26_6{X: (fixed.I(style.size) - drawer.MeasureString(caption)) / 2, Y: baseline}
+		drawer.DrawString(caption)
+	}
+
+	return canvas, nil
+}
+
+// renderQRLabelSVG draws the label as an SVG in the same layout as
+// drawQRLabel, using one user unit per module
+func renderQRLabelSVG(bitmap [][]bool, style *qrStyle, label *qrLabel, caption string, fontSize float64) ([]byte, error) {
+	modules := float64(len(bitmap))
+	unit := modules / float64(style.size)
+	height := modules + float64(captionHeight(style, caption))*unit
+	number := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	width, heightDimension := strconv.Itoa(style.size), strconv.Itoa(style.size+captionHeight(style, caption))
+	if style.dpi > 0 {
+		width = number(float64(style.size)/float64(style.dpi)) + "in"
+		heightDimension = number(float64(style.size+captionHeight(style, caption))/float64(style.dpi)) + "in"
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%s" height="%s" viewBox="0 0 %s %s">`, width, heightDimension, number(modules), number(height))
+	fmt.Fprintf(&svg, `<rect width="%s" height="%s" fill="%s"/>`, number(modules), number(height), hexColor(style.background))
+
+	var path strings.Builder
+	forEachQRRun(bitmap, func(x, y, length int) {
+		fmt.Fprintf(&path, "M%d,%dh%dv1h-%dz", x, y, length, length)
+	})
+	fmt.Fprintf(&svg, `<path d="%s" fill="%s" shape-rendering="crispEdges"/>`, path.String(), hexColor(style.foreground))
+
+	if label.logo != nil {
+		side := (modules - 2*float64(style.border)) * logoShare
+		centre := modules / 2
+
+		// Embed the logo at the resolution it is printed at
+		pixels := max(1, int(side/unit))
+		data, err := encodePNG(imaging.Fit(label.logo, pixels, pixels, imaging.Lanczos), 0)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&svg, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`, number(centre-side/2-1), number(centre-side/2-1), number(side+2), number(side+2), hexColor(style.background))
+		fmt.Fprintf(&svg, `<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="xMidYMid meet" xlink:href="data:image/png;base64,%s"/>`, number(centre-side/2), number(centre-side/2), number(side), number(side), base64.StdEncoding.EncodeToString(data))
+	}
+
+	if caption != "" {
+		// The baseline sits as in drawQRLabel, with the font's own metrics
+		face, err := captionFace(fontSize)
+		if err != nil {
+			return nil, err
+		}
+		metrics := face.Metrics()
+		face.Close()
+
+		strip := float64(captionHeight(style, caption))
+		ascent, descent := float64(metrics.Ascent)/64, float64(metrics.Descent)/64
+		baseline := float64(style.size) + (strip-ascent-descent)/2 + ascent
+		fmt.Fprintf(&svg, `<text x="%s" y="%s" font-family="Go, Helvetica, Arial, sans-serif" font-weight="bold" font-size="%s" text-anchor="middle" fill="%s">%s</text>`, number(modules/2), number(baseline*unit), number(fontSize*unit), hexColor(style.foreground), html.EscapeString(caption))
+	}
+
+	svg.WriteString(`</svg>`)
+	return []byte(svg.String()), nil
+}
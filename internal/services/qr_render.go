@@ -0,0 +1,406 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/skip2/go-qrcode"
+)
+
+// Limits and defaults for QR codes rendered on demand
+const (
+	defaultQRFormat = "png"
+	defaultQRSize   = 256
+	minQRSize       = 64
+	maxQRSize       = 4096
+	maxQRDPI        = 2400
+	defaultQRBorder = 4
+	maxQRBorder     = 16
+	defaultQRLevel  = "M"
+
+	// minQRContrast is the least difference in luminance, from 0 to 1, between
+	// the background and the darker foreground that scanners reliably read
+	minQRContrast = 0.4
+)
+
+var (
+	qrBlack = color.RGBA{A: 0xff}
+	qrWhite = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+// qrLevels maps error correction level letters to go-qrcode recovery levels,
+// which restore roughly 7%, 15%, 25% and 30% of a damaged code
+var qrLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// qrFormats lists the content type and file extension of each render format
+var qrFormats = map[string]models.QRImage{
+	"png": {ContentType: "image/png", Extension: "png"},
+	"svg": {ContentType: "image/svg+xml", Extension: "svg"},
+	"pdf": {ContentType: "application/pdf", Extension: "pdf"},
+	"eps": {ContentType: "application/postscript", Extension: "eps"},
+}
+
+// qrStyle is a validated set of QRRenderOptions with the defaults filled in
+type qrStyle struct {
+	format     string
+	size       int
+	dpi        int
+	level      qrcode.RecoveryLevel
+	foreground color.RGBA
+	background color.RGBA
+	border     int
+}
+
+// RenderBoxQR renders the QR code of a box the user may view, by its ID or
+// short code, with the requested format, size and colors. A caption or logo
+// turns it into a label.
+func (s *QRService) RenderBoxQR(userID, boxID string, options models.QRRenderOptions) (*models.QRImage, error) {
+	box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, boxID, actionView)
+	if err != nil {
+		return nil, err
+	}
+
+	style, err := newQRStyle(options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Caption == "" && len(options.Logo) == 0 {
+		return renderQRCode(box.QRCodeURL, style)
+	}
+
+	label, err := newQRLabel(box, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderQRLabel(box.QRCodeURL, style, label)
+}
+
+// BoxQRImage renders the QR code of a box the user may view with the default
+// options, as a PNG or SVG image
+func (s *QRService) BoxQRImage(userID, boxID, format string) (*models.QRImage, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = defaultQRFormat
+	}
+	if format != "png" && format != "svg" {
+		return nil, fmt.Errorf("invalid qr image format")
+	}
+
+	return s.RenderBoxQR(userID, boxID, models.QRRenderOptions{Format: format})
+}
+
+// newQRStyle validates render options and fills in their defaults
+func newQRStyle(options models.QRRenderOptions) (*qrStyle, error) {
+	style := &qrStyle{
+		format:     strings.ToLower(strings.TrimSpace(options.Format)),
+		size:       options.Size,
+		dpi:        options.DPI,
+		border:     defaultQRBorder,
+		foreground: qrBlack,
+		background: qrWhite,
+	}
+
+	if style.format == "" {
+		style.format = defaultQRFormat
+	}
+	if _, ok := qrFormats[style.format]; !ok {
+		return nil, fmt.Errorf("invalid qr format")
+	}
+
+	if style.size == 0 {
+		style.size = defaultQRSize
+	}
+	if style.size < minQRSize || style.size > maxQRSize {
+		return nil, fmt.Errorf("invalid qr size")
+	}
+
+	if style.dpi < 0 || style.dpi > maxQRDPI {
+		return nil, fmt.Errorf("invalid qr dpi")
+	}
+
+	// A logo hides part of the code, which only the highest level makes up for
+	level := strings.ToUpper(strings.TrimSpace(options.Level))
+	if len(options.Logo) > 0 {
+		if level != "" && level != "H" {
+			return nil, fmt.Errorf("qr logo requires level H")
+		}
+		level = "H"
+	}
+	if level == "" {
+		level = defaultQRLevel
+	}
+	recovery, ok := qrLevels[level]
+	if !ok {
+		return nil, fmt.Errorf("invalid qr error correction level")
+	}
+	style.level = recovery
+
+	if options.Border != nil {
+		if *options.Border < 0 || *options.Border > maxQRBorder {
+			return nil, fmt.Errorf("invalid qr border")
+		}
+		style.border = *options.Border
+	}
+
+	var err error
+	if options.Foreground != "" {
+		if style.foreground, err = parseHexColor(options.Foreground); err != nil {
+			return nil, err
+		}
+	}
+	if options.Background != "" {
+		if style.background, err = parseHexColor(options.Background); err != nil {
+			return nil, err
+		}
+	}
+
+	// Light-on-dark and faint codes look fine on screen but many scanners can't read them
+	if luminance(style.background)-luminance(style.foreground) < minQRContrast {
+		return nil, fmt.Errorf("qr colors lack contrast")
+	}
+
+	return style, nil
+}
+
+// renderQRCode encodes content and draws it in style
+func renderQRCode(content string, style *qrStyle) (*models.QRImage, error) {
+	qr, err := qrcode.New(content, style.level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	// go-qrcode always adds a four-module quiet zone; draw our own instead
+	qr.DisableBorder = true
+	bitmap := addQRBorder(qr.Bitmap(), style.border)
+
+	if style.size < len(bitmap) {
+		return nil, fmt.Errorf("qr size too small")
+	}
+
+	rendered := qrFormats[style.format]
+	switch style.format {
+	case "png":
+		rendered.Data, err = renderQRCodePNG(bitmap, style)
+	case "svg":
+		rendered.Data = []byte(renderQRCodeSVG(bitmap, style.dimension(), style.foreground, style.background))
+	case "pdf":
+		rendered.Data, err = renderQRCodePDF(bitmap, style)
+	case "eps":
+		rendered.Data = renderQRCodeEPS(bitmap, style)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rendered, nil
+}
+
+// dimension is the SVG width and height: the printed size in inches at a
+// given DPI, otherwise the size in pixels
+func (style *qrStyle) dimension() string {
+	if style.dpi > 0 {
+		return strconv.FormatFloat(float64(style.size)/float64(style.dpi), 'f', -1, 64) + "in"
+	}
+	return strconv.Itoa(style.size)
+}
+
+// points is the width of a vector image in PostScript points. Without a DPI
+// each pixel is one point.
+func (style *qrStyle) points() float64 {
+	if style.dpi > 0 {
+		return float64(style.size) / float64(style.dpi) * 72
+	}
+	return float64(style.size)
+}
+
+// addQRBorder surrounds a bitmap with border light modules on every side
+func addQRBorder(bitmap [][]bool, border int) [][]bool {
+	modules := len(bitmap) + 2*border
+	bordered := make([][]bool, modules)
+	for y := range bordered {
+		bordered[y] = make([]bool, modules)
+		if y >= border && y < modules-border {
+			copy(bordered[y][border:], bitmap[y-border])
+		}
+	}
+	return bordered
+}
+
+// renderQRCodePNG draws a bitmap as a two-color PNG
+func renderQRCodePNG(bitmap [][]bool, style *qrStyle) ([]byte, error) {
+	return encodePNG(drawQRCode(bitmap, style), style.dpi)
+}
+
+// drawQRCode draws a bitmap as a square image of style's size. Every module
+// gets the same whole number of pixels; what is left over widens the quiet zone.
+func drawQRCode(bitmap [][]bool, style *qrStyle) *image.Paletted {
+	modules := len(bitmap)
+	scale := style.size / modules
+	offset := (style.size - scale*modules) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, style.size, style.size), color.Palette{style.background, style.foreground})
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for py := offset + y*scale; py < offset+(y+1)*scale; py++ {
+				for px := offset + x*scale; px < offset+(x+1)*scale; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// encodePNG encodes an image as a PNG, recording dpi when it is set
+func encodePNG(img image.Image, dpi int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+
+	if dpi > 0 {
+		return withPNGResolution(buf.Bytes(), dpi), nil
+	}
+	return buf.Bytes(), nil
+}
+
+// withPNGResolution adds a pHYs chunk recording dpi to an encoded PNG, so
+// that it prints at the intended size. The chunk goes right after IHDR.
+func withPNGResolution(data []byte, dpi int) []byte {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+
+	pixelsPerMetre := uint32(math.Round(float64(dpi) / 0.0254))
+	chunk := make([]byte, 0, 4+4+9+4)
+	chunk = binary.BigEndian.AppendUint32(chunk, 9)
+	chunk = append(chunk, "pHYs"...)
+	chunk = binary.BigEndian.AppendUint32(chunk, pixelsPerMetre)
+	chunk = binary.BigEndian.AppendUint32(chunk, pixelsPerMetre)
+	chunk = append(chunk, 1) // unit: metre
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	result := make([]byte, 0, len(data)+len(chunk))
+	result = append(result, data[:ihdrEnd]...)
+	result = append(result, chunk...)
+	return append(result, data[ihdrEnd:]...)
+}
+
+// renderQRCodePDF draws a bitmap as a single-page vector PDF the size of the code
+func renderQRCodePDF(bitmap [][]bool, style *qrStyle) ([]byte, error) {
+	side := style.points()
+	module := side / float64(len(bitmap))
+
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "pt",
+		Size:    gofpdf.SizeType{Wd: side, Ht: side},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	pdf.SetFillColor(int(style.background.R), int(style.background.G), int(style.background.B))
+	pdf.Rect(0, 0, side, side, "F")
+
+	pdf.SetFillColor(int(style.foreground.R), int(style.foreground.G), int(style.foreground.B))
+	forEachQRRun(bitmap, func(x, y, length int) {
+		pdf.Rect(float64(x)*module, float64(y)*module, float64(length)*module, module, "F")
+	})
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderQRCodeEPS draws a bitmap as an Encapsulated PostScript file. Rows are
+// flipped because PostScript's origin is the bottom left corner.
+func renderQRCodeEPS(bitmap [][]bool, style *qrStyle) []byte {
+	side := style.points()
+	modules := len(bitmap)
+
+	var eps strings.Builder
+	eps.WriteString("%!PS-Adobe-3.0 EPSF-3.0\n")
+	fmt.Fprintf(&eps, "%%%%BoundingBox: 0 0 %d %d\n", int(math.Ceil(side)), int(math.Ceil(side)))
+	fmt.Fprintf(&eps, "%%%%HiResBoundingBox: 0 0 %.3f %.3f\n", side, side)
+	eps.WriteString("%%Creator: qr-boxes\n%%EndComments\n")
+	eps.WriteString("gsave\n")
+	fmt.Fprintf(&eps, "%s setrgbcolor\n0 0 %.3f %.3f rectfill\n", postScriptColor(style.background), side, side)
+	fmt.Fprintf(&eps, "%.6f dup scale\n", side/float64(modules))
+	fmt.Fprintf(&eps, "%s setrgbcolor\n", postScriptColor(style.foreground))
+	forEachQRRun(bitmap, func(x, y, length int) {
+		fmt.Fprintf(&eps, "%d %d %d 1 rectfill\n", x, modules-y-1, length)
+	})
+	eps.WriteString("grestore\n%%EOF\n")
+
+	return []byte(eps.String())
+}
+
+// forEachQRRun calls draw for each horizontal run of dark modules
+func forEachQRRun(bitmap [][]bool, draw func(x, y, length int)) {
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			draw(start, y, x-start)
+		}
+	}
+}
+
+// parseHexColor reads a color written as RRGGBB or RGB, with or without a
+// leading #
+func parseHexColor(value string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid qr color")
+	}
+
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
+
+// hexColor writes a color as #rrggbb
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// postScriptColor writes a color as the operands of setrgbcolor
+func postScriptColor(c color.RGBA) string {
+	return fmt.Sprintf("%.3f %.3f %.3f", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+}
+
+// luminance approximates how light a color looks, from 0 to 1
+func luminance(c color.RGBA) float64 {
+	return (0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)) / 255
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/qrdecode"
+)
+
+const (
+	// scanDimension is the longer side photos are scaled down to before
+	// looking for codes. Phone photos are several times larger, which only
+	// makes the search slower for codes that fill a good part of the frame.
+	scanDimension = 2000
+
+	// maxScanTime caps how long one photo is searched for codes
+	maxScanTime = 5 * time.Second
+)
+
+// ScanPhoto reads the QR codes in a photo and resolves each to a box the user
+// may view. Codes that aren't one of those boxes are reported as unrecognised,
+// the same whether the box doesn't exist or belongs to someone else.
+func (s *QRService) ScanPhoto(ctx context.Context, userID string, data []byte) (*models.ScanResult, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image")
+	}
+
+	if config.Width*config.Height > maxPhotoPixels {
+		return nil, fmt.Errorf("image is too large")
+	}
+
+	// Codes are read at any angle, so the EXIF orientation doesn't matter
+	img, err := imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image")
+	}
+
+	codes, err := scanCodes(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ScanResult{Boxes: []*models.Box{}, Unrecognised: []string{}}
+	found := make(map[string]bool)
+	for _, code := range codes {
+		box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, scannedBoxRef(code), actionView)
+		if err != nil {
+			if err.Error() != "box not found" && err.Error() != "forbidden" {
+				return nil, err
+			}
+			result.Unrecognised = append(result.Unrecognised, code)
+			continue
+		}
+
+		// The old /box/ and the /q/ code of one box both lead to it once
+		if !found[box.ID] {
+			found[box.ID] = true
+			result.Boxes = append(result.Boxes, box)
+		}
+	}
+
+	return result, nil
+}
+
+// scanCodes reads the codes in a photo scaled down to scanDimension. Only when
+// none are found there, as happens when a code is small in the frame, is the
+// full-size photo searched as well. Both searches share maxScanTime.
+func scanCodes(ctx context.Context, img image.Image) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, maxScanTime)
+	defer cancel()
+
+	bounds := img.Bounds()
+	scaled := bounds.Dx() > scanDimension || bounds.Dy() > scanDimension
+	if scaled {
+		codes, err := qrdecode.DecodeAllContext(ctx, imaging.Fit(img, scanDimension, scanDimension, imaging.Box))
+		if len(codes) > 0 {
+			return codes, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("scan timed out")
+		}
+	}
+
+	codes, err := qrdecode.DecodeAllContext(ctx, img)
+	if len(codes) > 0 {
+		return codes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan timed out")
+	}
+
+	return nil, fmt.Errorf("no qr code found")
+}
+
+// scannedBoxRef returns the box ID or short code in the text of a scanned
+// code: the last part of a box page URL (/box/<id>) or a redirect URL
+// (/q/<code>), or the text itself when it isn't a URL
+func scannedBoxRef(code string) string {
+	code = strings.TrimSpace(code)
+	parsed, err := url.Parse(code)
+	if err != nil || parsed.Scheme == "" {
+		return code
+	}
+
+	path := strings.TrimRight(parsed.Path, "/")
+	for _, prefix := range []string{"/box/", "/q/"} {
+		if i := strings.LastIndex(path, prefix); i >= 0 {
+			return path[i+len(prefix):]
+		}
+	}
+	return ""
+}
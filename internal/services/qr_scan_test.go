@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"testing"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// photoWithCode draws a code at scale pixels a module in the middle of a
+// white photo of the given size
+func photoWithCode(t *testing.T, content string, size, scale int) *image.Gray {
+	t.Helper()
+
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	bitmap := code.Bitmap()
+
+	photo := image.NewGray(image.Rect(0, 0, size, size))
+	draw.Draw(photo, photo.Bounds(), image.White, image.Point{}, draw.Src)
+	offset := (size - len(bitmap)*scale) / 2
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				module := image.Rect(x*scale, y*scale, (x+1)*scale, (y+1)*scale).Add(image.Pt(offset, offset))
+				draw.Draw(photo, module, image.Black, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return photo
+}
+
+func TestScanCodes(t *testing.T) {
+	const content = "https://qrboxes.app/q/K7F-3QX"
+
+	tests := []struct {
+		name  string
+		size  int
+		scale int
+	}{
+		{"small photo", 600, 6},
+		{"large photo, large code", 4000, 40},
+		// At 2000px the modules are a pixel wide, too small to read, so this
+		// one is only found at full size
+		{"large photo, small code", 4000, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes, err := scanCodes(context.Background(), photoWithCode(t, content, tt.size, tt.scale))
+			if err != nil || len(codes) != 1 || codes[0] != content {
+				t.Errorf("got %q, %v; want %q", codes, err, content)
+			}
+		})
+	}
+}
+
+func TestScanCodesStopsWhenDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	_, err := scanCodes(ctx, photoWithCode(t, "https://qrboxes.app/q/K7F-3QX", 4000, 2))
+	if err == nil || err.Error() != "scan timed out" {
+		t.Errorf("error = %v, want \"scan timed out\"", err)
+	}
+	if elapsed := time.Since(start); elapsed > maxScanTime {
+		t.Errorf("cancelled scan took %v", elapsed)
+	}
+}
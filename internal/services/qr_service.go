@@ -3,115 +3,470 @@ package services
 import (
 	"encoding/base64"
 	"fmt"
+	"image/color"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/qr-boxes/backend/internal/models"
 	"github.com/qr-boxes/backend/internal/repository"
+	"github.com/qr-boxes/backend/internal/storage"
 	"github.com/skip2/go-qrcode"
 )
 
 type QRService struct {
-	baseURL string
-	boxRepo *repository.BoxRepository
+	baseURL           string
+	scanURL           string
+	boxRepo           repository.BoxStore
+	itemRepo          repository.ItemStore
+	locationRepo      repository.LocationStore
+	tagRepo           repository.TagStore
+	workspaceRepo     repository.WorkspaceStore
+	blobs             storage.BlobStore
+	boxPINFailures    *attemptLimiter
+	clientPINAttempts *attemptLimiter
 }
 
-func NewQRService(baseURL string, boxRepo *repository.BoxRepository) *QRService {
+// NewQRService links boxes to the frontend at baseURL. QR codes encode a
+// redirect served by the backend at scanURL instead, so printed labels keep
+// working when the frontend moves.
+func NewQRService(baseURL, scanURL string, stores *repository.Stores, blobs storage.BlobStore) *QRService {
 	return &QRService{
-		baseURL: baseURL,
-		boxRepo: boxRepo,
+		baseURL:           baseURL,
+		scanURL:           scanURL,
+		boxRepo:           stores.Boxes,
+		itemRepo:          stores.Items,
+		locationRepo:      stores.Locations,
+		tagRepo:           stores.Tags,
+		workspaceRepo:     stores.Workspaces,
+		blobs:             blobs,
+		boxPINFailures:    newAttemptLimiter(0, pinAttemptWindow),
+		clientPINAttempts: newAttemptLimiter(pinAttemptsPerClient, pinAttemptWindow),
 	}
 }
 
 func (s *QRService) CreateBox(userID string, request *models.CreateBoxRequest) (*models.CreateBoxResponse, error) {
-	// Generate unique ID for the box
-	boxID := uuid.New().String()
+	rooms := newPendingRooms(s.locationRepo, userID)
+	box, err := s.newBox(userID, request, nil, rooms)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create the QR code content (URL that will redirect to the box details)
-	qrContent := fmt.Sprintf("%s/box/%s", s.baseURL, boxID)
+	box.QRCodeURL, box.QRCode, err = s.boxQRCode(box.ShortCode)
+	if err != nil {
+		return nil, err
+	}
 
-	// Generate QR code as PNG bytes
-	qrBytes, err := qrcode.Encode(qrContent, qrcode.Medium, 256)
+	// Generate SVG version for web display
+	qrSVG, err := s.generateQRCodeSVG(box.QRCodeURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+		return nil, fmt.Errorf("failed to generate QR code SVG: %w", err)
+	}
+
+	// Save box to database, together with its items, tags and new room
+	err = s.boxRepo.CreateBoxes([]*models.Box{box}, rooms.created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save box to database: %w", err)
+	}
+
+	response := &models.CreateBoxResponse{
+		Box:       box,
+		QRCodeSVG: qrSVG,
+		Message:   "Box created successfully with QR code",
 	}
 
-	// Convert to base64 for easy transmission
-	qrCodeBase64 := base64.StdEncoding.EncodeToString(qrBytes)
+	return response, nil
+}
 
-	// Process items list from text input
-	items := processItemsList(request.Items)
+// newBox checks a box request and builds the box it describes, with a short
+// code that is neither in use nor in taken, but without its QR code. Rooms
+// that don't exist yet are added to rooms rather than stored.
+func (s *QRService) newBox(userID string, request *models.CreateBoxRequest, taken map[string]bool, rooms *pendingRooms) (*models.Box, error) {
+	// Resolve the workspace, location and outer box before anything is generated
+	workspaceID, err := s.boxWorkspace(userID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := s.boxLocation(userID, workspaceID, request.LocationID, request.Room, rooms)
+	if err != nil {
+		return nil, err
+	}
+
+	var parentID string
+	if request.ParentBoxID != "" {
+		parent, err := s.parentBox(userID, workspaceID, request.ParentBoxID)
+		if err != nil {
+			return nil, err
+		}
+		parentID = parent.ID
+	}
+
+	tags, err := ownedBoxTags(s.tagRepo, s.workspaceRepo, userID, workspaceID, request.TagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate unique ID and short code for the box
+	boxID := uuid.New().String()
+	shortCode, err := uniqueShortCode(s.boxRepo)
+	for err == nil && taken[shortCode] {
+		shortCode, err = uniqueShortCode(s.boxRepo)
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	// Create the box object
 	now := time.Now()
 	box := &models.Box{
 		ID:          boxID,
+		ShortCode:   shortCode,
 		UserID:      userID,
+		WorkspaceID: workspaceID,
 		Name:        request.Name,
 		Description: request.Description,
-		Room:        request.Room,
-		Items:       items,
-		QRCode:      qrCodeBase64,
-		QRCodeURL:   qrContent,
+		ParentBoxID: parentID,
+		Items:       processItemsList(boxID, request.Items),
+		Tags:        tags,
+		Photos:      []models.Photo{},
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
+	setBoxLocation(box, location)
 
-	// Generate SVG version for web display
-	qrSVG, err := s.generateQRCodeSVG(qrContent)
+	if err := setBoxVisibility(box, request.Visibility, request.PIN); err != nil {
+		return nil, err
+	}
+
+	return box, nil
+}
+
+// generateQRCodeSVG renders content as a scalable vector QR code. The SVG is
+// built from the same module matrix go-qrcode uses for the PNG, including the
+// standard four-module quiet zone, so both images encode an identical payload.
+func (s *QRService) generateQRCodeSVG(content string) (string, error) {
+	qr, err := qrcode.New(content, qrcode.Medium)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate QR code SVG: %w", err)
+		return "", fmt.Errorf("failed to encode QR code: %w", err)
 	}
 
-	// Save box to database
-	err = s.boxRepo.Create(box)
+	return renderQRCodeSVG(qr.Bitmap(), "256", qrBlack, qrWhite), nil
+}
+
+// renderQRCodeSVG draws a QR bitmap as an SVG document. Each module is one user
+// unit in the viewBox, so the image scales cleanly to any size; dimension only
+// sets the default rendered width and height, in pixels unless it has a unit.
+func renderQRCodeSVG(bitmap [][]bool, dimension string, foreground, background color.RGBA) string {
+	modules := len(bitmap)
+
+	// Merge horizontal runs of dark modules into a single rectangle
+	var path strings.Builder
+	forEachQRRun(bitmap, func(x, y, length int) {
+		fmt.Fprintf(&path, "M%d,%dh%dv1h-%dz", x, y, length, length)
+	})
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, dimension, dimension, modules, modules)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="%s"/>`, modules, modules, hexColor(background))
+	fmt.Fprintf(&svg, `<path d="%s" fill="%s"/>`, path.String(), hexColor(foreground))
+	svg.WriteString(`</svg>`)
+
+	return svg.String()
+}
+
+// boxWorkspace picks the workspace for a new box: the requested one, else the
+// workspace of the box it is packed into or of its location, else the user's
+// default workspace
+func (s *QRService) boxWorkspace(userID string, request *models.CreateBoxRequest) (string, error) {
+	workspaceID := strings.TrimSpace(request.WorkspaceID)
+
+	if workspaceID == "" && request.ParentBoxID != "" {
+		parent, err := lookupBox(s.boxRepo, request.ParentBoxID)
+		if err != nil {
+			return "", fmt.Errorf("parent box not found")
+		}
+		if err := authorizeReference(s.workspaceRepo, userID, parent.WorkspaceID, actionView, "parent box not found"); err != nil {
+			return "", err
+		}
+		workspaceID = parent.WorkspaceID
+	}
+
+	if workspaceID == "" && request.LocationID != "" {
+		location, err := ownedLocation(s.locationRepo, s.workspaceRepo, userID, request.LocationID, actionView)
+		if err != nil {
+			return "", err
+		}
+		workspaceID = location.WorkspaceID
+	}
+
+	return resolveWorkspace(s.workspaceRepo, userID, workspaceID)
+}
+
+// parentBox loads a box, by its ID or short code, that another box is being
+// packed into and checks that it is in the same workspace, which the user may edit
+func (s *QRService) parentBox(userID, workspaceID, parentID string) (*models.Box, error) {
+	parent, err := lookupBox(s.boxRepo, parentID)
+	if err != nil || parent.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("parent box not found")
+	}
+
+	if err := authorizeReference(s.workspaceRepo, userID, workspaceID, actionEdit, "parent box not found"); err != nil {
+		return nil, err
+	}
+
+	return parent, nil
+}
+
+// checkBoxParent returns the ID of the box another box is being packed into,
+// rejecting the box itself and boxes that are already inside it
+func (s *QRService) checkBoxParent(userID, workspaceID, boxID, parentRef string) (string, error) {
+	parent, err := s.parentBox(userID, workspaceID, parentRef)
 	if err != nil {
-		return nil, fmt.Errorf("failed to save box to database: %w", err)
+		return "", err
 	}
 
-	response := &models.CreateBoxResponse{
-		Box:       box,
-		QRCodeSVG: qrSVG,
-		Message:   "Box created successfully with QR code",
+	if parent.ID == boxID {
+		return "", fmt.Errorf("box cannot be placed inside itself")
 	}
 
-	return response, nil
+	chains, err := s.boxRepo.GetContainmentChains([]string{parent.ID})
+	if err != nil {
+		return "", err
+	}
+
+	for _, outer := range chains[parent.ID] {
+		if outer.ID == boxID {
+			return "", fmt.Errorf("box cannot be placed inside itself")
+		}
+	}
+
+	return parent.ID, nil
 }
 
-func (s *QRService) generateQRCodeSVG(content string) (string, error) {
-	// For now, we'll return a placeholder SVG
-	// In a real implementation, you might want to use a library that generates SVG QR codes
-	svgTemplate := `<svg width="256" height="256" xmlns="http://www.w3.org/2000/svg">
-		<rect width="100%%" height="100%%" fill="white"/>
-		<text x="50%%" y="50%%" text-anchor="middle" dy=".3em" font-family="Arial" font-size="12">
-			QR Code Generated
-		</text>
-		<text x="50%%" y="60%%" text-anchor="middle" dy=".3em" font-family="Arial" font-size="8">
-			%s
-		</text>
-	</svg>`
+// boxLocation picks the location for a new box in a workspace: the given
+// location ID if set, otherwise the top-level location matching room, which
+// rooms plans if it is new, otherwise none
+func (s *QRService) boxLocation(userID, workspaceID, locationID, room string, rooms *pendingRooms) (*models.Location, error) {
+	if locationID != "" {
+		return workspaceLocation(s.locationRepo, s.workspaceRepo, userID, workspaceID, locationID)
+	}
+
+	if strings.TrimSpace(room) != "" {
+		return rooms.location(workspaceID, room)
+	}
+
+	return nil, nil
+}
+
+// workspaceLocation loads one of the user's locations and checks that it is in
+// the given workspace
+func workspaceLocation(locationRepo repository.LocationStore, workspaceRepo repository.WorkspaceStore, userID, workspaceID, locationID string) (*models.Location, error) {
+	location, err := ownedLocation(locationRepo, workspaceRepo, userID, locationID, actionView)
+	if err != nil {
+		return nil, err
+	}
+
+	if location.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("location not found")
+	}
+
+	return location, nil
+}
+
+// setBoxLocation points the box at location, or at no location when nil. Room
+// caches the breadcrumb text for search, sorting and filtering.
+func setBoxLocation(box *models.Box, location *models.Location) {
+	if location == nil {
+		box.LocationID = ""
+		box.LocationPath = models.LocationPath{}
+		box.Room = ""
+		return
+	}
+
+	box.LocationID = location.ID
+	box.LocationPath = location.Path
+	box.Room = location.Path.String()
+}
+
+// GenerateQRCodePNG encodes the box's QR payload as a square PNG of the given size
+func (s *QRService) GenerateQRCodePNG(box *models.Box, size int) ([]byte, error) {
+	return encodeQRCodePNG(box.QRCodeURL, size)
+}
+
+// boxQRCode returns the URL a box's QR code encodes and the code as a base64
+// PNG. The URL is the backend's redirect for the box's short code, which keeps
+// it, and so the QR code, small.
+func (s *QRService) boxQRCode(shortCode string) (qrContent, qrCodeBase64 string, err error) {
+	qrContent = fmt.Sprintf("%s/q/%s", s.scanURL, shortCode)
+
+	qrBytes, err := encodeQRCodePNG(qrContent, 256)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate QR code: %w", err)
+	}
+
+	return qrContent, base64.StdEncoding.EncodeToString(qrBytes), nil
+}
+
+// RegenerateQRCodes rebuilds the stored QR code of every box from the current
+// configuration and returns how many it rebuilt
+func (s *QRService) RegenerateQRCodes() (int, error) {
+	codes, err := s.boxRepo.GetShortCodes()
+	if err != nil {
+		return 0, err
+	}
+
+	regenerated := 0
+	for boxID, shortCode := range codes {
+		qrContent, qrCodeBase64, err := s.boxQRCode(shortCode)
+		if err != nil {
+			return regenerated, err
+		}
+
+		if err := s.boxRepo.SetQRCode(boxID, qrCodeBase64, qrContent); err != nil {
+			return regenerated, err
+		}
+		regenerated++
+	}
+
+	return regenerated, nil
+}
+
+// BoxPageURL returns the frontend page of a box, found by its ID or short
+// code, for the QR code redirect
+func (s *QRService) BoxPageURL(ref string) (string, error) {
+	box, err := lookupBox(s.boxRepo, ref)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/box/%s", s.baseURL, box.ID), nil
+}
 
-	return fmt.Sprintf(svgTemplate, content), nil
+func encodeQRCodePNG(content string, size int) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, size)
 }
 
+// GetBoxByID returns a box by its ID or short code
 func (s *QRService) GetBoxByID(boxID string) (*models.Box, error) {
-	return s.boxRepo.GetByID(boxID)
+	return lookupBox(s.boxRepo, boxID)
+}
+
+// GetUserBox returns a box the user may view
+func (s *QRService) GetUserBox(userID string, boxID string) (*models.Box, error) {
+	return authorizeBox(s.boxRepo, s.workspaceRepo, userID, boxID, actionView)
 }
 
 func (s *QRService) GetUserBoxes(userID string) ([]*models.Box, error) {
 	return s.boxRepo.GetByUserID(userID)
 }
 
-func (s *QRService) UpdateBox(userID string, boxID string, request *models.UpdateBoxRequest) (*models.Box, error) {
-	// Get existing box to verify ownership
-	box, err := s.boxRepo.GetByID(boxID)
+// ListUserBoxes returns one page of the user's boxes with the requested ordering and filters
+func (s *QRService) ListUserBoxes(userID string, options models.BoxListOptions) (*models.BoxPage, error) {
+	return s.boxRepo.ListByUserID(userID, options)
+}
+
+// SearchBoxes finds the user's boxes whose name, description, room or items
+// match query, optionally only those with certain tags. Each hit lists the
+// boxes it is packed inside.
+func (s *QRService) SearchBoxes(userID string, query string, limit int, tags models.TagFilter) ([]*models.BoxSearchResult, error) {
+	results, err := s.boxRepo.Search(userID, query, limit, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	boxIDs := make([]string, len(results))
+	for i, result := range results {
+		boxIDs[i] = result.Box.ID
+	}
+
+	chains, err := s.boxRepo.GetContainmentChains(boxIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	if box.UserID != userID {
-		return nil, fmt.Errorf("unauthorized")
+	for _, result := range results {
+		result.ContainedIn = chains[result.Box.ID]
+		if result.ContainedIn == nil {
+			result.ContainedIn = []models.BoxCrumb{}
+		}
+	}
+
+	return results, nil
+}
+
+// GetBoxContents returns the box with its items and, recursively, the boxes
+// packed inside it
+func (s *QRService) GetBoxContents(box *models.Box) (*models.BoxContents, error) {
+	return s.boxContents(box, false)
+}
+
+// boxContents builds the tree of boxes inside box. For the public scan page
+// inner boxes follow their own visibility.
+func (s *QRService) boxContents(box *models.Box, public bool) (*models.BoxContents, error) {
+	descendants, err := s.boxRepo.GetDescendants(box.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := map[string]*models.BoxContents{box.ID: newBoxContents(box)}
+	for _, inner := range descendants {
+		nodes[inner.ID] = newBoxContents(inner)
+		if public && inner.Visibility != models.VisibilityPublic {
+			nodes[inner.ID] = lockedBoxContents(inner)
+		}
+	}
+
+	for _, inner := range descendants {
+		parent, ok := nodes[inner.ParentBoxID]
+		if !ok {
+			continue
+		}
+
+		// Boxes inside a private or locked box stay out of the tree with it
+		if public && (inner.Visibility == models.VisibilityPrivate || parent.Locked) {
+			continue
+		}
+
+		parent.Boxes = append(parent.Boxes, nodes[inner.ID])
+	}
+
+	return nodes[box.ID], nil
+}
+
+func newBoxContents(box *models.Box) *models.BoxContents {
+	items := box.Items
+	if items == nil {
+		items = []models.Item{}
+	}
+
+	return &models.BoxContents{
+		ID:          box.ID,
+		Name:        box.Name,
+		Description: box.Description,
+		Items:       items,
+		Boxes:       []*models.BoxContents{},
+	}
+}
+
+// lockedBoxContents shows only the name of a box whose details are hidden
+func lockedBoxContents(box *models.Box) *models.BoxContents {
+	return &models.BoxContents{
+		ID:     box.ID,
+		Name:   box.Name,
+		Locked: true,
+		Items:  []models.Item{},
+		Boxes:  []*models.BoxContents{},
+	}
+}
+
+// UpdateBox changes a box in one of the user's workspaces. Its location, outer
+// box and tags must come from the same workspace.
+func (s *QRService) UpdateBox(userID string, boxID string, request *models.UpdateBoxRequest) (*models.Box, error) {
+	// Get existing box to verify the user may edit it
+	box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, boxID, actionEdit)
+	if err != nil {
+		return nil, err
 	}
 
 	// Update box data
@@ -123,105 +478,232 @@ func (s *QRService) UpdateBox(userID string, boxID string, request *models.Updat
 		box.Description = request.Description
 	}
 
-	if request.Room != "" {
-		box.Room = request.Room
+	if err := setBoxVisibility(box, request.Visibility, request.PIN); err != nil {
+		return nil, err
 	}
 
+	// An explicit location wins over a free-text room
+	if request.LocationID != nil {
+		var location *models.Location
+		if *request.LocationID != "" {
+			location, err = workspaceLocation(s.locationRepo, s.workspaceRepo, userID, box.WorkspaceID, *request.LocationID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		setBoxLocation(box, location)
+	} else if strings.TrimSpace(request.Room) != "" {
+		location, err := roomLocation(s.locationRepo, userID, box.WorkspaceID, request.Room)
+		if err != nil {
+			return nil, err
+		}
+		setBoxLocation(box, location)
+	}
+
+	// An empty parent box ID takes the box out of the one it is in
+	if request.ParentBoxID != nil {
+		parentID := *request.ParentBoxID
+		if parentID != "" {
+			if parentID, err = s.checkBoxParent(userID, box.WorkspaceID, box.ID, parentID); err != nil {
+				return nil, err
+			}
+		}
+		box.ParentBoxID = parentID
+	}
+
+	// A tag list, even an empty one, replaces the box's tags
+	var tagIDs []string
+	if request.TagIDs != nil {
+		tags, err := ownedBoxTags(s.tagRepo, s.workspaceRepo, userID, box.WorkspaceID, *request.TagIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		tagIDs = make([]string, len(tags))
+		for i, tag := range tags {
+			tagIDs[i] = tag.ID
+		}
+		box.Tags = tags
+	}
+
+	// A new items list replaces the box contents
+	var items []models.Item
 	if request.Items != "" {
-		box.Items = processItemsList(request.Items)
+		items = processItemsList(box.ID, request.Items)
+		box.Items = items
 	}
 
-	// Save updated box
-	err = s.boxRepo.Update(box)
-	if err != nil {
+	// Save the box, its tags and its items in one transaction
+	if err := s.boxRepo.Update(box, tagIDs, items); err != nil {
 		return nil, err
 	}
 
 	return box, nil
 }
 
+// DeleteBox removes a box along with its photos. Only workspace owners may
+// delete boxes.
 func (s *QRService) DeleteBox(userID string, boxID string) error {
-	return s.boxRepo.Delete(boxID, userID)
+	box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, boxID, actionDelete)
+	if err != nil {
+		return err
+	}
+
+	if err := s.boxRepo.Delete(box.ID, userID); err != nil {
+		return err
+	}
+
+	deletePhotoFiles(s.blobs, box.Photos)
+	return nil
 }
 
 func (s *QRService) GetUserBoxCount(userID string) (int, error) {
 	return s.boxRepo.GetUserBoxCount(userID)
 }
 
+// GetTagCounts returns the user's tags with the number of boxes carrying each
+func (s *QRService) GetTagCounts(userID string) ([]*models.Tag, error) {
+	return s.tagRepo.GetTagsByUserID(userID)
+}
+
+// GetItemTotals returns the combined quantity of each kind of item the user owns
+func (s *QRService) GetItemTotals(userID string) ([]models.ItemTotal, error) {
+	return s.itemRepo.GetItemTotals(userID)
+}
+
 // AddItemToBox adds a single item to an existing box
 func (s *QRService) AddItemToBox(userID string, boxID string, item string) (*models.Box, error) {
-	// Get existing box to verify ownership
-	box, err := s.boxRepo.GetByID(boxID)
+	// Get existing box to verify the user may edit it
+	box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, boxID, actionEdit)
 	if err != nil {
-		return nil, fmt.Errorf("box not found")
+		return nil, err
 	}
 
-	if box.UserID != userID {
-		return nil, fmt.Errorf("unauthorized")
+	return addBoxItem(s.boxRepo, s.itemRepo, box, item)
+}
+
+// addBoxItem adds an item line after the box's existing items, reading any
+// leading quantity, and returns the updated box
+func addBoxItem(boxRepo repository.BoxStore, itemRepo repository.ItemStore, box *models.Box, line string) (*models.Box, error) {
+	name, quantity, unit := parseItemLine(line)
+	if err := itemRepo.CreateItem(newItem(box.ID, name, quantity, unit, "")); err != nil {
+		return nil, err
 	}
 
-	// Add the new item to the existing items
-	box.Items = append(box.Items, strings.TrimSpace(item))
+	return boxRepo.GetByID(box.ID)
+}
 
-	// Save updated box
-	err = s.boxRepo.Update(box)
+// RemoveItemFromBox removes the first item matching an item line from an existing box
+func (s *QRService) RemoveItemFromBox(userID string, boxID string, itemToRemove string) (*models.Box, error) {
+	// Get existing box to verify the user may edit it
+	box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, boxID, actionEdit)
 	if err != nil {
 		return nil, err
 	}
 
-	return box, nil
+	return removeBoxItem(s.boxRepo, s.itemRepo, box, itemToRemove)
 }
 
-// RemoveItemFromBox removes a single item from an existing box
-func (s *QRService) RemoveItemFromBox(userID string, boxID string, itemToRemove string) (*models.Box, error) {
-	// Get existing box to verify ownership
-	box, err := s.boxRepo.GetByID(boxID)
+// removeBoxItem removes the first item matching an item line from the box and
+// returns the updated box. The line is read the way addBoxItem reads it, so
+// "3x screws" removes the screws, and names match regardless of case.
+func removeBoxItem(boxRepo repository.BoxStore, itemRepo repository.ItemStore, box *models.Box, line string) (*models.Box, error) {
+	name, _, _ := parseItemLine(line)
+	for _, item := range box.Items {
+		if strings.EqualFold(strings.TrimSpace(item.Name), name) {
+			if err := itemRepo.DeleteItem(item.ID); err != nil {
+				return nil, err
+			}
+			return boxRepo.GetByID(box.ID)
+		}
+	}
+
+	return nil, fmt.Errorf("item not found")
+}
+
+// MoveItems moves items between two boxes the user can reach in one transaction, so
+// an item is never left out of both boxes if something fails halfway
+func (s *QRService) MoveItems(userID string, request *models.MoveItemsRequest) (*models.MoveItemsResponse, error) {
+	// The user must be able to edit both boxes
+	fromBox, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, request.FromBoxID, actionEdit)
 	if err != nil {
-		return nil, fmt.Errorf("box not found")
+		return nil, err
 	}
 
-	if box.UserID != userID {
-		return nil, fmt.Errorf("unauthorized")
+	toBox, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, request.ToBoxID, actionEdit)
+	if err != nil {
+		return nil, err
 	}
 
-	// Find and remove the item
-	itemToRemove = strings.TrimSpace(itemToRemove)
-	found := false
-	newItems := make([]string, 0)
+	// Compared once resolved, as either box may be given by its short code
+	if fromBox.ID == toBox.ID {
+		return nil, fmt.Errorf("source and destination boxes must differ")
+	}
 
-	for _, item := range box.Items {
-		if strings.TrimSpace(item) == itemToRemove && !found {
-			// Skip the first occurrence of the item
-			found = true
-			continue
-		}
-		newItems = append(newItems, item)
+	itemIDs, err := resolveItemIDs(fromBox, request.ItemIDs, request.Items)
+	if err != nil {
+		return nil, err
 	}
 
-	if !found {
-		return nil, fmt.Errorf("item not found")
+	if err := s.itemRepo.MoveItems(fromBox.ID, toBox.ID, itemIDs); err != nil {
+		return nil, err
 	}
 
-	// Update the box with the new items list
-	box.Items = newItems
+	fromBox, err = s.boxRepo.GetByID(fromBox.ID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Save updated box
-	err = s.boxRepo.Update(box)
+	toBox, err = s.boxRepo.GetByID(toBox.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return box, nil
+	return &models.MoveItemsResponse{FromBox: fromBox, ToBox: toBox}, nil
+}
+
+// resolveItemIDs turns item IDs and names into a list of distinct IDs from the
+// box. Each name picks the first matching item that hasn't been picked yet.
+func resolveItemIDs(box *models.Box, itemIDs []string, names []string) ([]string, error) {
+	picked := make(map[string]bool)
+	resolved := make([]string, 0, len(itemIDs)+len(names))
+
+	for _, id := range itemIDs {
+		if picked[id] {
+			continue
+		}
+		picked[id] = true
+		resolved = append(resolved, id)
+	}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, item := range box.Items {
+			if !picked[item.ID] && strings.TrimSpace(item.Name) == name {
+				picked[item.ID] = true
+				resolved = append(resolved, item.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("item not found")
+		}
+	}
+
+	return resolved, nil
 }
 
-// processItemsList converts a multi-line string into a cleaned list of items
-func processItemsList(itemsText string) []string {
+// processItemsList converts a multi-line string into a cleaned list of items for
+// a box. Lines may start with a quantity and unit, as in "3x screws" or "3 m of cable".
+func processItemsList(boxID string, itemsText string) []models.Item {
+	items := []models.Item{}
 	if strings.TrimSpace(itemsText) == "" {
-		return []string{}
+		return items
 	}
 
 	lines := strings.Split(itemsText, "\n")
-	var items []string
 
 	for _, line := range lines {
 		// Clean each line
@@ -229,7 +711,8 @@ func processItemsList(itemsText string) []string {
 
 		// Only add non-empty items (no need to remove prefixes since items come from frontend array)
 		if cleaned != "" {
-			items = append(items, cleaned)
+			name, quantity, unit := parseItemLine(cleaned)
+			items = append(items, *newItem(boxID, name, quantity, unit, ""))
 		}
 	}
 
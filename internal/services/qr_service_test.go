@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/skip2/go-qrcode"
+)
+
+// svgRun matches one run of dark modules in the SVG path
+var svgRun = regexp.MustCompile(`M(\d+),(\d+)h(\d+)v1h-(\d+)z`)
+
+// svgModules paints the runs of an SVG path back onto a grid of the given size
+func svgModules(t *testing.T, path string, modules int) [][]bool {
+	t.Helper()
+
+	grid := make([][]bool, modules)
+	for y := range grid {
+		grid[y] = make([]bool, modules)
+	}
+
+	runs := svgRun.FindAllStringSubmatch(path, -1)
+	if strings.Join(svgRun.FindAllString(path, -1), "") != path {
+		t.Fatalf("path has something other than module runs: %s", path)
+	}
+	for _, run := range runs {
+		var x, y, width, back int
+		fmt.Sscan(run[1]+" "+run[2]+" "+run[3]+" "+run[4], &x, &y, &width, &back)
+		if width != back || width == 0 {
+			t.Fatalf("run %s is not a closed rectangle", run[0])
+		}
+		for i := x; i < x+width; i++ {
+			if grid[y][i] {
+				t.Fatalf("run %s overlaps another", run[0])
+			}
+			grid[y][i] = true
+		}
+	}
+	return grid
+}
+
+func TestRenderQRCodeSVG(t *testing.T) {
+	for _, content := range []string{"https://example.com/box/1", strings.Repeat("a long box URL ", 20)} {
+		qr, err := qrcode.New(content, qrcode.Medium)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		bitmap := qr.Bitmap()
+		modules := len(bitmap)
+
+		svg := renderQRCodeSVG(bitmap, "256", qrBlack, qrWhite)
+
+		header := fmt.Sprintf(`width="256" height="256" viewBox="0 0 %d %d"`, modules, modules)
+		if !strings.Contains(svg, header) {
+			t.Errorf("svg does not start with %s: %.200s", header, svg)
+		}
+
+		path := regexp.MustCompile(`<path d="([^"]*)"`).FindStringSubmatch(svg)
+		if path == nil {
+			t.Fatalf("svg has no path: %.200s", svg)
+		}
+
+		grid := svgModules(t, path[1], modules)
+		for y := range bitmap {
+			for x := range bitmap[y] {
+				if grid[y][x] != bitmap[y][x] {
+					t.Fatalf("module %d,%d is %v in the svg, %v in the code", x, y, grid[y][x], bitmap[y][x])
+				}
+			}
+		}
+	}
+}
+
+func TestRemoveItemFromBoxMatchesParsedName(t *testing.T) {
+	w := newTestWorkspace(t)
+	created, err := w.qr.CreateBox("owner", &models.CreateBoxRequest{Name: "Toolbox", WorkspaceID: w.id, Items: "5 Screws\nhammer"})
+	if err != nil {
+		t.Fatalf("create box: %v", err)
+	}
+
+	box, err := w.qr.RemoveItemFromBox("owner", created.Box.ID, "3x screws")
+	if err != nil {
+		t.Fatalf("RemoveItemFromBox: %v", err)
+	}
+	if len(box.Items) != 1 || box.Items[0].Name != "hammer" {
+		t.Errorf("items = %v, want only the hammer", box.Items)
+	}
+
+	if _, err := w.qr.RemoveItemFromBox("owner", created.Box.ID, "screws"); err == nil || err.Error() != "item not found" {
+		t.Errorf("removing a missing item: err = %v, want item not found", err)
+	}
+}
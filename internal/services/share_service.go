@@ -0,0 +1,378 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+)
+
+// maxShareDuration caps how far ahead a share link can expire
+const maxShareDuration = 365 * 24 * time.Hour
+
+type ShareService struct {
+	baseURL       string
+	boxRepo       repository.BoxStore
+	itemRepo      repository.ItemStore
+	locationRepo  repository.LocationStore
+	shareRepo     repository.ShareStore
+	tagRepo       repository.TagStore
+	workspaceRepo repository.WorkspaceStore
+	secret        []byte
+}
+
+// NewShareService signs share tokens with secret. Without one a random secret
+// is used, so links stop working when the server restarts.
+func NewShareService(baseURL string, stores *repository.Stores, secret []byte) (*ShareService, error) {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate share token secret: %w", err)
+		}
+	}
+
+	return &ShareService{
+		baseURL:       baseURL,
+		boxRepo:       stores.Boxes,
+		itemRepo:      stores.Items,
+		locationRepo:  stores.Locations,
+		shareRepo:     stores.Shares,
+		tagRepo:       stores.Tags,
+		workspaceRepo: stores.Workspaces,
+		secret:        secret,
+	}, nil
+}
+
+// CreateShare creates a share link for a box, location or tag the user may edit
+func (s *ShareService) CreateShare(userID string, request *models.CreateShareRequest) (*models.ShareLink, error) {
+	scope := strings.TrimSpace(request.Scope)
+	workspaceID, targetID, name, err := s.shareTarget(userID, scope, request.TargetID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !request.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("share expiry must be in the future")
+	}
+	if request.ExpiresAt.After(now.Add(maxShareDuration)) {
+		return nil, fmt.Errorf("share expiry too far ahead")
+	}
+
+	// The token carries the expiry in whole seconds
+	share := &models.ShareLink{
+		ID:          uuid.New().String(),
+		WorkspaceID: workspaceID,
+		Scope:       scope,
+		TargetID:    targetID,
+		TargetName:  name,
+		CanEdit:     request.CanEdit,
+		CreatedBy:   userID,
+		CreatedAt:   now,
+		ExpiresAt:   time.Unix(request.ExpiresAt.Unix(), 0),
+	}
+
+	if err := s.shareRepo.CreateShare(share); err != nil {
+		return nil, err
+	}
+
+	s.setToken(share)
+	return share, nil
+}
+
+// GetShares returns the share links of the workspaces the user may edit,
+// including expired and revoked ones
+func (s *ShareService) GetShares(userID string) ([]*models.ShareLink, error) {
+	workspaces, err := s.workspaceRepo.GetWorkspacesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	editable := make(map[string]bool)
+	for _, workspace := range workspaces {
+		editable[workspace.ID] = roleAllows(memberRole(workspace, userID), actionEdit)
+	}
+
+	shares, err := s.shareRepo.GetSharesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]*models.ShareLink, 0, len(shares))
+	for _, share := range shares {
+		if !editable[share.WorkspaceID] {
+			continue
+		}
+		share.TargetName = s.targetName(share)
+		s.setToken(share)
+		visible = append(visible, share)
+	}
+
+	return visible, nil
+}
+
+// RevokeShare puts a share link of a workspace the user may edit on the
+// revocation list
+func (s *ShareService) RevokeShare(userID string, shareID string) error {
+	share, err := s.shareRepo.GetShareByID(shareID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeReference(s.workspaceRepo, userID, share.WorkspaceID, actionEdit, "share not found"); err != nil {
+		return err
+	}
+
+	return s.shareRepo.RevokeShare(share.ID)
+}
+
+// ResolveShare returns the live share link for a token with the boxes it
+// covers. Location and tag links leave out private boxes; a link to one box
+// shows it whatever its visibility.
+func (s *ShareService) ResolveShare(token string) (*models.ShareLink, []*models.Box, error) {
+	share, err := s.verifyToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	boxes, err := s.sharedBoxes(share)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	share.TargetName = s.targetName(share)
+	sharePhotos(token, boxes...)
+	return share, boxes, nil
+}
+
+// AddSharedItem adds an item to a box through a share link with edit rights
+func (s *ShareService) AddSharedItem(token, boxID, item string) (*models.Box, error) {
+	box, err := s.editableBox(token, boxID)
+	if err != nil {
+		return nil, err
+	}
+
+	if box, err = addBoxItem(s.boxRepo, s.itemRepo, box, item); err != nil {
+		return nil, err
+	}
+
+	sharePhotos(token, box)
+	return box, nil
+}
+
+// RemoveSharedItem removes an item from a box through a share link with edit rights
+func (s *ShareService) RemoveSharedItem(token, boxID, item string) (*models.Box, error) {
+	box, err := s.editableBox(token, boxID)
+	if err != nil {
+		return nil, err
+	}
+
+	if box, err = removeBoxItem(s.boxRepo, s.itemRepo, box, item); err != nil {
+		return nil, err
+	}
+
+	sharePhotos(token, box)
+	return box, nil
+}
+
+// editableBox returns a box covered by a share link that allows editing
+func (s *ShareService) editableBox(token, boxID string) (*models.Box, error) {
+	share, err := s.verifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !share.CanEdit {
+		return nil, fmt.Errorf("share is read only")
+	}
+
+	target, err := lookupBox(s.boxRepo, boxID)
+	if err != nil {
+		return nil, err
+	}
+
+	boxes, err := s.sharedBoxes(share)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, box := range boxes {
+		if box.ID == target.ID {
+			return box, nil
+		}
+	}
+
+	return nil, fmt.Errorf("box not found")
+}
+
+// coversBox reports whether token is a live share link giving access to the box
+func (s *ShareService) coversBox(token, boxID string) bool {
+	share, err := s.verifyToken(token)
+	if err != nil {
+		return false
+	}
+
+	boxes, err := s.sharedBoxes(share)
+	if err != nil {
+		return false
+	}
+
+	for _, box := range boxes {
+		if box.ID == boxID {
+			return true
+		}
+	}
+	return false
+}
+
+// sharePhotos adds the share token to the photo URLs of shared boxes, which
+// is what lets the photos of boxes that aren't public load
+func sharePhotos(token string, boxes ...*models.Box) {
+	for _, box := range boxes {
+		for i := range box.Photos {
+			box.Photos[i].AddURLParam("share", strings.TrimSpace(token))
+		}
+	}
+}
+
+// sharedBoxes returns the boxes a share link gives access to
+func (s *ShareService) sharedBoxes(share *models.ShareLink) ([]*models.Box, error) {
+	boxes, err := s.shareRepo.GetSharedBoxes(share)
+	if err != nil {
+		return nil, err
+	}
+
+	if share.Scope == models.ShareScopeBox {
+		return boxes, nil
+	}
+
+	shown := make([]*models.Box, 0, len(boxes))
+	for _, box := range boxes {
+		if box.Visibility != models.VisibilityPrivate {
+			shown = append(shown, box)
+		}
+	}
+	return shown, nil
+}
+
+// shareTarget checks that the user may edit the box, location or tag being
+// shared and returns its workspace, ID and name. Boxes can be given by their
+// short code.
+func (s *ShareService) shareTarget(userID, scope, targetID string) (workspaceID, id, name string, err error) {
+	switch scope {
+	case models.ShareScopeBox:
+		box, err := authorizeBox(s.boxRepo, s.workspaceRepo, userID, targetID, actionEdit)
+		if err != nil {
+			return "", "", "", err
+		}
+		return box.WorkspaceID, box.ID, box.Name, nil
+
+	case models.ShareScopeLocation:
+		location, err := s.locationRepo.GetLocationByID(targetID)
+		if err != nil {
+			return "", "", "", fmt.Errorf("location not found")
+		}
+		if err := authorizeReference(s.workspaceRepo, userID, location.WorkspaceID, actionEdit, "location not found"); err != nil {
+			return "", "", "", err
+		}
+		return location.WorkspaceID, location.ID, location.Path.String(), nil
+
+	case models.ShareScopeTag:
+		tag, err := s.tagRepo.GetTagByID(targetID)
+		if err != nil {
+			return "", "", "", fmt.Errorf("tag not found")
+		}
+		if err := authorizeReference(s.workspaceRepo, userID, tag.WorkspaceID, actionEdit, "tag not found"); err != nil {
+			return "", "", "", err
+		}
+		return tag.WorkspaceID, tag.ID, tag.Name, nil
+	}
+
+	return "", "", "", fmt.Errorf("invalid share scope")
+}
+
+// targetName looks up the current name of what a share link covers. It is
+// empty once that has been deleted.
+func (s *ShareService) targetName(share *models.ShareLink) string {
+	switch share.Scope {
+	case models.ShareScopeBox:
+		if box, err := s.boxRepo.GetByID(share.TargetID); err == nil {
+			return box.Name
+		}
+	case models.ShareScopeLocation:
+		if location, err := s.locationRepo.GetLocationByID(share.TargetID); err == nil {
+			return location.Path.String()
+		}
+	case models.ShareScopeTag:
+		if tag, err := s.tagRepo.GetTagByID(share.TargetID); err == nil {
+			return tag.Name
+		}
+	}
+	return ""
+}
+
+// setToken fills in the signed token and link URL of a share link. The token
+// is the link's ID and expiry followed by their HMAC-SHA256 signature.
+func (s *ShareService) setToken(share *models.ShareLink) {
+	payload := share.ID + "." + strconv.FormatInt(share.ExpiresAt.Unix(), 10)
+	share.Token = base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+	share.URL = fmt.Sprintf("%s/share/%s", s.baseURL, share.Token)
+}
+
+// verifyToken checks a token's signature and returns its share link as long
+// as the link hasn't expired or been revoked
+func (s *ShareService) verifyToken(token string) (*models.ShareLink, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return nil, fmt.Errorf("share not found")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("share not found")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, s.sign(string(payload))) {
+		return nil, fmt.Errorf("share not found")
+	}
+
+	id, expiresText, ok := strings.Cut(string(payload), ".")
+	if !ok {
+		return nil, fmt.Errorf("share not found")
+	}
+
+	expires, err := strconv.ParseInt(expiresText, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("share not found")
+	}
+
+	if !time.Unix(expires, 0).After(time.Now()) {
+		return nil, fmt.Errorf("share expired")
+	}
+
+	share, err := s.shareRepo.GetShareByID(id)
+	if err != nil || share.ExpiresAt.Unix() != expires {
+		return nil, fmt.Errorf("share not found")
+	}
+
+	if share.RevokedAt != nil {
+		return nil, fmt.Errorf("share revoked")
+	}
+
+	return share, nil
+}
+
+func (s *ShareService) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+)
+
+// shortCodeAlphabet is the invitation code alphabet, without 0/O and 1/I
+const shortCodeAlphabet = invitationAlphabet
+
+// A short code is shortCodeLength random characters and a check character,
+// shown in two groups of three as in K7F-3QX
+const shortCodeLength = 5
+
+// maxShortCodeAttempts bounds the retries when a random code is already taken
+const maxShortCodeAttempts = 10
+
+// newShortCode returns a random short code with its check character
+func newShortCode() (string, error) {
+	max := big.NewInt(int64(len(shortCodeAlphabet)))
+	code := make([]byte, shortCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate short code: %w", err)
+		}
+		code[i] = shortCodeAlphabet[n.Int64()]
+	}
+
+	raw := string(code) + string(shortCodeCheck(string(code)))
+	return raw[:3] + "-" + raw[3:], nil
+}
+
+// shortCodeCheck computes the Luhn mod N check character for code, which
+// catches any single mistyped character and most swapped neighbours
+func shortCodeCheck(code string) byte {
+	n := len(shortCodeAlphabet)
+	sum := 0
+	factor := 2
+	for i := len(code) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(shortCodeAlphabet, code[i])
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+	return shortCodeAlphabet[(n-sum%n)%n]
+}
+
+// parseShortCode normalises a typed or scanned short code to its stored form,
+// upper-casing it and regrouping it around a dash. It reports false when the
+// code has the wrong length, a character outside the alphabet or a bad check
+// character.
+func parseShortCode(input string) (string, bool) {
+	raw := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(input)))
+
+	if len(raw) != shortCodeLength+1 {
+		return "", false
+	}
+
+	for i := 0; i < len(raw); i++ {
+		if strings.IndexByte(shortCodeAlphabet, raw[i]) < 0 {
+			return "", false
+		}
+	}
+
+	if shortCodeCheck(raw[:shortCodeLength]) != raw[shortCodeLength] {
+		return "", false
+	}
+
+	return raw[:3] + "-" + raw[3:], true
+}
+
+// uniqueShortCode returns a short code no box has yet
+func uniqueShortCode(boxRepo repository.BoxStore) (string, error) {
+	for attempt := 0; attempt < maxShortCodeAttempts; attempt++ {
+		code, err := newShortCode()
+		if err != nil {
+			return "", err
+		}
+
+		_, err = boxRepo.GetByShortCode(code)
+		if err == nil {
+			continue
+		}
+		if err.Error() != "box not found" {
+			return "", err
+		}
+		return code, nil
+	}
+
+	return "", fmt.Errorf("failed to generate a unique short code")
+}
+
+// lookupBox finds a box by its UUID or by its short code
+func lookupBox(boxRepo repository.BoxStore, ref string) (*models.Box, error) {
+	ref = strings.TrimSpace(ref)
+	if _, err := uuid.Parse(ref); err == nil {
+		return boxRepo.GetByID(ref)
+	}
+
+	code, ok := parseShortCode(ref)
+	if !ok {
+		return nil, fmt.Errorf("box not found")
+	}
+
+	return boxRepo.GetByShortCode(code)
+}
+
+// AssignShortCodes gives a short code to every box created before short codes
+// existed and returns how many it assigned
+func (s *QRService) AssignShortCodes() (int, error) {
+	ids, err := s.boxRepo.GetBoxIDsWithoutShortCode()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		code, err := uniqueShortCode(s.boxRepo)
+		if err != nil {
+			return i, err
+		}
+
+		if err := s.boxRepo.SetShortCode(id, code); err != nil {
+			return i, err
+		}
+	}
+
+	return len(ids), nil
+}
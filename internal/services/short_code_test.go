@@ -0,0 +1,180 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/qr-boxes/backend/internal/models"
+)
+
+var shortCodePattern = regexp.MustCompile(`^[` + shortCodeAlphabet + `]{3}-[` + shortCodeAlphabet + `]{3}$`)
+
+// withCheck completes five code characters with their check character, in the stored form
+func withCheck(code string) string {
+	raw := code + string(shortCodeCheck(code))
+	return raw[:3] + "-" + raw[3:]
+}
+
+func TestNewShortCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 500; i++ {
+		code, err := newShortCode()
+		if err != nil {
+			t.Fatalf("newShortCode: %v", err)
+		}
+
+		if !shortCodePattern.MatchString(code) {
+			t.Fatalf("code %q is not in the XXX-XXX form", code)
+		}
+
+		parsed, ok := parseShortCode(code)
+		if !ok || parsed != code {
+			t.Fatalf("parseShortCode(%q) = %q, %v", code, parsed, ok)
+		}
+
+		seen[code] = true
+	}
+
+	// 32^5 codes make a repeat among 500 very unlikely
+	if len(seen) < 495 {
+		t.Errorf("only %d distinct codes out of 500", len(seen))
+	}
+}
+
+func TestParseShortCode(t *testing.T) {
+	code := withCheck("K7F3Q")
+
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{code, code, true},
+		{strings.ToLower(code), code, true},
+		{strings.ReplaceAll(code, "-", ""), code, true},
+		{"  " + code[:3] + " " + code[4:] + " ", code, true},
+		{code[:2] + "-" + code[2:3] + code[4:], code, true},
+		{code[:6], "", false},
+		{code + "A", "", false},
+		{"", "", false},
+		{"K0F-3QX", "", false},
+		{"K1F-3QX", "", false},
+		{"KOF-3QX", "", false},
+		{"K7F-3Q!", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseShortCode(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseShortCode(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestShortCodeCheckCatchesTypos(t *testing.T) {
+	codes := []string{"K7F3Q", "AAAAA", "Z2Z2Z", "HJKMN", "98765"}
+
+	for _, code := range codes {
+		raw := strings.ReplaceAll(withCheck(code), "-", "")
+
+		// Every single mistyped character, check character included
+		for pos := 0; pos < len(raw); pos++ {
+			for _, r := range shortCodeAlphabet {
+				if byte(r) == raw[pos] {
+					continue
+				}
+				typo := raw[:pos] + string(r) + raw[pos+1:]
+				if _, ok := parseShortCode(typo); ok {
+					t.Errorf("typo %s of %s was accepted", typo, raw)
+				}
+			}
+		}
+	}
+}
+
+func TestShortCodeCheckCatchesMostSwaps(t *testing.T) {
+	missed, total := 0, 0
+	for _, a := range shortCodeAlphabet {
+		for _, b := range shortCodeAlphabet {
+			// Put a and b next to each other at every position, the last
+			// pair being b and the check character
+			for pos := 0; pos < shortCodeLength; pos++ {
+				code := []byte("K7F3Q")
+				code[pos] = byte(a)
+				if pos+1 < shortCodeLength {
+					code[pos+1] = byte(b)
+				}
+				raw := []byte(strings.ReplaceAll(withCheck(string(code)), "-", ""))
+
+				if raw[pos] == raw[pos+1] {
+					continue
+				}
+				raw[pos], raw[pos+1] = raw[pos+1], raw[pos]
+
+				total++
+				if _, ok := parseShortCode(string(raw)); ok {
+					missed++
+				}
+			}
+		}
+	}
+
+	if rate := float64(missed) / float64(total); rate > 0.05 {
+		t.Errorf("%d of %d swapped neighbours went unnoticed (%.1f%%)", missed, total, rate*100)
+	}
+}
+
+func TestLookupBoxByShortCode(t *testing.T) {
+	w := newTestWorkspace(t)
+	box := w.newBox(t)
+
+	if !shortCodePattern.MatchString(box.ShortCode) {
+		t.Fatalf("new box has short code %q", box.ShortCode)
+	}
+
+	typo := []byte(box.ShortCode)
+	typo[0] = shortCodeAlphabet[(strings.IndexByte(shortCodeAlphabet, typo[0])+1)%len(shortCodeAlphabet)]
+
+	tests := []struct {
+		ref   string
+		found bool
+	}{
+		{box.ID, true},
+		{box.ShortCode, true},
+		{strings.ToLower(strings.ReplaceAll(box.ShortCode, "-", "")), true},
+		{string(typo), false},
+		{"not a code", false},
+	}
+
+	for _, tt := range tests {
+		got, err := lookupBox(w.stores.Boxes, tt.ref)
+		switch {
+		case tt.found && (err != nil || got.ID != box.ID):
+			t.Errorf("lookupBox(%q) = %v, %v; want box %s", tt.ref, got, err, box.ID)
+		case !tt.found && (err == nil || err.Error() != "box not found"):
+			t.Errorf("lookupBox(%q) error = %v, want \"box not found\"", tt.ref, err)
+		}
+	}
+}
+
+func TestCreateBoxesGetDistinctShortCodes(t *testing.T) {
+	w := newTestWorkspace(t)
+
+	response, err := w.qr.CreateBoxes("owner", &models.CreateBoxesRequest{Count: 50, WorkspaceID: w.id})
+	if err != nil {
+		t.Fatalf("CreateBoxes: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, box := range response.Boxes {
+		if seen[box.ShortCode] {
+			t.Errorf("short code %s given to two boxes", box.ShortCode)
+		}
+		seen[box.ShortCode] = true
+
+		if !strings.HasSuffix(box.QRCodeURL, "/q/"+box.ShortCode) {
+			t.Errorf("QR code of %s points at %s", box.ShortCode, box.QRCodeURL)
+		}
+	}
+}
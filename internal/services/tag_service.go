@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+)
+
+// defaultTagColor is used for tags created without a colour
+const defaultTagColor = "#9ca3af"
+
+// tagColorPattern accepts #rgb and #rrggbb hex colours
+var tagColorPattern = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+type TagService struct {
+	tagRepo       repository.TagStore
+	workspaceRepo repository.WorkspaceStore
+}
+
+func NewTagService(stores *repository.Stores) *TagService {
+	return &TagService{
+		tagRepo:       stores.Tags,
+		workspaceRepo: stores.Workspaces,
+	}
+}
+
+// CreateTag adds a tag with a name that is unique within its workspace
+func (s *TagService) CreateTag(userID string, request *models.CreateTagRequest) (*models.Tag, error) {
+	name := strings.TrimSpace(request.Name)
+
+	color, err := normalizeTagColor(request.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceID, err := resolveWorkspace(s.workspaceRepo, userID, strings.TrimSpace(request.WorkspaceID))
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.tagRepo.GetTagsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if tagNameTaken(tags, workspaceID, "", name) {
+		return nil, fmt.Errorf("tag already exists")
+	}
+
+	now := time.Now()
+	tag := &models.Tag{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Color:       color,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.tagRepo.CreateTag(tag); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// GetUserTags returns the tags of all the user's workspaces ordered by name,
+// with box counts
+func (s *TagService) GetUserTags(userID string) ([]*models.Tag, error) {
+	return s.tagRepo.GetTagsByUserID(userID)
+}
+
+// UpdateTag renames or recolours one of the user's tags. Renaming a tag to
+// the name of another one is refused; merge them instead.
+func (s *TagService) UpdateTag(userID string, tagID string, request *models.UpdateTagRequest) (*models.Tag, error) {
+	tag, err := ownedTag(s.tagRepo, s.workspaceRepo, userID, tagID, actionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Name != nil {
+		tags, err := s.tagRepo.GetTagsByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		tag.Name = strings.TrimSpace(*request.Name)
+		if tagNameTaken(tags, tag.WorkspaceID, tag.ID, tag.Name) {
+			return nil, fmt.Errorf("tag already exists")
+		}
+	}
+
+	if request.Color != nil {
+		tag.Color, err = normalizeTagColor(*request.Color)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.tagRepo.UpdateTag(tag); err != nil {
+		return nil, err
+	}
+
+	return s.tagRepo.GetTagByID(tag.ID)
+}
+
+// MergeTags folds the source tags into the target tag. All of them must be in
+// the same workspace.
+func (s *TagService) MergeTags(userID string, request *models.MergeTagsRequest) (*models.Tag, error) {
+	target, err := ownedTag(s.tagRepo, s.workspaceRepo, userID, request.TargetID, actionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceIDs, err := ownedTagIDs(s.tagRepo, s.workspaceRepo, userID, target.WorkspaceID, request.SourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range sourceIDs {
+		if id == target.ID {
+			return nil, fmt.Errorf("tag cannot be merged into itself")
+		}
+	}
+
+	if err := s.tagRepo.MergeTags(target.ID, sourceIDs); err != nil {
+		return nil, err
+	}
+
+	return s.tagRepo.GetTagByID(target.ID)
+}
+
+// DeleteTag removes one of the user's tags from all boxes and deletes it
+func (s *TagService) DeleteTag(userID string, tagID string) error {
+	if _, err := ownedTag(s.tagRepo, s.workspaceRepo, userID, tagID, actionEdit); err != nil {
+		return err
+	}
+
+	return s.tagRepo.DeleteTag(tagID)
+}
+
+// ownedTag loads a tag and checks that the user may perform act in its workspace
+func ownedTag(tagRepo repository.TagStore, workspaceRepo repository.WorkspaceStore, userID string, tagID string, act action) (*models.Tag, error) {
+	tag, err := tagRepo.GetTagByID(tagID)
+	if err != nil {
+		return nil, fmt.Errorf("tag not found")
+	}
+
+	if err := authorizeReference(workspaceRepo, userID, tag.WorkspaceID, act, "tag not found"); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// ownedTagIDs checks that every tag is in the given workspace, which the user
+// belongs to, and drops repeats
+func ownedTagIDs(tagRepo repository.TagStore, workspaceRepo repository.WorkspaceStore, userID, workspaceID string, tagIDs []string) ([]string, error) {
+	tags, err := ownedBoxTags(tagRepo, workspaceRepo, userID, workspaceID, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make([]string, len(tags))
+	for i, tag := range tags {
+		owned[i] = tag.ID
+	}
+	return owned, nil
+}
+
+// ownedBoxTags is ownedTagIDs returning the tags as shown on a box, by name
+func ownedBoxTags(tagRepo repository.TagStore, workspaceRepo repository.WorkspaceStore, userID, workspaceID string, tagIDs []string) ([]models.BoxTag, error) {
+	seen := make(map[string]bool, len(tagIDs))
+	owned := make([]models.BoxTag, 0, len(tagIDs))
+	for _, id := range tagIDs {
+		id = strings.TrimSpace(id)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		tag, err := ownedTag(tagRepo, workspaceRepo, userID, id, actionView)
+		if err != nil {
+			return nil, err
+		}
+		if tag.WorkspaceID != workspaceID {
+			return nil, fmt.Errorf("tag not found")
+		}
+		owned = append(owned, models.BoxTag{ID: tag.ID, Name: tag.Name, Color: tag.Color})
+	}
+
+	sort.Slice(owned, func(i, j int) bool {
+		a, b := strings.ToLower(owned[i].Name), strings.ToLower(owned[j].Name)
+		if a != b {
+			return a < b
+		}
+		return owned[i].ID < owned[j].ID
+	})
+	return owned, nil
+}
+
+// normalizeTagColor lower-cases a hex colour, defaulting an empty one
+func normalizeTagColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color == "" {
+		return defaultTagColor, nil
+	}
+
+	if !tagColorPattern.MatchString(color) {
+		return "", fmt.Errorf("invalid tag color")
+	}
+
+	return color, nil
+}
+
+// tagNameTaken reports whether another tag in the workspace already uses name
+func tagNameTaken(tags []*models.Tag, workspaceID, tagID, name string) bool {
+	for _, tag := range tags {
+		if tag.WorkspaceID == workspaceID && tag.ID != tagID && strings.EqualFold(tag.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -77,4 +77,25 @@ func (s *UserService) GetUserProfile(ctx context.Context, userID string) (*UserP
 	profile.MemberSince = time.Unix(user.CreatedAt, 0).Format("2006-01-02")
 
 	return profile, nil
-}
\ No newline at end of file
+}
+// GetUserEmails returns the user's verified email addresses, used to match
+// invitations sent to an email
+func (s *UserService) GetUserEmails(ctx context.Context, userID string) ([]string, error) {
+	clerk.SetKey(s.clerkSecretKey)
+
+	req := clerk.NewAPIRequest("GET", "/users/"+userID)
+
+	var user clerk.User
+	if err := clerk.GetBackend().Call(ctx, req, &user); err != nil {
+		return nil, fmt.Errorf("failed to fetch user from Clerk: %w", err)
+	}
+
+	emails := make([]string, 0, len(user.EmailAddresses))
+	for _, address := range user.EmailAddresses {
+		if address.Verification != nil && address.Verification.Status == "verified" {
+			emails = append(emails, address.EmailAddress)
+		}
+	}
+
+	return emails, nil
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qr-boxes/backend/internal/models"
+	"github.com/qr-boxes/backend/internal/repository"
+)
+
+// defaultWorkspaceName names the workspace created for a user who has none
+const defaultWorkspaceName = "My boxes"
+
+// invitationTTL is how long an invitation can be accepted after it is created
+const invitationTTL = 7 * 24 * time.Hour
+
+// invitationAlphabet leaves out characters that are easy to misread, such as
+// 0/O and 1/I
+const invitationAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const invitationCodeLength = 10
+
+type WorkspaceService struct {
+	workspaceRepo repository.WorkspaceStore
+}
+
+func NewWorkspaceService(stores *repository.Stores) *WorkspaceService {
+	return &WorkspaceService{
+		workspaceRepo: stores.Workspaces,
+	}
+}
+
+// CreateWorkspace adds a workspace with the user as its only member
+func (s *WorkspaceService) CreateWorkspace(userID string, request *models.CreateWorkspaceRequest) (*models.Workspace, error) {
+	return createWorkspace(s.workspaceRepo, userID, strings.TrimSpace(request.Name))
+}
+
+// GetUserWorkspaces returns the workspaces the user belongs to in the order
+// they joined them
+func (s *WorkspaceService) GetUserWorkspaces(userID string) ([]*models.Workspace, error) {
+	return s.workspaceRepo.GetWorkspacesByUserID(userID)
+}
+
+// UpdateWorkspace renames a workspace the user owns
+func (s *WorkspaceService) UpdateWorkspace(userID string, workspaceID string, request *models.UpdateWorkspaceRequest) (*models.Workspace, error) {
+	workspace, err := s.memberWorkspace(userID, workspaceID, actionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace.Name = strings.TrimSpace(request.Name)
+	if err := s.workspaceRepo.UpdateWorkspace(workspace); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// CreateInvitation creates a single-use invitation to a workspace the user
+// owns. With an email only a user with that address can accept it.
+func (s *WorkspaceService) CreateInvitation(userID string, request *models.CreateInvitationRequest) (*models.WorkspaceInvitation, error) {
+	workspace, err := s.memberWorkspace(userID, request.WorkspaceID, actionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	role := strings.TrimSpace(request.Role)
+	if role == "" {
+		role = models.RoleEditor
+	}
+	if !validRole(role) {
+		return nil, fmt.Errorf("invalid role")
+	}
+
+	code, err := newInvitationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation := &models.WorkspaceInvitation{
+		ID:            uuid.New().String(),
+		WorkspaceID:   workspace.ID,
+		WorkspaceName: workspace.Name,
+		Code:          code,
+		Email:         strings.ToLower(strings.TrimSpace(request.Email)),
+		Role:          role,
+		InvitedBy:     userID,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(invitationTTL),
+	}
+
+	if err := s.workspaceRepo.CreateInvitation(invitation); err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// GetPendingInvitations returns the open invitations addressed to any of the
+// user's email addresses
+func (s *WorkspaceService) GetPendingInvitations(emails []string) ([]*models.WorkspaceInvitation, error) {
+	return s.workspaceRepo.GetPendingInvitationsByEmails(emails)
+}
+
+// JoinWorkspace accepts an invitation by its code and returns the workspace
+// joined. emails are the user's addresses, checked against invitations sent
+// to an email.
+func (s *WorkspaceService) JoinWorkspace(userID string, emails []string, code string) (*models.Workspace, error) {
+	invitation, err := s.workspaceRepo.GetInvitationByCode(normalizeInvitationCode(code))
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.AcceptedBy != "" {
+		return nil, fmt.Errorf("invitation already used")
+	}
+
+	if !invitation.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("invitation expired")
+	}
+
+	if invitation.Email != "" && !containsFold(emails, invitation.Email) {
+		return nil, fmt.Errorf("invitation is for another email")
+	}
+
+	if err := s.workspaceRepo.AcceptInvitation(invitation.ID, userID); err != nil {
+		if err.Error() == "invitation not found" {
+			return nil, fmt.Errorf("invitation already used")
+		}
+		return nil, err
+	}
+
+	return s.workspaceRepo.GetWorkspaceByID(invitation.WorkspaceID)
+}
+
+// LeaveWorkspace removes the user from a workspace. The last member can't
+// leave, and neither can the last owner while others remain, so a workspace
+// and its boxes always have someone to manage them.
+func (s *WorkspaceService) LeaveWorkspace(userID string, workspaceID string) error {
+	workspace, err := s.memberWorkspace(userID, workspaceID, actionView)
+	if err != nil {
+		return err
+	}
+
+	if len(workspace.Members) <= 1 {
+		return fmt.Errorf("last member cannot leave")
+	}
+
+	if isLastOwner(workspace, userID) {
+		return fmt.Errorf("workspace must keep an owner")
+	}
+
+	return s.workspaceRepo.RemoveWorkspaceMember(workspace.ID, userID)
+}
+
+// UpdateMemberRole changes the role of a member of a workspace the user owns
+func (s *WorkspaceService) UpdateMemberRole(userID, workspaceID, memberID string, request *models.UpdateMemberRoleRequest) (*models.Workspace, error) {
+	workspace, err := s.memberWorkspace(userID, workspaceID, actionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	role := strings.TrimSpace(request.Role)
+	if !validRole(role) {
+		return nil, fmt.Errorf("invalid role")
+	}
+
+	if memberRole(workspace, memberID) == "" {
+		return nil, fmt.Errorf("member not found")
+	}
+
+	if role != models.RoleOwner && isLastOwner(workspace, memberID) {
+		return nil, fmt.Errorf("workspace must keep an owner")
+	}
+
+	if err := s.workspaceRepo.SetWorkspaceMemberRole(workspace.ID, memberID, role); err != nil {
+		return nil, err
+	}
+
+	return s.workspaceRepo.GetWorkspaceByID(workspace.ID)
+}
+
+// RemoveMember takes someone out of a workspace the user owns
+func (s *WorkspaceService) RemoveMember(userID, workspaceID, memberID string) error {
+	workspace, err := s.memberWorkspace(userID, workspaceID, actionManage)
+	if err != nil {
+		return err
+	}
+
+	if memberRole(workspace, memberID) == "" {
+		return fmt.Errorf("member not found")
+	}
+
+	if isLastOwner(workspace, memberID) {
+		return fmt.Errorf("workspace must keep an owner")
+	}
+
+	return s.workspaceRepo.RemoveWorkspaceMember(workspace.ID, memberID)
+}
+
+// memberWorkspace loads a workspace and checks that the user's role in it
+// allows act. Users outside the workspace get "workspace not found".
+func (s *WorkspaceService) memberWorkspace(userID string, workspaceID string, act action) (*models.Workspace, error) {
+	workspace, err := s.workspaceRepo.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("workspace not found")
+	}
+
+	role := memberRole(workspace, userID)
+	if role == "" {
+		return nil, fmt.Errorf("workspace not found")
+	}
+
+	if !roleAllows(role, act) {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	return workspace, nil
+}
+
+// memberRole returns the user's role in the workspace, or "" for non-members
+func memberRole(workspace *models.Workspace, userID string) string {
+	for _, member := range workspace.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// isLastOwner reports whether userID is the workspace's only owner
+func isLastOwner(workspace *models.Workspace, userID string) bool {
+	if memberRole(workspace, userID) != models.RoleOwner {
+		return false
+	}
+
+	for _, member := range workspace.Members {
+		if member.UserID != userID && member.Role == models.RoleOwner {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveWorkspace picks the workspace for something the user is creating:
+// workspaceID when set and the user may edit it, otherwise their default
+func resolveWorkspace(workspaceRepo repository.WorkspaceStore, userID, workspaceID string) (string, error) {
+	if workspaceID != "" {
+		if err := authorizeReference(workspaceRepo, userID, workspaceID, actionEdit, "workspace not found"); err != nil {
+			return "", err
+		}
+		return workspaceID, nil
+	}
+
+	return defaultWorkspace(workspaceRepo, userID)
+}
+
+// defaultWorkspace returns the first workspace the user joined that they may
+// edit, creating a personal one for users who have none yet
+func defaultWorkspace(workspaceRepo repository.WorkspaceStore, userID string) (string, error) {
+	workspaces, err := workspaceRepo.GetWorkspacesByUserID(userID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, workspace := range workspaces {
+		if roleAllows(memberRole(workspace, userID), actionEdit) {
+			return workspace.ID, nil
+		}
+	}
+
+	workspace, err := createWorkspace(workspaceRepo, userID, defaultWorkspaceName)
+	if err != nil {
+		return "", err
+	}
+
+	return workspace.ID, nil
+}
+
+func createWorkspace(workspaceRepo repository.WorkspaceStore, userID, name string) (*models.Workspace, error) {
+	now := time.Now()
+	workspace := &models.Workspace{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := workspaceRepo.CreateWorkspace(workspace); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// newInvitationCode returns a random code that is easy to read out or type
+func newInvitationCode() (string, error) {
+	max := big.NewInt(int64(len(invitationAlphabet)))
+	code := make([]byte, invitationCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate invitation code: %w", err)
+		}
+		code[i] = invitationAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeInvitationCode upper-cases a typed code and drops the spaces and
+// dashes people add when copying it
+func normalizeInvitationCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimSpace(candidate), value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+)
+
+// BlobStore keeps uploaded files such as box photos. Keys are slash-separated
+// paths chosen by the caller.
+type BlobStore interface {
+	Put(key string, data io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Supported blob store backends
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Config selects and configures a blob store backend
+type Config struct {
+	Backend     string
+	LocalDir    string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3UseSSL    bool
+}
+
+// NewBlobStore opens the blob store described by config
+func NewBlobStore(config Config) (BlobStore, error) {
+	switch config.Backend {
+	case "", BackendLocal:
+		return NewLocalBlobStore(config.LocalDir)
+	case BackendS3:
+		return NewS3BlobStore(config)
+	default:
+		return nil, fmt.Errorf("unknown blob store backend %q", config.Backend)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,44 +12,107 @@ import (
 	"github.com/qr-boxes/backend/internal/repository"
 	"github.com/qr-boxes/backend/internal/routes"
 	"github.com/qr-boxes/backend/internal/services"
+	"github.com/qr-boxes/backend/internal/storage"
 	"github.com/qr-boxes/backend/pkg/utils"
 )
 
 func main() {
+	useMemoryStore := flag.Bool("memory", false, "Keep boxes in memory instead of the database (data is lost on restart)")
+	flag.Parse()
+
 	// Load configuration
 	config := utils.LoadConfig()
 
+	// "migrate" runs schema migrations and exits without starting the server
+	if flag.Arg(0) == "migrate" {
+		runMigrateCommand(config, flag.Args()[1:])
+		return
+	}
+
+	// "regenerate-qr" rebuilds every stored QR code and exits
+	if flag.Arg(0) == "regenerate-qr" {
+		runRegenerateQRCommand(config)
+		return
+	}
+
 	// Validate required environment variables
 	if config.ClerkSecretKey == "" {
 		log.Fatal("CLERK_SECRET_KEY environment variable must be set")
 	}
 
-	// Initialize database connection
-	db, err := database.NewConnection(config.DatabaseURL)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer db.Close()
+	// Initialize repositories
+	var stores *repository.Stores
+	if *useMemoryStore {
+		log.Println("⚠️  Using in-memory box store, data will not be persisted")
+		stores = repository.NewMemoryStores()
+	} else {
+		// Initialize database connection
+		db, err := database.NewConnection(config.DatabaseDriver, config.DatabaseURL)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
 
-	// Initialize database schema
-	if err := db.InitSchema(); err != nil {
-		log.Fatalf("Failed to initialize database schema: %v", err)
+		// Apply pending schema migrations
+		if err := db.Migrate(); err != nil {
+			log.Fatalf("Failed to migrate database schema: %v", err)
+		}
+
+		stores = repository.NewStores(db)
 	}
 
-	// Initialize repositories
-	boxRepo := repository.NewBoxRepository(db)
+	// Initialize the store for uploaded photos
+	blobs, err := storage.NewBlobStore(storage.Config{
+		Backend:     config.BlobStore,
+		LocalDir:    config.BlobDir,
+		S3Endpoint:  config.S3Endpoint,
+		S3Region:    config.S3Region,
+		S3Bucket:    config.S3Bucket,
+		S3AccessKey: config.S3AccessKey,
+		S3SecretKey: config.S3SecretKey,
+		S3UseSSL:    config.S3UseSSL,
+	})
+	if err != nil {
+		log.Fatalf("Failed to open blob store: %v", err)
+	}
 
 	// Initialize services
 	userService := services.NewUserService(config.ClerkSecretKey)
-	qrService := services.NewQRService(config.FrontendURL, boxRepo)
+	qrService := services.NewQRService(config.FrontendURL, config.PublicURL, stores, blobs)
+	// Boxes created before short codes existed get one on the next start
+	if assigned, err := qrService.AssignShortCodes(); err != nil {
+		log.Fatalf("Failed to assign box short codes: %v", err)
+	} else if assigned > 0 {
+		log.Printf("🔖 Assigned short codes to %d boxes", assigned)
+	}
+	labelService := services.NewLabelService(qrService)
+	itemService := services.NewItemService(stores)
+	locationService := services.NewLocationService(stores)
+	tagService := services.NewTagService(stores)
+	workspaceService := services.NewWorkspaceService(stores)
+	if config.ShareTokenSecret == "" {
+		log.Println("⚠️  SHARE_TOKEN_SECRET not set, share links will stop working when the server restarts")
+	}
+	shareService, err := services.NewShareService(config.FrontendURL, stores, []byte(config.ShareTokenSecret))
+	if err != nil {
+		log.Fatalf("Failed to create share service: %v", err)
+	}
+	photoService := services.NewPhotoService(stores, blobs, shareService)
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService)
 	healthHandler := handlers.NewHealthHandler()
-	qrHandler := handlers.NewQRHandler(qrService)
+	qrHandler := handlers.NewQRHandler(qrService, labelService, photoService)
+	labelHandler := handlers.NewLabelHandler(labelService)
+	itemHandler := handlers.NewItemHandler(itemService)
+	locationHandler := handlers.NewLocationHandler(locationService)
+	photoHandler := handlers.NewPhotoHandler(photoService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService, userService)
+	shareHandler := handlers.NewShareHandler(shareService, qrService)
 
 	// Initialize router
-	router := routes.NewRouter(userHandler, healthHandler, qrHandler)
+	router := routes.NewRouter(userHandler, healthHandler, qrHandler, labelHandler, itemHandler, locationHandler, photoHandler, tagHandler, workspaceHandler, shareHandler)
 	handler := router.SetupRoutes()
 
 	// Configure server
@@ -64,15 +128,57 @@ func main() {
 	// Start server
 	log.Printf("🚀 Server starting on http://localhost%s", port)
 	log.Printf("🌐 Frontend URL: %s", config.FrontendURL)
+	log.Printf("🔗 QR codes point at: %s/q/{code}", config.PublicURL)
 	log.Printf("📋 API Endpoints:")
+	log.Printf("   GET    /q/{code}           - Redirect a scanned QR code to the box page")
 	log.Printf("   GET    /api/health         - Health check")
 	log.Printf("   GET    /api/user/profile   - User profile (protected)")
 	log.Printf("   POST   /api/boxes          - Create new box with QR (protected)")
+	log.Printf("   POST   /api/boxes/batch    - Create many boxes at once, with optional labels (protected)")
 	log.Printf("   GET    /api/boxes/list     - Get user's boxes (protected)")
+	log.Printf("   GET    /api/boxes/search   - Search user's boxes (protected)")
 	log.Printf("   GET    /api/boxes/details  - Get box details (protected)")
+	log.Printf("   GET    /api/boxes/qr/image - Box QR code as PNG or SVG (protected)")
+	log.Printf("   POST   /api/boxes/scan     - Find boxes from QR codes in a photo (protected)")
+	log.Printf("   POST   /api/boxes/labels   - Printable PDF label sheet (protected)")
 	log.Printf("   PUT    /api/boxes/update   - Update box (protected)")
+	log.Printf("   POST   /api/boxes/move-item - Move items between boxes (protected)")
 	log.Printf("   DELETE /api/boxes/delete   - Delete box (protected)")
 	log.Printf("   GET    /api/boxes/stats    - Get user statistics (protected)")
+	log.Printf("   POST   /api/boxes/photos   - Upload box or item photo (protected)")
+	log.Printf("   DELETE /api/boxes/photos/delete - Delete photo (protected)")
+	log.Printf("   GET    /api/public/box     - Public box details by visibility")
+	log.Printf("   POST   /api/public/box/unlock - Unlock PIN-protected box")
+	log.Printf("   GET    /api/public/photo   - Download photo or thumbnail")
+	log.Printf("   GET    /api/public/share   - Boxes covered by a share link")
+	log.Printf("   POST   /api/public/share/add-item - Add item through an editable share link")
+	log.Printf("   POST   /api/public/share/remove-item - Remove item through an editable share link")
+	log.Printf("   POST   /api/items          - Add item to box (protected)")
+	log.Printf("   PUT    /api/items/update   - Rename or edit item (protected)")
+	log.Printf("   POST   /api/items/move     - Move item to another box (protected)")
+	log.Printf("   POST   /api/items/adjust   - Increment or decrement item quantity (protected)")
+	log.Printf("   DELETE /api/items/delete   - Delete item (protected)")
+	log.Printf("   POST   /api/locations      - Create location (protected)")
+	log.Printf("   GET    /api/locations/list - Get user's location tree (protected)")
+	log.Printf("   PUT    /api/locations/update - Rename or move location (protected)")
+	log.Printf("   DELETE /api/locations/delete - Delete location (protected)")
+	log.Printf("   POST   /api/tags           - Create tag (protected)")
+	log.Printf("   GET    /api/tags/list      - Get user's tags with box counts (protected)")
+	log.Printf("   PUT    /api/tags/update    - Rename or recolour tag (protected)")
+	log.Printf("   POST   /api/tags/merge     - Merge tags (protected)")
+	log.Printf("   DELETE /api/tags/delete    - Delete tag (protected)")
+	log.Printf("   POST   /api/workspaces     - Create household workspace (protected)")
+	log.Printf("   GET    /api/workspaces/list - Get user's workspaces with members (protected)")
+	log.Printf("   PUT    /api/workspaces/update - Rename workspace (protected)")
+	log.Printf("   POST   /api/workspaces/invite - Invite by email or code with a role (protected)")
+	log.Printf("   GET    /api/workspaces/invitations - Get invitations sent to the user (protected)")
+	log.Printf("   POST   /api/workspaces/join - Join workspace with invitation code (protected)")
+	log.Printf("   DELETE /api/workspaces/leave - Leave workspace (protected)")
+	log.Printf("   PUT    /api/workspaces/members/role - Change member role (protected)")
+	log.Printf("   DELETE /api/workspaces/members/remove - Remove member (protected)")
+	log.Printf("   POST   /api/shares         - Create expiring share link (protected)")
+	log.Printf("   GET    /api/shares/list    - Get share links (protected)")
+	log.Printf("   DELETE /api/shares/revoke  - Revoke share link (protected)")
 	
 	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/pkg/utils"
+)
+
+const migrateUsage = `Usage: run-app migrate <command>
+
+Commands:
+  up          Apply all pending migrations (default)
+  down [N]    Roll back the last N applied migrations (default 1)
+  status      List migrations and whether they have been applied`
+
+// runMigrateCommand handles the "migrate" subcommand against DATABASE_URL
+func runMigrateCommand(config utils.Config, args []string) {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	db, err := database.NewConnection(config.DatabaseDriver, config.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	switch command {
+	case "up":
+		if err := db.Migrate(); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid number of migrations to roll back: %s", args[1])
+			}
+		}
+		if err := db.MigrateDown(steps); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+
+	case "status":
+		statuses, err := db.MigrationStatus()
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, applied)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
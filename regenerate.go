@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/qr-boxes/backend/internal/database"
+	"github.com/qr-boxes/backend/internal/repository"
+	"github.com/qr-boxes/backend/internal/services"
+	"github.com/qr-boxes/backend/pkg/utils"
+)
+
+// runRegenerateQRCommand handles the "regenerate-qr" subcommand, rebuilding the
+// stored QR code of every box in DATABASE_URL from PUBLIC_URL. Run it after
+// changing PUBLIC_URL or FRONTEND_URL.
+func runRegenerateQRCommand(config utils.Config) {
+	db, err := database.NewConnection(config.DatabaseDriver, config.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Migrate(); err != nil {
+		log.Fatalf("Failed to migrate database schema: %v", err)
+	}
+
+	// Photos are left alone, so no blob store is needed
+	qrService := services.NewQRService(config.FrontendURL, config.PublicURL, repository.NewStores(db), nil)
+
+	// Every box needs a short code for its QR code to point at
+	if _, err := qrService.AssignShortCodes(); err != nil {
+		log.Fatalf("Failed to assign box short codes: %v", err)
+	}
+
+	regenerated, err := qrService.RegenerateQRCodes()
+	if err != nil {
+		log.Fatalf("Regenerated %d QR codes before failing: %v", regenerated, err)
+	}
+
+	fmt.Printf("Regenerated %d QR codes pointing at %s/q/\n", regenerated, config.PublicURL)
+}